@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"nft-market/internal/api/middleware"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"nft-market/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AuthHandler 认证处理器
+type AuthHandler struct {
+	authService *services.AuthService
+}
+
+// NewAuthHandler 创建新的认证处理器
+func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+	}
+}
+
+// GetNonce 获取SIWE登录随机数
+func (ah *AuthHandler) GetNonce(c *gin.Context) {
+	address := c.Query("address")
+	if address == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "钱包地址不能为空",
+			Code:    400,
+		})
+		return
+	}
+
+	nonce, err := ah.authService.GenerateNonce(address)
+	if err != nil {
+		logger.Warn("生成登录随机数失败", logrus.Fields{
+			"address": address,
+			"error":   err.Error(),
+		})
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "generate_nonce_failed",
+			Message: "生成登录随机数失败: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取登录随机数成功",
+		"data": models.NonceResponse{
+			Nonce:     nonce.Nonce,
+			Address:   nonce.Address,
+			Domain:    ah.authService.Domain(),
+			ExpiresAt: nonce.ExpireTime,
+		},
+	})
+}
+
+// Login 使用SIWE消息和签名登录
+func (ah *AuthHandler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "请求参数无效: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	resp, err := ah.authService.Login(req.Message, req.Signature)
+	if err != nil {
+		logger.Warn("SIWE登录失败", logrus.Fields{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error:   "login_failed",
+			Message: "登录失败: " + err.Error(),
+			Code:    401,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "登录成功",
+		"data":    resp,
+	})
+}
+
+// GetCurrentUser 获取当前登录用户
+func (ah *AuthHandler) GetCurrentUser(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取当前用户成功",
+		"data": gin.H{
+			"address": middleware.GetUserAddress(c),
+		},
+	})
+}
+
+// abortIfForbidden 服务层返回无权限错误时响应403并返回true
+func abortIfForbidden(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrPermissionDenied) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, models.ErrorResponse{
+		Error:   "forbidden",
+		Message: err.Error(),
+		Code:    http.StatusForbidden,
+	})
+	return true
+}
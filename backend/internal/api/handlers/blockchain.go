@@ -1,26 +1,32 @@
 package handlers
 
 import (
+	"encoding/hex"
+	"errors"
 	"net/http"
+	"nft-market/internal/api/middleware"
 	"nft-market/internal/logger"
 	"nft-market/internal/models"
 	"nft-market/internal/services"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // BlockchainHandler 区块链处理器
 type BlockchainHandler struct {
-	blockchainService *services.EnhancedBlockchainService
+	blockchainService    *services.EnhancedBlockchainService
+	txReplacementService *services.TxReplacementService
 }
 
 // NewBlockchainHandler 创建新的区块链处理器
-func NewBlockchainHandler(blockchainService *services.EnhancedBlockchainService) *BlockchainHandler {
+func NewBlockchainHandler(blockchainService *services.EnhancedBlockchainService, txReplacementService *services.TxReplacementService) *BlockchainHandler {
 	return &BlockchainHandler{
-		blockchainService: blockchainService,
+		blockchainService:    blockchainService,
+		txReplacementService: txReplacementService,
 	}
 }
 
@@ -152,75 +158,246 @@ func (bh *BlockchainHandler) GetChainOrderInfo(c *gin.Context) {
 	})
 }
 
-// ExecuteOrder 执行订单
-func (bh *BlockchainHandler) ExecuteOrder(c *gin.Context) {
-	orderIDStr := c.Param("orderid")
-	orderID, err := strconv.ParseUint(orderIDStr, 10, 64)
+// GetBlockchainStatus 获取区块链服务状态
+func (bh *BlockchainHandler) GetBlockchainStatus(c *gin.Context) {
+	status := bh.blockchainService.GetBlockchainStatus()
+	logger.Info("获取区块链服务状态", logrus.Fields{"status": status["status"]})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "区块链服务状态",
+		"data":    status,
+	})
+}
+
+// ListTransactions 获取后端发送的链上交易列表
+func (bh *BlockchainHandler) ListTransactions(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var status *models.TransactionStatus
+	if statusStr := c.Query("status"); statusStr != "" {
+		if v, err := strconv.Atoi(statusStr); err == nil {
+			s := models.TransactionStatus(v)
+			status = &s
+		}
+	}
+
+	response, err := bh.blockchainService.ListTransactions(page, pageSize, status, c.Query("method"), c.Query("order_id"))
 	if err != nil {
+		logger.Error("获取交易列表失败", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "list_transactions_failed",
+			Message: "获取交易列表失败: " + err.Error(),
+			Code:    500,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取交易列表成功",
+		"data":    response,
+	})
+}
+
+// GetTransaction 根据交易hash获取交易记录
+func (bh *BlockchainHandler) GetTransaction(c *gin.Context) {
+	txHash := c.Param("hash")
+	if !isTxHash(txHash) {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_order_id",
-			Message: "无效的订单ID",
+			Error:   "invalid_tx_hash",
+			Message: "无效的交易hash",
 			Code:    400,
 		})
 		return
 	}
 
-	var req struct {
-		Price float64 `json:"price" binding:"required"`
+	transaction, err := bh.blockchainService.GetTransactionByHash(txHash)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "transaction_not_found",
+			Message: "交易记录不存在",
+			Code:    404,
+		})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_request",
-			Message: "请求参数无效: " + err.Error(),
-			Code:    400,
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取交易记录成功",
+		"data":    transaction,
+	})
+}
+
+// ListFailedLogs 获取处理失败的链上日志
+func (bh *BlockchainHandler) ListFailedLogs(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var status *models.FailedLogStatus
+	if statusStr := c.Query("status"); statusStr != "" {
+		if v, err := strconv.Atoi(statusStr); err == nil {
+			s := models.FailedLogStatus(v)
+			status = &s
+		}
+	}
+
+	response, err := bh.blockchainService.ListFailedLogs(page, pageSize, status)
+	if err != nil {
+		logger.Error("获取失败日志列表失败", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "list_failed_logs_failed",
+			Message: "获取失败日志列表失败: " + err.Error(),
+			Code:    500,
 		})
 		return
 	}
 
-	logger.Info("开始执行链上订单", logrus.Fields{
-		"order_id": orderID,
-		"price":    req.Price,
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取失败日志列表成功",
+		"data":    response,
 	})
+}
 
-	// 异步执行订单
-	go func() {
-		tx, err := bh.blockchainService.ExecuteOrderOnChain(orderID, req.Price)
-		if err != nil {
-			logger.Error("执行链上订单失败", err, logrus.Fields{
-				"order_id": orderID,
+// RetryFailedLog 人工重试处理失败的链上日志
+func (bh *BlockchainHandler) RetryFailedLog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "无效的失败日志ID",
+			Code:    400,
+		})
+		return
+	}
+
+	failedLog, err := bh.blockchainService.RetryFailedLog(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "failed_log_not_found",
+				Message: "失败日志记录不存在",
+				Code:    404,
 			})
 			return
 		}
+		logger.Error("重试失败日志失败", err, logrus.Fields{
+			"id":    id,
+			"admin": middleware.GetUserAddress(c),
+		})
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "retry_failed_log_failed",
+			Message: err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "重试失败日志成功",
+		"data":    failedLog,
+	})
+}
+
+// isTxHash 检查是否为0x开头的32字节hash
+func isTxHash(s string) bool {
+	if len(s) != 66 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(s[2:])
+	return err == nil
+}
+
+// ListStuckTransactions 获取待打包时间过长的交易
+func (bh *BlockchainHandler) ListStuckTransactions(c *gin.Context) {
+	transactions, err := bh.txReplacementService.ListStuckTransactions()
+	if err != nil {
+		logger.Error("获取卡住的交易失败", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "list_stuck_transactions_failed",
+			Message: "获取卡住的交易失败: " + err.Error(),
+			Code:    500,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取卡住的交易成功",
+		"data":    transactions,
+	})
+}
 
-		// 等待交易确认
-		receipt, err := bh.blockchainService.WaitForTransactionConfirmation(tx, 5*time.Minute)
-		if err != nil {
-			logger.Error("等待执行交易确认失败", err, logrus.Fields{
-				"order_id": orderID,
-				"tx_hash":  tx.Hash().Hex(),
+// SpeedUpTransaction 以更高手续费重新发送交易
+func (bh *BlockchainHandler) SpeedUpTransaction(c *gin.Context) {
+	bh.replaceTransaction(c, false)
+}
+
+// CancelTransaction 以0金额自转账取消交易
+func (bh *BlockchainHandler) CancelTransaction(c *gin.Context) {
+	bh.replaceTransaction(c, true)
+}
+
+// replaceTransaction 发送同nonce的替换交易
+func (bh *BlockchainHandler) replaceTransaction(c *gin.Context, cancel bool) {
+	txHash := c.Param("hash")
+	if !isTxHash(txHash) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_tx_hash",
+			Message: "无效的交易hash",
+			Code:    400,
+		})
+		return
+	}
+
+	var (
+		replacement *models.Transaction
+		err         error
+	)
+	if cancel {
+		replacement, err = bh.txReplacementService.Cancel(txHash)
+	} else {
+		replacement, err = bh.txReplacementService.SpeedUp(txHash)
+	}
+	if err != nil {
+		logger.Error("发送替换交易失败", err, logrus.Fields{
+			"tx_hash": txHash,
+			"cancel":  cancel,
+			"admin":   middleware.GetUserAddress(c),
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "transaction_not_found",
+				Message: "交易记录不存在",
+				Code:    404,
 			})
 			return
 		}
-
-		logger.Info("链上订单执行并确认成功", logrus.Fields{
-			"order_id":     orderID,
-			"tx_hash":      tx.Hash().Hex(),
-			"block_number": receipt.BlockNumber.String(),
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "replace_transaction_failed",
+			Message: "发送替换交易失败: " + err.Error(),
+			Code:    400,
 		})
-	}()
+		return
+	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"message": "开始执行订单，请查看日志了解进度",
+	logger.Info("管理员发送替换交易", logrus.Fields{
+		"tx_hash":     txHash,
+		"replacement": replacement.TxHash,
+		"cancel":      cancel,
+		"admin":       middleware.GetUserAddress(c),
 	})
-}
 
-// GetBlockchainStatus 获取区块链服务状态
-func (bh *BlockchainHandler) GetBlockchainStatus(c *gin.Context) {
-	status := bh.blockchainService.GetBlockchainStatus()
-	logger.Info("获取区块链服务状态", logrus.Fields{"status": status["status"]})
 	c.JSON(http.StatusOK, gin.H{
-		"message": "区块链服务状态",
-		"data":    status,
+		"message": "替换交易已发送",
+		"data":    replacement,
 	})
 }
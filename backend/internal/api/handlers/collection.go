@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"nft-market/internal/api/middleware"
 	"nft-market/internal/models"
 	"nft-market/internal/services"
 	"strconv"
@@ -33,6 +34,12 @@ func (h *CollectionHandler) CreateCollection(c *gin.Context) {
 		return
 	}
 
+	// 非管理员只能以自己的地址作为创建者登记集合
+	if !middleware.GetOperator(c).CanModify(req.Creator) {
+		abortIfForbidden(c, services.ErrPermissionDenied)
+		return
+	}
+
 	collection, err := h.collectionService.CreateCollection(&req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -156,7 +163,10 @@ func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
 		return
 	}
 
-	err = h.collectionService.UpdateCollection(id, updates)
+	err = h.collectionService.UpdateCollection(id, updates, middleware.GetOperator(c))
+	if abortIfForbidden(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to update collection",
@@ -182,7 +192,10 @@ func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
 		return
 	}
 
-	err = h.collectionService.DeleteCollection(id)
+	err = h.collectionService.DeleteCollection(id, middleware.GetOperator(c))
+	if abortIfForbidden(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to delete collection",
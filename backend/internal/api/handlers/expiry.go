@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"nft-market/internal/api/middleware"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"nft-market/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ExpiryHandler 订单过期任务处理器
+type ExpiryHandler struct {
+	expiryScheduler *services.ExpiryScheduler
+}
+
+// NewExpiryHandler 创建新的订单过期任务处理器
+func NewExpiryHandler(expiryScheduler *services.ExpiryScheduler) *ExpiryHandler {
+	return &ExpiryHandler{
+		expiryScheduler: expiryScheduler,
+	}
+}
+
+// ListExpiryRuns 获取订单过期任务执行记录
+func (eh *ExpiryHandler) ListExpiryRuns(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	response, err := eh.expiryScheduler.ListRuns(page, pageSize)
+	if err != nil {
+		logger.Error("获取订单过期任务记录失败", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "list_expiry_runs_failed",
+			Message: "获取订单过期任务记录失败: " + err.Error(),
+			Code:    500,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取订单过期任务记录成功",
+		"data":    response,
+	})
+}
+
+// RunExpiry 管理员手动执行一次订单过期任务
+func (eh *ExpiryHandler) RunExpiry(c *gin.Context) {
+	adminAddress := middleware.GetUserAddress(c)
+	run, err := eh.expiryScheduler.TriggerManual(adminAddress)
+	if errors.Is(err, services.ErrExpiryRunning) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "expiry_running",
+			Message: err.Error(),
+			Code:    409,
+		})
+		return
+	}
+	if err != nil {
+		logger.Error("手动执行订单过期任务失败", err, logrus.Fields{
+			"admin_address": adminAddress,
+		})
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "expiry_run_failed",
+			Message: "执行订单过期任务失败: " + err.Error(),
+			Code:    500,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "订单过期任务执行完成",
+		"data":    run,
+	})
+}
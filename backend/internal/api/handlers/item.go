@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"nft-market/internal/api/middleware"
 	"nft-market/internal/models"
 	"nft-market/internal/services"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ItemHandler 物品处理器
@@ -160,7 +163,10 @@ func (h *ItemHandler) UpdateItem(c *gin.Context) {
 		return
 	}
 
-	err = h.itemService.UpdateItem(id, updates)
+	err = h.itemService.UpdateItem(id, updates, middleware.GetOperator(c))
+	if abortIfForbidden(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to update item",
@@ -213,6 +219,68 @@ func (h *ItemHandler) UpdateItemOwner(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Item owner updated successfully"})
 }
 
+// GetItemAttributes 获取物品特征
+func (h *ItemHandler) GetItemAttributes(c *gin.Context) {
+	collectionAddress := c.Param("collection_address")
+	tokenID := c.Param("token_id")
+
+	attributes, err := h.itemService.GetItemAttributes(collectionAddress, tokenID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to get item attributes",
+			Message: err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attributes": attributes})
+}
+
+// RefreshItemAttributes 从tokenURI重新索引物品特征
+func (h *ItemHandler) RefreshItemAttributes(c *gin.Context) {
+	collectionAddress := c.Param("collection_address")
+	tokenID := c.Param("token_id")
+
+	attributes, err := h.itemService.IndexItemMetadata(collectionAddress, tokenID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "Item not found",
+				Message: err.Error(),
+				Code:    http.StatusNotFound,
+			})
+			return
+		}
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error:   "Failed to refresh item attributes",
+			Message: err.Error(),
+			Code:    http.StatusBadGateway,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attributes": attributes})
+}
+
+// GetItemBalances 获取ERC-1155物品各拥有者的持有数量
+func (h *ItemHandler) GetItemBalances(c *gin.Context) {
+	collectionAddress := c.Param("collection_address")
+	tokenID := c.Param("token_id")
+
+	balances, err := h.itemService.GetItemBalances(collectionAddress, tokenID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to get item balances",
+			Message: err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balances": balances})
+}
+
 // UpdateItemPrice 更新物品价格
 func (h *ItemHandler) UpdateItemPrice(c *gin.Context) {
 	collectionAddress := c.Param("collection_address")
@@ -228,8 +296,8 @@ func (h *ItemHandler) UpdateItemPrice(c *gin.Context) {
 	}
 
 	var req struct {
-		ListPrice *float64 `json:"list_price"`
-		SalePrice *float64 `json:"sale_price"`
+		ListPrice *models.Wei `json:"list_price"` // 十进制wei字符串
+		SalePrice *models.Wei `json:"sale_price"` // 十进制wei字符串
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -241,7 +309,10 @@ func (h *ItemHandler) UpdateItemPrice(c *gin.Context) {
 		return
 	}
 
-	err := h.itemService.UpdateItemPrice(collectionAddress, tokenID, req.ListPrice, req.SalePrice)
+	err := h.itemService.UpdateItemPrice(collectionAddress, tokenID, req.ListPrice, req.SalePrice, middleware.GetOperator(c))
+	if abortIfForbidden(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to update item price",
@@ -267,7 +338,10 @@ func (h *ItemHandler) DeleteItem(c *gin.Context) {
 		return
 	}
 
-	err = h.itemService.DeleteItem(id)
+	err = h.itemService.DeleteItem(id, middleware.GetOperator(c))
+	if abortIfForbidden(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to delete item",
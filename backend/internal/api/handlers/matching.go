@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"nft-market/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MatchingHandler 撮合处理器
+type MatchingHandler struct {
+	matchingService *services.MatchingService
+}
+
+// NewMatchingHandler 创建新的撮合处理器
+func NewMatchingHandler(matchingService *services.MatchingService) *MatchingHandler {
+	return &MatchingHandler{
+		matchingService: matchingService,
+	}
+}
+
+// ListMatches 获取价格交叉的订单撮合建议，按撮合时间排序
+func (mh *MatchingHandler) ListMatches(c *gin.Context) {
+	matches, err := mh.matchingService.Proposals()
+	if err != nil {
+		logger.Error("获取撮合建议失败", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "list_matches_failed",
+			Message: "获取撮合建议失败: " + err.Error(),
+			Code:    500,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取撮合建议成功",
+		"data":    matches,
+	})
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"nft-market/internal/api/middleware"
+	"nft-market/internal/blockchain"
 	"nft-market/internal/logger"
 	"nft-market/internal/models"
 	"nft-market/internal/services"
@@ -9,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // OrderHandler 订单处理器
@@ -35,8 +39,8 @@ func (oh *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// 从请求头或认证中获取用户地址（这里简化处理）
-	userAddress := c.GetHeader("X-User-Address")
+	// 从认证中间件获取已验证的用户地址
+	userAddress := middleware.GetUserAddress(c)
 	if userAddress == "" {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Error:   "unauthorized",
@@ -53,7 +57,7 @@ func (oh *OrderHandler) CreateOrder(c *gin.Context) {
 			"collection_address": req.CollectionAddress,
 			"token_id":           req.TokenID,
 			"order_type":         req.OrderType,
-			"price":              req.Price,
+			"price_wei":          req.Price.String(),
 		})
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "create_order_failed",
@@ -69,7 +73,7 @@ func (oh *OrderHandler) CreateOrder(c *gin.Context) {
 		"collection_address": req.CollectionAddress,
 		"token_id":           req.TokenID,
 		"order_type":         req.OrderType,
-		"price":              req.Price,
+		"price_wei":          req.Price.String(),
 	})
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "订单创建成功",
@@ -110,6 +114,46 @@ func (oh *OrderHandler) GetOrderByID(c *gin.Context) {
 	})
 }
 
+// GetOrderChainStatus 获取订单的链上同步状态及链上操作记录
+func (oh *OrderHandler) GetOrderChainStatus(c *gin.Context) {
+	id, ok := parseOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	order, err := oh.orderService.GetOrderByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "order_not_found",
+			Message: "订单不存在",
+			Code:    404,
+		})
+		return
+	}
+
+	actions, err := oh.orderService.GetOrderChainActions(id)
+	if err != nil {
+		logger.Error("获取订单链上操作记录失败", err, logrus.Fields{
+			"order_id": id,
+		})
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "获取链上操作记录失败",
+			Code:    500,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取订单链上状态成功",
+		"data": gin.H{
+			"chain_status": order.ChainStatus,
+			"chain_error":  order.ChainError,
+			"actions":      actions,
+		},
+	})
+}
+
 // GetOrders 获取订单列表
 func (oh *OrderHandler) GetOrders(c *gin.Context) {
 	// 解析查询参数
@@ -205,6 +249,27 @@ func (oh *OrderHandler) GetNFTOrders(c *gin.Context) {
 	})
 }
 
+// GetQualifyingBids 获取NFT可以接受的出价，包括特征条件满足的集合出价
+func (oh *OrderHandler) GetQualifyingBids(c *gin.Context) {
+	collectionAddress := c.Param("collection_address")
+	tokenID := c.Param("token_id")
+
+	bids, err := oh.orderService.GetQualifyingBids(collectionAddress, tokenID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "get_qualifying_bids_failed",
+			Message: "获取可接受的出价失败: " + err.Error(),
+			Code:    500,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取可接受的出价成功",
+		"data":    bids,
+	})
+}
+
 // CancelOrder 取消订单
 func (oh *OrderHandler) CancelOrder(c *gin.Context) {
 	idStr := c.Param("id")
@@ -218,8 +283,8 @@ func (oh *OrderHandler) CancelOrder(c *gin.Context) {
 		return
 	}
 
-	// 从请求头获取用户地址
-	userAddress := c.GetHeader("X-User-Address")
+	// 从认证中间件获取已验证的用户地址
+	userAddress := middleware.GetUserAddress(c)
 	if userAddress == "" {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Error:   "unauthorized",
@@ -252,6 +317,98 @@ func (oh *OrderHandler) CancelOrder(c *gin.Context) {
 	})
 }
 
+// EditOrder 修改订单价格和过期时间，原订单被取消并由新订单替换
+func (oh *OrderHandler) EditOrder(c *gin.Context) {
+	id, ok := parseOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.EditOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "请求参数无效: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	userAddress := middleware.GetUserAddress(c)
+	order, err := oh.orderService.EditOrder(id, userAddress, &req)
+	if err != nil {
+		logger.Error("修改订单失败", err, logrus.Fields{
+			"order_id":     id,
+			"user_address": userAddress,
+		})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "order_not_found",
+				Message: "订单不存在",
+				Code:    404,
+			})
+		case errors.Is(err, blockchain.ErrSignerNotConfigured):
+			c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+				Error:   "service_unavailable",
+				Message: "交易签名不可用，无法修改已上链订单",
+				Code:    503,
+			})
+		default:
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "edit_order_failed",
+				Message: "修改订单失败: " + err.Error(),
+				Code:    400,
+			})
+		}
+		return
+	}
+
+	logger.Info("订单修改成功", logrus.Fields{
+		"order_id":       id,
+		"replacement_id": order.ID,
+		"user_address":   userAddress,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "订单修改成功",
+		"data":    order,
+	})
+}
+
+// GetOrderHistory 获取订单的修改历史
+func (oh *OrderHandler) GetOrderHistory(c *gin.Context) {
+	id, ok := parseOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	history, err := oh.orderService.GetOrderHistory(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "order_not_found",
+				Message: "订单不存在",
+				Code:    404,
+			})
+			return
+		}
+		logger.Error("获取订单修改历史失败", err, logrus.Fields{
+			"order_id": id,
+		})
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "获取订单修改历史失败",
+			Code:    500,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取订单修改历史成功",
+		"data":    history,
+	})
+}
+
 // PurchaseOrder 购买订单
 func (oh *OrderHandler) PurchaseOrder(c *gin.Context) {
 	idStr := c.Param("id")
@@ -266,8 +423,8 @@ func (oh *OrderHandler) PurchaseOrder(c *gin.Context) {
 		return
 	}
 
-	// 从请求头或认证中获取用户地址
-	userAddress := c.GetHeader("X-User-Address")
+	// 从认证中间件获取已验证的用户地址
+	userAddress := middleware.GetUserAddress(c)
 	if userAddress == "" {
 		logger.Warn("购买订单用户未认证", nil)
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
@@ -278,23 +435,26 @@ func (oh *OrderHandler) PurchaseOrder(c *gin.Context) {
 		return
 	}
 
-	// 解析请求体（可选的价格参数）
+	// 解析请求体（可选的价格参数，十进制wei字符串；ERC-1155可选购买数量）
 	var req struct {
-		Price float64 `json:"price"`
+		Price    models.Wei `json:"price"`
+		Quantity int64      `json:"quantity"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// 如果没有请求体，使用默认价格0（表示接受订单原价）
-		req.Price = 0
+		req.Price = models.Wei{}
+		req.Quantity = 0
 	}
 
 	logger.Info("开始处理购买订单请求", logrus.Fields{
 		"order_id":      id,
 		"user_address":  userAddress,
-		"offered_price": req.Price,
+		"offered_price": req.Price.String(),
+		"quantity":      req.Quantity,
 	})
 
 	// 调用服务层处理购买逻辑
-	err = oh.orderService.PurchaseOrder(id, userAddress, req.Price)
+	err = oh.orderService.PurchaseOrder(id, userAddress, req.Price, req.Quantity)
 	if err != nil {
 		logger.Error("购买订单失败", err, logrus.Fields{
 			"order_id":     id,
@@ -317,6 +477,74 @@ func (oh *OrderHandler) PurchaseOrder(c *gin.Context) {
 	})
 }
 
+// AcceptOffer NFT拥有者接受出价、物品出价或集合出价
+func (oh *OrderHandler) AcceptOffer(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "无效的订单ID",
+			Code:    400,
+		})
+		return
+	}
+
+	userAddress := middleware.GetUserAddress(c)
+	if userAddress == "" {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error:   "unauthorized",
+			Message: "用户未认证",
+			Code:    401,
+		})
+		return
+	}
+
+	// 请求体可选，集合出价需要指定token_id
+	var req models.AcceptOfferRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: err.Error(),
+				Code:    400,
+			})
+			return
+		}
+	}
+
+	order, unsignedTx, err := oh.orderService.AcceptOffer(id, userAddress, &req)
+	if err != nil {
+		logger.Error("接受出价失败", err, logrus.Fields{
+			"order_id":     id,
+			"user_address": userAddress,
+		})
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "accept_offer_failed",
+			Message: "接受出价失败: " + err.Error(),
+			Code:    500,
+		})
+		return
+	}
+
+	// 链上出价返回由卖方钱包签名的成交交易，订单在链上成交后由事件监听器更新
+	if unsignedTx != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "请使用钱包签名并发送交易",
+			"data": gin.H{
+				"order":       order,
+				"transaction": unsignedTx,
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "出价接受成功",
+		"data":    order,
+	})
+}
+
 // SyncOrderFromChain 从链上同步订单
 func (oh *OrderHandler) SyncOrderFromChain(c *gin.Context) {
 	orderIDStr := c.Param("orderid")
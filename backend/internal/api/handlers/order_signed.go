@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"nft-market/internal/api/middleware"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"nft-market/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// GetSignedOrderTypedData 返回待钱包通过eth_signTypedData_v4签名的订单类型化数据
+func (oh *OrderHandler) GetSignedOrderTypedData(c *gin.Context) {
+	var req models.SignedOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "请求参数无效: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	userAddress := middleware.GetUserAddress(c)
+	typedData, hash, err := oh.orderService.SignedOrderTypedData(&req, userAddress)
+	if abortIfSignedOrdersDisabled(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_order",
+			Message: "构建订单签名数据失败: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "请使用钱包签名订单数据",
+		"data": gin.H{
+			"typed_data": typedData,
+			"order_hash": hash.Hex(),
+		},
+	})
+}
+
+// CreateSignedOrder 提交maker签名的链下订单
+func (oh *OrderHandler) CreateSignedOrder(c *gin.Context) {
+	var req models.SignedOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "请求参数无效: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	userAddress := middleware.GetUserAddress(c)
+	order, err := oh.orderService.CreateSignedOrder(&req, userAddress)
+	if abortIfSignedOrdersDisabled(c, err) {
+		return
+	}
+	if err != nil {
+		logger.Error("创建链下签名订单失败", err, logrus.Fields{
+			"user_address":       userAddress,
+			"collection_address": req.CollectionAddress,
+			"token_id":           req.TokenID,
+			"order_type":         req.OrderType,
+		})
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "create_signed_order_failed",
+			Message: "创建签名订单失败: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "签名订单创建成功",
+		"data":    order,
+	})
+}
+
+// GetSignedOrder 获取链下签名订单及类型化数据，对手方可据此独立校验maker的签名
+func (oh *OrderHandler) GetSignedOrder(c *gin.Context) {
+	id, ok := parseOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	order, typedData, err := oh.orderService.GetSignedOrder(id)
+	if abortIfSignedOrdersDisabled(c, err) {
+		return
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "order_not_found",
+				Message: "订单不存在",
+				Code:    404,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_signed_order",
+			Message: err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取签名订单成功",
+		"data": gin.H{
+			"order":      order,
+			"typed_data": typedData,
+			"signature":  order.Signature,
+		},
+	})
+}
+
+// abortIfSignedOrdersDisabled 未开启链下签名订单时返回404
+func abortIfSignedOrdersDisabled(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrSignedOrdersDisabled) {
+		return false
+	}
+	c.JSON(http.StatusNotFound, models.ErrorResponse{
+		Error:   "signed_orders_disabled",
+		Message: err.Error(),
+		Code:    404,
+	})
+	return true
+}
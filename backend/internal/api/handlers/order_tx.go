@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"nft-market/internal/api/middleware"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"nft-market/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// PrepareCreateOrder 创建待上链订单，返回由用户钱包签名的交易
+func (oh *OrderHandler) PrepareCreateOrder(c *gin.Context) {
+	var req models.CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "请求参数无效: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	userAddress := middleware.GetUserAddress(c)
+	order, unsignedTx, err := oh.orderService.PrepareCreateOrder(&req, userAddress)
+	if err != nil {
+		logger.Error("构建创建订单交易失败", err, logrus.Fields{
+			"user_address":       userAddress,
+			"collection_address": req.CollectionAddress,
+			"token_id":           req.TokenID,
+			"order_type":         req.OrderType,
+		})
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "build_transaction_failed",
+			Message: "构建交易失败: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "请使用钱包签名并发送交易，链上确认后订单生效",
+		"data": gin.H{
+			"order":       order,
+			"transaction": unsignedTx,
+		},
+	})
+}
+
+// PrepareCancelOrder 返回由订单创建者签名的取消订单交易
+func (oh *OrderHandler) PrepareCancelOrder(c *gin.Context) {
+	id, ok := parseOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	userAddress := middleware.GetUserAddress(c)
+	unsignedTx, err := oh.orderService.PrepareCancelOrder(id, userAddress)
+	if err != nil {
+		respondBuildTxError(c, "构建取消订单交易失败", id, userAddress, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "请使用钱包签名并发送交易",
+		"data":    unsignedTx,
+	})
+}
+
+// PrepareExecuteOrder 返回由成交方签名的执行订单交易
+func (oh *OrderHandler) PrepareExecuteOrder(c *gin.Context) {
+	id, ok := parseOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	userAddress := middleware.GetUserAddress(c)
+	unsignedTx, err := oh.orderService.PrepareExecuteOrder(id, userAddress)
+	if err != nil {
+		respondBuildTxError(c, "构建执行订单交易失败", id, userAddress, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "请使用钱包签名并发送交易",
+		"data":    unsignedTx,
+	})
+}
+
+// PrepareEditOrder 返回由订单创建者签名的修改订单交易
+func (oh *OrderHandler) PrepareEditOrder(c *gin.Context) {
+	id, ok := parseOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.EditOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "请求参数无效: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	userAddress := middleware.GetUserAddress(c)
+	order, unsignedTx, err := oh.orderService.PrepareEditOrder(id, userAddress, &req)
+	if err != nil {
+		respondBuildTxError(c, "构建修改订单交易失败", id, userAddress, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "请使用钱包签名并发送交易，链上确认后新订单生效",
+		"data": gin.H{
+			"order":       order,
+			"transaction": unsignedTx,
+		},
+	})
+}
+
+// PrepareMarketBuy 按最优卖单报价并返回由买方签名的市价买入交易
+func (oh *OrderHandler) PrepareMarketBuy(c *gin.Context) {
+	oh.prepareMarketOrder(c, "市价买入", oh.orderService.PrepareMarketBuy)
+}
+
+// PrepareMarketSell 按最优买单报价并返回由NFT持有者签名的市价卖出交易
+func (oh *OrderHandler) PrepareMarketSell(c *gin.Context) {
+	oh.prepareMarketOrder(c, "市价卖出", oh.orderService.PrepareMarketSell)
+}
+
+// prepareMarketOrder 解析市价单请求并返回报价和未签名交易
+func (oh *OrderHandler) prepareMarketOrder(c *gin.Context, action string, prepare func(*models.MarketOrderRequest, string) (*models.MarketQuote, *blockchain.UnsignedTransaction, error)) {
+	var req models.MarketOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "请求参数无效: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	userAddress := middleware.GetUserAddress(c)
+	quote, unsignedTx, err := prepare(&req, userAddress)
+	if err != nil {
+		logger.Error("构建"+action+"交易失败", err, logrus.Fields{
+			"user_address":       userAddress,
+			"collection_address": req.CollectionAddress,
+			"token_id":           req.TokenID,
+		})
+		if errors.Is(err, services.ErrNoMatchingOrder) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "no_matching_order",
+				Message: err.Error(),
+				Code:    404,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "build_transaction_failed",
+			Message: "构建交易失败: " + err.Error(),
+			Code:    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "请使用钱包签名并发送交易",
+		"data": gin.H{
+			"quote":       quote,
+			"transaction": unsignedTx,
+		},
+	})
+}
+
+// parseOrderIDParam 解析路径中的订单ID，失败时直接返回400
+func parseOrderIDParam(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "无效的订单ID",
+			Code:    400,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondBuildTxError 返回构建交易失败的响应
+func respondBuildTxError(c *gin.Context, msg string, id uint64, userAddress string, err error) {
+	logger.Error(msg, err, logrus.Fields{
+		"order_id":     id,
+		"user_address": userAddress,
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "order_not_found",
+			Message: "订单不存在",
+			Code:    404,
+		})
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error:   "build_transaction_failed",
+		Message: "构建交易失败: " + err.Error(),
+		Code:    400,
+	})
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"nft-market/internal/models"
+	"nft-market/internal/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ContextUserAddressKey 认证通过后写入gin上下文的用户地址键
+	ContextUserAddressKey = "user_address"
+	// ContextIsAdminKey 认证通过后写入gin上下文的管理员标记键
+	ContextIsAdminKey = "is_admin"
+)
+
+// AuthRequired 返回JWT认证中间件，校验通过后将钱包地址写入上下文
+func AuthRequired(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		if !strings.HasPrefix(header, "Bearer ") || tokenString == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error:   "unauthorized",
+				Message: "缺少认证令牌",
+				Code:    401,
+			})
+			return
+		}
+
+		address, err := authService.ParseToken(tokenString)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error:   "unauthorized",
+				Message: "认证令牌无效: " + err.Error(),
+				Code:    401,
+			})
+			return
+		}
+
+		c.Set(ContextUserAddressKey, address)
+		c.Set(ContextIsAdminKey, authService.IsAdmin(address))
+		c.Next()
+	}
+}
+
+// AdminRequired 返回管理员校验中间件，需在AuthRequired之后使用
+func AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool(ContextIsAdminKey) {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "forbidden",
+				Message: "需要管理员权限",
+				Code:    403,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetUserAddress 获取认证中间件写入的用户地址，未认证时返回空字符串
+func GetUserAddress(c *gin.Context) string {
+	return c.GetString(ContextUserAddressKey)
+}
+
+// GetOperator 获取认证中间件写入的操作者，用于校验资源拥有者或管理员权限
+func GetOperator(c *gin.Context) services.Operator {
+	return services.Operator{
+		Address: GetUserAddress(c),
+		IsAdmin: c.GetBool(ContextIsAdminKey),
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"nft-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CapabilityRequired 返回功能可用性校验中间件，功能不可用时返回503
+// 用于只读模式下拦截依赖区块链节点或热钱包签名的接口
+func CapabilityRequired(available bool, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !available {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, models.ErrorResponse{
+				Error:   "service_unavailable",
+				Message: feature + "不可用，服务当前以只读模式运行",
+				Code:    503,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -2,20 +2,33 @@ package api
 
 import (
 	"nft-market/internal/api/handlers"
+	"nft-market/internal/api/middleware"
 	"nft-market/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes 设置API路由
-func SetupRoutes(router *gin.Engine, orderService *services.OrderService, nftService *services.NFTService, collectionService *services.CollectionService, itemService *services.ItemService, activityService *services.ActivityService, blockchainService *services.EnhancedBlockchainService) {
+func SetupRoutes(router *gin.Engine, orderService *services.OrderService, nftService *services.NFTService, collectionService *services.CollectionService, itemService *services.ItemService, activityService *services.ActivityService, blockchainService *services.EnhancedBlockchainService, authService *services.AuthService, txReplacementService *services.TxReplacementService, expiryScheduler *services.ExpiryScheduler, matchingService *services.MatchingService) {
 	// 创建处理器
+	authHandler := handlers.NewAuthHandler(authService)
 	orderHandler := handlers.NewOrderHandler(orderService)
 	nftHandler := handlers.NewNFTHandler(nftService)
 	collectionHandler := handlers.NewCollectionHandler(collectionService)
 	itemHandler := handlers.NewItemHandler(itemService)
 	activityHandler := handlers.NewActivityHandler(activityService)
-	blockchainHandler := handlers.NewBlockchainHandler(blockchainService)
+	blockchainHandler := handlers.NewBlockchainHandler(blockchainService, txReplacementService)
+	expiryHandler := handlers.NewExpiryHandler(expiryScheduler)
+	matchingHandler := handlers.NewMatchingHandler(matchingService)
+
+	// 认证中间件，所有写操作路由都需要登录
+	authRequired := middleware.AuthRequired(authService)
+	// 管理员中间件，需在认证中间件之后使用
+	adminRequired := middleware.AdminRequired()
+	// 只读模式下依赖区块链节点或热钱包签名的接口返回503
+	capabilities := blockchainService.Capabilities()
+	chainRead := middleware.CapabilityRequired(capabilities.ChainRead, "区块链服务")
+	chainWrite := middleware.CapabilityRequired(capabilities.ChainWrite, "交易签名")
 
 	// API版本组
 	v1 := router.Group("/api/v1")
@@ -28,17 +41,46 @@ func SetupRoutes(router *gin.Engine, orderService *services.OrderService, nftSer
 			})
 		})
 
+		// 认证相关路由
+		auth := v1.Group("/auth")
+		{
+			auth.GET("/nonce", authHandler.GetNonce)                  // 获取SIWE登录随机数
+			auth.POST("/login", authHandler.Login)                    // SIWE签名登录
+			auth.GET("/me", authRequired, authHandler.GetCurrentUser) // 获取当前登录用户
+		}
+
 		// 订单相关路由
 		orders := v1.Group("/orders")
 		{
-			orders.POST("", orderHandler.CreateOrder)                                   // 创建订单
-			orders.GET("", orderHandler.GetOrders)                                      // 获取订单列表
-			orders.GET("/:id", orderHandler.GetOrderByID)                               // 获取单个订单
-			orders.PUT("/:id/cancel", orderHandler.CancelOrder)                         // 取消订单
-			orders.POST("/:id/purchase", orderHandler.PurchaseOrder)                    // 购买订单
-			orders.GET("/user/:address", orderHandler.GetUserOrders)                    // 获取用户订单
-			orders.GET("/nft/:collection_address/:token_id", orderHandler.GetNFTOrders) // 获取NFT订单
-			orders.POST("/sync/:orderid", orderHandler.SyncOrderFromChain)              // 从链上同步订单
+			orders.POST("", authRequired, orderHandler.CreateOrder)                                 // 创建订单
+			orders.GET("", orderHandler.GetOrders)                                                  // 获取订单列表
+			orders.GET("/:id", orderHandler.GetOrderByID)                                           // 获取单个订单
+			orders.GET("/:id/chain", orderHandler.GetOrderChainStatus)                              // 获取订单链上状态
+			orders.PUT("/:id", authRequired, orderHandler.EditOrder)                                // 修改订单价格和过期时间
+			orders.GET("/:id/history", orderHandler.GetOrderHistory)                                // 获取订单修改历史
+			orders.PUT("/:id/cancel", authRequired, orderHandler.CancelOrder)                       // 取消订单
+			orders.POST("/:id/purchase", authRequired, orderHandler.PurchaseOrder)                  // 购买订单
+			orders.POST("/:id/accept", authRequired, orderHandler.AcceptOffer)                      // NFT拥有者接受出价
+			orders.GET("/user/:address", orderHandler.GetUserOrders)                                // 获取用户订单
+			orders.GET("/nft/:collection_address/:token_id", orderHandler.GetNFTOrders)             // 获取NFT订单
+			orders.GET("/nft/:collection_address/:token_id/bids", orderHandler.GetQualifyingBids)   // 获取NFT可接受的出价
+			orders.POST("/sync/:orderid", authRequired, chainRead, orderHandler.SyncOrderFromChain) // 从链上同步订单
+			orders.GET("/matches", matchingHandler.ListMatches)                                     // 获取价格交叉的订单撮合建议
+			orders.GET("/expiry/runs", expiryHandler.ListExpiryRuns)                                // 获取订单过期任务记录
+			orders.POST("/expiry/run", authRequired, adminRequired, expiryHandler.RunExpiry)        // 手动执行订单过期任务
+
+			// 链下签名订单：maker签名EIP-712订单数据，挂单和出价不需要支付gas
+			orders.POST("/signed/typed-data", authRequired, chainRead, orderHandler.GetSignedOrderTypedData) // 获取待签名的订单数据
+			orders.POST("/signed", authRequired, chainRead, orderHandler.CreateSignedOrder)                  // 提交签名订单
+			orders.GET("/:id/signed", chainRead, orderHandler.GetSignedOrder)                                // 获取签名订单供成交方成交
+
+			// 非托管交易构建：返回未签名交易，由用户钱包签名发送
+			orders.POST("/tx/create", authRequired, chainRead, orderHandler.PrepareCreateOrder)       // 构建创建订单交易
+			orders.POST("/:id/tx/cancel", authRequired, chainRead, orderHandler.PrepareCancelOrder)   // 构建取消订单交易
+			orders.POST("/:id/tx/execute", authRequired, chainRead, orderHandler.PrepareExecuteOrder) // 构建执行订单交易
+			orders.POST("/:id/tx/edit", authRequired, chainRead, orderHandler.PrepareEditOrder)       // 构建修改订单交易
+			orders.POST("/market/buy", authRequired, chainRead, orderHandler.PrepareMarketBuy)        // 按最优卖单报价并构建市价买入交易
+			orders.POST("/market/sell", authRequired, chainRead, orderHandler.PrepareMarketSell)      // 按最优买单报价并构建市价卖出交易
 		}
 
 		// NFT相关路由
@@ -49,47 +91,52 @@ func SetupRoutes(router *gin.Engine, orderService *services.OrderService, nftSer
 			nfts.GET("/user/:address", nftHandler.GetUserNFTs)            // 获取用户NFT
 			nfts.GET("/contract/:contract", nftHandler.GetNFTsByContract) // 获取合约NFT
 			nfts.GET("/search", nftHandler.SearchNFTs)                    // 搜索NFT
-			nfts.POST("", nftHandler.CreateOrUpdateNFT)                   // 创建或更新NFT
+			nfts.POST("", authRequired, nftHandler.CreateOrUpdateNFT)     // 创建或更新NFT
 		}
 
 		// 集合相关路由
 		collections := v1.Group("/collections")
 		{
-			collections.POST("", collectionHandler.CreateCollection)                       // 创建集合
+			collections.POST("", authRequired, collectionHandler.CreateCollection)         // 创建集合
 			collections.GET("", collectionHandler.ListCollections)                         // 获取集合列表
 			collections.GET("/:id", collectionHandler.GetCollection)                       // 获取集合详情
 			collections.GET("/address/:address", collectionHandler.GetCollectionByAddress) // 根据地址获取集合
-			collections.PUT("/:id", collectionHandler.UpdateCollection)                    // 更新集合
-			collections.DELETE("/:id", collectionHandler.DeleteCollection)                 // 删除集合
+			collections.PUT("/:id", authRequired, collectionHandler.UpdateCollection)      // 更新集合
+			collections.DELETE("/:id", authRequired, collectionHandler.DeleteCollection)   // 删除集合
 		}
 
 		// 物品相关路由
 		items := v1.Group("/items")
 		{
-			items.POST("", itemHandler.CreateItem)                                               // 创建物品
-			items.GET("", itemHandler.ListItems)                                                 // 获取物品列表
-			items.GET("/id/:id", itemHandler.GetItem)                                            // 获取物品详情
-			items.GET("/token/:collection_address/:token_id", itemHandler.GetItemByTokenID)      // 根据代币ID获取物品
-			items.PUT("/id/:id", itemHandler.UpdateItem)                                         // 更新物品
-			items.PUT("/token/:collection_address/:token_id/owner", itemHandler.UpdateItemOwner) // 更新物品拥有者
-			items.PUT("/token/:collection_address/:token_id/price", itemHandler.UpdateItemPrice) // 更新物品价格
-			items.DELETE("/id/:id", itemHandler.DeleteItem)                                      // 删除物品
-			items.GET("/collection/:collection_address", itemHandler.GetItemsByCollection)       // 获取集合下的所有物品
-			items.GET("/owner/:owner", itemHandler.GetItemsByOwner)                              // 获取用户拥有的所有物品
+			items.POST("", authRequired, itemHandler.CreateItem)                                                              // 创建物品
+			items.GET("", itemHandler.ListItems)                                                                              // 获取物品列表
+			items.GET("/id/:id", itemHandler.GetItem)                                                                         // 获取物品详情
+			items.GET("/token/:collection_address/:token_id", itemHandler.GetItemByTokenID)                                   // 根据代币ID获取物品
+			items.PUT("/id/:id", authRequired, itemHandler.UpdateItem)                                                        // 更新物品
+			items.PUT("/token/:collection_address/:token_id/owner", authRequired, adminRequired, itemHandler.UpdateItemOwner) // 更新物品拥有者，拥有者由链上转移事件同步，仅管理员可手动修正
+			items.PUT("/token/:collection_address/:token_id/price", authRequired, itemHandler.UpdateItemPrice)                // 更新物品价格
+			items.GET("/token/:collection_address/:token_id/attributes", itemHandler.GetItemAttributes)                       // 获取物品特征
+			items.GET("/token/:collection_address/:token_id/balances", itemHandler.GetItemBalances)                           // 获取ERC-1155物品的持有数量
+			items.DELETE("/id/:id", authRequired, itemHandler.DeleteItem)                                                     // 删除物品
+			items.GET("/collection/:collection_address", itemHandler.GetItemsByCollection)                                    // 获取集合下的所有物品
+			items.GET("/owner/:owner", itemHandler.GetItemsByOwner)                                                           // 获取用户拥有的所有物品
+
+			// 物品特征只从tokenURI指向的元数据索引，由后台任务定期更新，管理员可手动触发重新索引
+			items.POST("/token/:collection_address/:token_id/attributes/refresh", authRequired, adminRequired, chainRead, itemHandler.RefreshItemAttributes)
 		}
 
 		// 活动相关路由
 		activities := v1.Group("/activities")
 		{
-			activities.POST("", activityHandler.CreateActivity)                                          // 创建活动
+			activities.POST("", authRequired, activityHandler.CreateActivity)                            // 创建活动
 			activities.GET("", activityHandler.ListActivities)                                           // 获取活动列表
 			activities.GET("/:id", activityHandler.GetActivity)                                          // 获取活动详情
 			activities.GET("/collection/:collection_address", activityHandler.GetActivitiesByCollection) // 获取集合的活动
 			activities.GET("/item/:collection_address/:token_id", activityHandler.GetActivitiesByItem)   // 获取物品的活动
 			activities.GET("/user/:user_address", activityHandler.GetActivitiesByUser)                   // 获取用户的活动
 			activities.GET("/recent", activityHandler.GetRecentActivities)                               // 获取最近的活动
-			activities.PUT("/:id", activityHandler.UpdateActivity)                                       // 更新活动
-			activities.DELETE("/:id", activityHandler.DeleteActivity)                                    // 删除活动
+			activities.PUT("/:id", authRequired, adminRequired, activityHandler.UpdateActivity)          // 更新活动，活动由链上事件生成，仅管理员可修改
+			activities.DELETE("/:id", authRequired, adminRequired, activityHandler.DeleteActivity)       // 删除活动
 			activities.GET("/stats", activityHandler.GetActivityStats)                                   // 获取活动统计
 		}
 
@@ -107,12 +154,24 @@ func SetupRoutes(router *gin.Engine, orderService *services.OrderService, nftSer
 		// 区块链管理相关路由
 		blockchain := v1.Group("/blockchain")
 		{
-			blockchain.GET("/status", blockchainHandler.GetBlockchainStatus)              // 获取区块链服务状态
-			blockchain.GET("/counter", blockchainHandler.GetOrderCounter)                 // 获取订单计数器
-			blockchain.GET("/order/:orderid", blockchainHandler.GetChainOrderInfo)        // 获取链上订单信息
-			blockchain.POST("/sync/order/:orderid", blockchainHandler.SyncOrderFromChain) // 同步单个订单
-			blockchain.POST("/sync/all", blockchainHandler.SyncAllOrdersFromChain)        // 同步所有订单
-			blockchain.POST("/execute/:orderid", blockchainHandler.ExecuteOrder)          // 执行订单
+			blockchain.GET("/status", blockchainHandler.GetBlockchainStatus)                                       // 获取区块链服务状态
+			blockchain.GET("/counter", chainRead, blockchainHandler.GetOrderCounter)                               // 获取订单计数器
+			blockchain.GET("/order/:orderid", chainRead, blockchainHandler.GetChainOrderInfo)                      // 获取链上订单信息
+			blockchain.GET("/transactions", chainRead, blockchainHandler.ListTransactions)                         // 获取后端发送的交易列表
+			blockchain.GET("/transactions/:hash", chainRead, blockchainHandler.GetTransaction)                     // 获取交易详情
+			blockchain.POST("/sync/order/:orderid", authRequired, chainRead, blockchainHandler.SyncOrderFromChain) // 同步单个订单
+
+			// 全量同步会逐个查询链上订单，仅管理员可用
+			blockchain.POST("/sync/all", authRequired, adminRequired, chainRead, blockchainHandler.SyncAllOrdersFromChain) // 同步所有订单
+
+			// 卡住交易处理，仅管理员可用
+			blockchain.GET("/stuck-transactions", authRequired, adminRequired, chainWrite, blockchainHandler.ListStuckTransactions)       // 获取卡住的交易
+			blockchain.POST("/transactions/:hash/speedup", authRequired, adminRequired, chainWrite, blockchainHandler.SpeedUpTransaction) // 加速交易
+			blockchain.POST("/transactions/:hash/cancel", authRequired, adminRequired, chainWrite, blockchainHandler.CancelTransaction)   // 取消交易
+
+			// 处理失败的链上日志，仅管理员可用
+			blockchain.GET("/failed-logs", authRequired, adminRequired, chainRead, blockchainHandler.ListFailedLogs)            // 获取处理失败的日志
+			blockchain.POST("/failed-logs/:id/retry", authRequired, adminRequired, chainRead, blockchainHandler.RetryFailedLog) // 人工重试死信日志
 		}
 	}
 }
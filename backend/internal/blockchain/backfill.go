@@ -0,0 +1,139 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// backfillBatchesPerRound 每轮同步中单个集合最多回溯的批次数，避免回溯历史长时间阻塞新事件的处理
+const backfillBatchesPerRound = 10
+
+// backfillCollections 回溯转移同步进度落后于主同步进度的集合，新登记的集合从其起始区块开始
+func (el *EventListener) backfillCollections() {
+	nextBlock := el.getNextBlock()
+
+	var collections []models.Collection
+	err := el.db.Where("sync_block IS NULL OR sync_block < ?", nextBlock).Find(&collections).Error
+	if err != nil {
+		logger.Error("查询待回溯集合失败", err)
+		el.setLastError(err)
+		return
+	}
+	if len(collections) == 0 {
+		return
+	}
+
+	el.detectTokenStandards()
+	for i := range collections {
+		if err := el.backfillCollection(&collections[i], nextBlock); err != nil {
+			logger.Error("回溯集合转移事件失败，等待下次重试", err, logrus.Fields{
+				"collection": collections[i].Address,
+			})
+			el.setLastError(err)
+		}
+
+		select {
+		case <-el.stopChan:
+			return
+		default:
+		}
+	}
+}
+
+// backfillCollection 按批次处理集合在[起始区块, nextBlock)内的转移事件，每批完成后保存进度
+// 已处理日志表保证重放的日志不会重复生效
+func (el *EventListener) backfillCollection(collection *models.Collection, nextBlock uint64) error {
+	from := el.config.StartBlock
+	if collection.StartBlock > 0 {
+		from = collection.StartBlock
+	}
+	if collection.SyncBlock != nil {
+		from = *collection.SyncBlock
+	}
+	// 起始区块晚于主同步进度时无需回溯，直接随主同步跟踪
+	if from > nextBlock {
+		from = nextBlock
+	}
+
+	saved := collection.SyncBlock
+	if common.IsHexAddress(collection.Address) {
+		address := common.HexToAddress(collection.Address)
+		blockRange := el.logBlockRange()
+		for batch := 0; batch < backfillBatchesPerRound && from < nextBlock; batch++ {
+			to := from + blockRange - 1
+			if to >= nextBlock {
+				to = nextBlock - 1
+			}
+			if err := el.backfillTransferBatch(address, from, to); err != nil {
+				return err
+			}
+			from = to + 1
+			if err := el.saveCollectionSync(collection.ID, from); err != nil {
+				return err
+			}
+			saved = &from
+		}
+	} else {
+		from = nextBlock
+	}
+
+	if saved == nil || *saved != from {
+		if err := el.saveCollectionSync(collection.ID, from); err != nil {
+			return err
+		}
+	}
+	if from == nextBlock && (collection.SyncBlock == nil || *collection.SyncBlock != from) {
+		logger.Info("集合转移事件回溯完成", logrus.Fields{
+			"collection": collection.Address,
+			"next_block": from,
+		})
+	}
+	return nil
+}
+
+// backfillTransferBatch 处理单个集合在区块区间内的转移事件
+func (el *EventListener) backfillTransferBatch(address common.Address, fromBlock, toBlock uint64) error {
+	logs, err := el.client.FilterLogs(context.Background(), ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: []common.Address{address},
+		Topics:    [][]common.Hash{{transferEventTopic, transferSingleTopic, transferBatchTopic}},
+	})
+	if err != nil {
+		return fmt.Errorf("获取转移日志失败: %v", err)
+	}
+
+	failedLogs, err := el.loadFailedLogs(fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+	for _, vLog := range logs {
+		if err := el.processLogIsolated(vLog, failedLogs[logKey{vLog.TxHash.Hex(), vLog.Index}]); err != nil {
+			return fmt.Errorf("处理转移事件失败 (tx: %s, log_index: %d): %v", vLog.TxHash.Hex(), vLog.Index, err)
+		}
+	}
+
+	logger.Debug("集合转移事件回溯批次完成", logrus.Fields{
+		"collection": address.Hex(),
+		"from_block": fromBlock,
+		"to_block":   toBlock,
+		"logs":       len(logs),
+	})
+	return nil
+}
+
+// saveCollectionSync 保存集合的转移同步进度
+func (el *EventListener) saveCollectionSync(id uint64, nextBlock uint64) error {
+	err := el.db.Model(&models.Collection{}).Where("id = ?", id).Update("sync_block", nextBlock).Error
+	if err != nil {
+		return fmt.Errorf("保存集合同步进度失败: %v", err)
+	}
+	return nil
+}
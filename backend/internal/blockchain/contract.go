@@ -2,7 +2,6 @@ package blockchain
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"nft-market/internal/logger"
@@ -14,7 +13,6 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/sirupsen/logrus"
 )
@@ -24,9 +22,12 @@ type NFTMarketplaceContract struct {
 	client          *ethclient.Client
 	contractAddress common.Address
 	contractABI     abi.ABI
-	privateKey      *ecdsa.PrivateKey
-	publicKey       *ecdsa.PublicKey
+	signer          Signer
 	fromAddress     common.Address
+	chainID         *big.Int
+	feeConfig       FeeConfig
+	nonceManager    *NonceManager
+	txTracker       *TransactionTracker
 }
 
 // ContractOrder 合约中的订单结构
@@ -68,6 +69,11 @@ type OrderCancelledEvent struct {
 	Maker   common.Address
 }
 
+// OrderExpiredEvent 订单过期事件
+type OrderExpiredEvent struct {
+	OrderId *big.Int
+}
+
 // NFTMarketplace合约ABI（简化版）
 const NFTMarketplaceABI = `[
 	{
@@ -105,6 +111,17 @@ const NFTMarketplaceABI = `[
 		"stateMutability": "nonpayable",
 		"type": "function"
 	},
+	{
+		"inputs": [
+			{"name": "_orderId", "type": "uint256"},
+			{"name": "_newPrice", "type": "uint256"},
+			{"name": "_newExpiration", "type": "uint256"}
+		],
+		"name": "editOrder",
+		"outputs": [],
+		"stateMutability": "payable",
+		"type": "function"
+	},
 	{
 		"inputs": [{"name": "_orderId", "type": "uint256"}],
 		"name": "executeOrder",
@@ -112,6 +129,53 @@ const NFTMarketplaceABI = `[
 		"stateMutability": "payable",
 		"type": "function"
 	},
+	{
+		"inputs": [{"name": "_orderId", "type": "uint256"}],
+		"name": "markOrderExpired",
+		"outputs": [],
+		"stateMutability": "nonpayable",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{"name": "_nftContract", "type": "address"},
+			{"name": "_tokenId", "type": "uint256"}
+		],
+		"name": "createMarketBuyOrder",
+		"outputs": [],
+		"stateMutability": "payable",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{"name": "_nftContract", "type": "address"},
+			{"name": "_tokenId", "type": "uint256"}
+		],
+		"name": "createMarketSellOrder",
+		"outputs": [],
+		"stateMutability": "nonpayable",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{"name": "_nftContract", "type": "address"},
+			{"name": "_tokenId", "type": "uint256"}
+		],
+		"name": "findBestBuyOrder",
+		"outputs": [{"name": "", "type": "uint256"}],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{"name": "_nftContract", "type": "address"},
+			{"name": "_tokenId", "type": "uint256"}
+		],
+		"name": "findBestSellOrder",
+		"outputs": [{"name": "", "type": "uint256"}],
+		"stateMutability": "view",
+		"type": "function"
+	},
 	{
 		"inputs": [{"name": "", "type": "uint256"}],
 		"name": "orders",
@@ -170,11 +234,19 @@ const NFTMarketplaceABI = `[
 		],
 		"name": "OrderCancelled",
 		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "orderId", "type": "uint256"}
+		],
+		"name": "OrderExpired",
+		"type": "event"
 	}
 ]`
 
 // NewNFTMarketplaceContract 创建新的NFT市场合约实例
-func NewNFTMarketplaceContract(rpcURL, contractAddr, privateKeyHex string) (*NFTMarketplaceContract, error) {
+func NewNFTMarketplaceContract(rpcURL, contractAddr string, signer Signer, feeConfig FeeConfig) (*NFTMarketplaceContract, error) {
 	// 连接到以太坊客户端
 	client, err := ethclient.Dial(rpcURL)
 	if err != nil {
@@ -190,37 +262,44 @@ func NewNFTMarketplaceContract(rpcURL, contractAddr, privateKeyHex string) (*NFT
 		return nil, fmt.Errorf("解析合约ABI失败: %v", err)
 	}
 
-	// 解析私钥（移除0x前缀如果存在）
-	if strings.HasPrefix(privateKeyHex, "0x") || strings.HasPrefix(privateKeyHex, "0X") {
-		privateKeyHex = privateKeyHex[2:]
-	}
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
-	if err != nil {
-		return nil, fmt.Errorf("解析私钥失败: %v", err)
+	// 签名账户即热钱包地址，未配置签名器时合约只读
+	var fromAddress common.Address
+	if signer != nil {
+		fromAddress = signer.Address()
 	}
 
-	// 获取公钥和地址
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("获取公钥失败")
+	chainID, err := client.ChainID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取链ID失败: %v", err)
 	}
 
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-
 	logger.Info("NFT市场合约初始化成功", logrus.Fields{
 		"contract_address": contractAddr,
 		"from_address":     fromAddress.Hex(),
 		"rpc_url":          rpcURL,
+		"read_only":        signer == nil,
 	})
 
+	// 启动时与节点同步nonce，失败时在首次发送前重试
+	nonceManager := NewNonceManager(client)
+	if signer != nil {
+		if err := nonceManager.Sync(context.Background(), fromAddress); err != nil {
+			logger.Warn("同步热钱包nonce失败", logrus.Fields{
+				"from_address": fromAddress.Hex(),
+				"error":        err.Error(),
+			})
+		}
+	}
+
 	return &NFTMarketplaceContract{
 		client:          client,
 		contractAddress: contractAddress,
 		contractABI:     contractABI,
-		privateKey:      privateKey,
-		publicKey:       publicKeyECDSA,
+		signer:          signer,
 		fromAddress:     fromAddress,
+		chainID:         chainID,
+		feeConfig:       feeConfig,
+		nonceManager:    nonceManager,
 	}, nil
 }
 
@@ -239,28 +318,41 @@ func (c *NFTMarketplaceContract) ContractABI() abi.ABI {
 	return c.contractABI
 }
 
+// ChainID 获取合约所在链的链ID
+func (c *NFTMarketplaceContract) ChainID() *big.Int {
+	return new(big.Int).Set(c.chainID)
+}
+
 // FromAddress 获取发送地址
 func (c *NFTMarketplaceContract) FromAddress() common.Address {
 	return c.fromAddress
 }
 
+// CanSign 是否配置了交易签名器
+func (c *NFTMarketplaceContract) CanSign() bool {
+	return c.signer != nil
+}
+
+// SetTransactionTracker 设置交易跟踪器，设置后发送的交易会被记录
+func (c *NFTMarketplaceContract) SetTransactionTracker(tracker *TransactionTracker) {
+	c.txTracker = tracker
+}
+
 // CreateLimitSellOrder 在链上创建限价卖单
-func (c *NFTMarketplaceContract) CreateLimitSellOrder(nftContract, tokenID string, price float64, expiration int64) (*types.Transaction, error) {
+func (c *NFTMarketplaceContract) CreateLimitSellOrder(nftContract, tokenID string, priceWei *big.Int, expiration int64) (*types.Transaction, error) {
 	logger.Info("开始创建链上限价卖单", logrus.Fields{
 		"nft_contract": nftContract,
 		"token_id":     tokenID,
-		"price":        price,
+		"price_wei":    priceWei.String(),
 		"expiration":   expiration,
 	})
 
 	// 准备交易参数
 	nftAddr := common.HexToAddress(nftContract)
-	tokenIDBig := new(big.Int)
-	tokenIDBig.SetString(tokenID, 10)
-
-	// 将价格从ETH转换为Wei
-	priceWei := new(big.Int)
-	priceWei.SetString(fmt.Sprintf("%.0f", price*1e18), 10)
+	tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的Token ID: %s", tokenID)
+	}
 
 	expirationBig := big.NewInt(expiration)
 
@@ -290,22 +382,20 @@ func (c *NFTMarketplaceContract) CreateLimitSellOrder(nftContract, tokenID strin
 }
 
 // CreateLimitBuyOrder 在链上创建限价买单
-func (c *NFTMarketplaceContract) CreateLimitBuyOrder(nftContract, tokenID string, price float64, expiration int64) (*types.Transaction, error) {
+func (c *NFTMarketplaceContract) CreateLimitBuyOrder(nftContract, tokenID string, priceWei *big.Int, expiration int64) (*types.Transaction, error) {
 	logger.Info("开始创建链上限价买单", logrus.Fields{
 		"nft_contract": nftContract,
 		"token_id":     tokenID,
-		"price":        price,
+		"price_wei":    priceWei.String(),
 		"expiration":   expiration,
 	})
 
 	// 准备交易参数
 	nftAddr := common.HexToAddress(nftContract)
-	tokenIDBig := new(big.Int)
-	tokenIDBig.SetString(tokenID, 10)
-
-	// 将价格从ETH转换为Wei
-	priceWei := new(big.Int)
-	priceWei.SetString(fmt.Sprintf("%.0f", price*1e18), 10)
+	tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的Token ID: %s", tokenID)
+	}
 
 	expirationBig := big.NewInt(expiration)
 
@@ -366,11 +456,73 @@ func (c *NFTMarketplaceContract) CancelOrder(orderID uint64) (*types.Transaction
 	return tx, nil
 }
 
+// EditOrder 在链上修改订单：合约取消原订单并以新价格和过期时间创建新订单
+// 修改买单时valueWei为新的出价金额，原订单托管的ETH由合约退还
+func (c *NFTMarketplaceContract) EditOrder(orderID uint64, newPriceWei *big.Int, newExpiration int64, valueWei *big.Int) (*types.Transaction, error) {
+	logger.Info("开始修改链上订单", logrus.Fields{
+		"order_id":       orderID,
+		"new_price_wei":  newPriceWei.String(),
+		"new_expiration": newExpiration,
+	})
+
+	// 获取交易选项
+	auth, err := c.getTransactOpts()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易选项失败: %v", err)
+	}
+	auth.Value = valueWei
+
+	// 调用合约方法
+	tx, err := c.callContract(auth, "editOrder", big.NewInt(int64(orderID)), newPriceWei, big.NewInt(newExpiration))
+	if err != nil {
+		logger.Error("修改链上订单失败", err, logrus.Fields{
+			"order_id": orderID,
+		})
+		return nil, err
+	}
+
+	logger.Info("链上订单修改成功", logrus.Fields{
+		"tx_hash":  tx.Hash().Hex(),
+		"order_id": orderID,
+	})
+
+	return tx, nil
+}
+
+// MarkOrderExpired 在链上标记过期订单，买单托管的ETH由合约退还给出价方
+func (c *NFTMarketplaceContract) MarkOrderExpired(orderID uint64) (*types.Transaction, error) {
+	logger.Info("开始标记链上过期订单", logrus.Fields{
+		"order_id": orderID,
+	})
+
+	// 获取交易选项
+	auth, err := c.getTransactOpts()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易选项失败: %v", err)
+	}
+
+	// 调用合约方法
+	tx, err := c.callContract(auth, "markOrderExpired", big.NewInt(int64(orderID)))
+	if err != nil {
+		logger.Error("标记链上过期订单失败", err, logrus.Fields{
+			"order_id": orderID,
+		})
+		return nil, err
+	}
+
+	logger.Info("链上过期订单标记成功", logrus.Fields{
+		"tx_hash":  tx.Hash().Hex(),
+		"order_id": orderID,
+	})
+
+	return tx, nil
+}
+
 // ExecuteOrder 在链上执行订单
-func (c *NFTMarketplaceContract) ExecuteOrder(orderID uint64, price float64) (*types.Transaction, error) {
+func (c *NFTMarketplaceContract) ExecuteOrder(orderID uint64, priceWei *big.Int) (*types.Transaction, error) {
 	logger.Info("开始执行链上订单", logrus.Fields{
-		"order_id": orderID,
-		"price":    price,
+		"order_id":  orderID,
+		"price_wei": priceWei.String(),
 	})
 
 	orderIDBig := big.NewInt(int64(orderID))
@@ -382,9 +534,7 @@ func (c *NFTMarketplaceContract) ExecuteOrder(orderID uint64, price float64) (*t
 	}
 
 	// 如果是买单执行，需要发送ETH
-	if price > 0 {
-		priceWei := new(big.Int)
-		priceWei.SetString(fmt.Sprintf("%.0f", price*1e18), 10)
+	if priceWei.Sign() > 0 {
 		auth.Value = priceWei
 	}
 
@@ -451,32 +601,51 @@ func (c *NFTMarketplaceContract) GetOrderCounter() (*big.Int, error) {
 	return result[0].(*big.Int), nil
 }
 
-// getTransactOpts 获取交易选项
-func (c *NFTMarketplaceContract) getTransactOpts() (*bind.TransactOpts, error) {
-	nonce, err := c.client.PendingNonceAt(context.Background(), c.fromAddress)
-	if err != nil {
-		return nil, fmt.Errorf("获取nonce失败: %v", err)
-	}
+// FindBestBuyOrder 查询合约中指定NFT出价最高的有效买单，没有时返回0
+func (c *NFTMarketplaceContract) FindBestBuyOrder(nftContract, tokenID string) (*big.Int, error) {
+	return c.findBestOrder("findBestBuyOrder", nftContract, tokenID)
+}
 
-	gasPrice, err := c.client.SuggestGasPrice(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("获取gas价格失败: %v", err)
+// FindBestSellOrder 查询合约中指定NFT价格最低的有效卖单，没有时返回0
+func (c *NFTMarketplaceContract) FindBestSellOrder(nftContract, tokenID string) (*big.Int, error) {
+	return c.findBestOrder("findBestSellOrder", nftContract, tokenID)
+}
+
+// findBestOrder 调用合约的最优订单查询方法
+func (c *NFTMarketplaceContract) findBestOrder(method, nftContract, tokenID string) (*big.Int, error) {
+	tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的Token ID: %s", tokenID)
 	}
 
-	chainID, err := c.client.NetworkID(context.Background())
+	result, err := c.callContractRead(method, common.HexToAddress(nftContract), tokenIDBig)
 	if err != nil {
-		return nil, fmt.Errorf("获取链ID失败: %v", err)
+		return nil, fmt.Errorf("查询最优订单失败: %v", err)
+	}
+	if len(result) == 0 {
+		return big.NewInt(0), nil
 	}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(c.privateKey, chainID)
-	if err != nil {
-		return nil, fmt.Errorf("创建交易签名器失败: %v", err)
+	return result[0].(*big.Int), nil
+}
+
+// getTransactOpts 获取交易选项
+// nonce由nonceManager在发送时分配，gas上限与手续费在callContract中按调用估算
+func (c *NFTMarketplaceContract) getTransactOpts() (*bind.TransactOpts, error) {
+	if c.signer == nil {
+		return nil, ErrSignerNotConfigured
 	}
 
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)
-	auth.GasLimit = uint64(300000)
-	auth.GasPrice = gasPrice
+	auth := &bind.TransactOpts{
+		From: c.fromAddress,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != c.fromAddress {
+				return nil, bind.ErrNotAuthorized
+			}
+			return c.signer.SignTx(tx, c.chainID)
+		},
+		Value: big.NewInt(0),
+	}
 
 	return auth, nil
 }
@@ -489,36 +658,58 @@ func (c *NFTMarketplaceContract) callContract(auth *bind.TransactOpts, method st
 		return nil, fmt.Errorf("编码合约调用失败: %v", err)
 	}
 
-	// 创建交易
-	tx := types.NewTransaction(
-		auth.Nonce.Uint64(),
-		c.contractAddress,
-		auth.Value,
-		auth.GasLimit,
-		auth.GasPrice,
-		data,
-	)
-
-	// 签名交易
-	chainID, err := c.client.NetworkID(context.Background())
+	// 估算gas上限与手续费，估算失败（如交易会revert）时不占用nonce
+	fees, err := c.feeConfig.suggestFees(context.Background(), c.client, auth.From, c.contractAddress, auth.Value, data)
 	if err != nil {
-		return nil, fmt.Errorf("获取链ID失败: %v", err)
+		return nil, err
 	}
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), c.privateKey)
+	// 分配nonce、签名并发送，同一热钱包的发送串行执行
+	var signedTx *types.Transaction
+	err = c.nonceManager.Send(context.Background(), c.fromAddress, func(nonce uint64) error {
+		tx := fees.newTransaction(c.chainID, nonce, c.contractAddress, auth.Value, data)
+
+		signed, err := auth.Signer(auth.From, tx)
+		if err != nil {
+			return fmt.Errorf("签名交易失败: %v", err)
+		}
+
+		signedTx = signed
+		if err := c.client.SendTransaction(context.Background(), signed); err != nil {
+			return fmt.Errorf("发送交易失败: %v", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("签名交易失败: %v", err)
+		return nil, err
 	}
 
-	// 发送交易
-	err = c.client.SendTransaction(context.Background(), signedTx)
-	if err != nil {
-		return nil, fmt.Errorf("发送交易失败: %v", err)
+	// 记录交易，记录失败不影响已发送的交易
+	if c.txTracker != nil {
+		if err := c.txTracker.Record(signedTx, c.fromAddress, method, relatedOrderID(method, params)); err != nil {
+			logger.Error("记录交易失败", err, logrus.Fields{
+				"tx_hash": signedTx.Hash().Hex(),
+				"method":  method,
+			})
+		}
 	}
 
 	return signedTx, nil
 }
 
+// relatedOrderID 从合约调用参数中取出关联的订单ID，创建订单类方法返回nil
+func relatedOrderID(method string, params []interface{}) *big.Int {
+	switch method {
+	case "cancelOrder", "executeOrder", "editOrder", "markOrderExpired":
+		if len(params) > 0 {
+			if id, ok := params[0].(*big.Int); ok {
+				return id
+			}
+		}
+	}
+	return nil
+}
+
 // callContractRead 调用合约只读方法
 func (c *NFTMarketplaceContract) callContractRead(method string, params ...interface{}) ([]interface{}, error) {
 	// 编码方法调用
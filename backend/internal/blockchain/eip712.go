@@ -0,0 +1,111 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	// orderDomainName EIP-712域名称
+	orderDomainName = "NFTMarketplace"
+	// orderDomainVersion EIP-712域版本
+	orderDomainVersion = "1"
+	// orderPrimaryType 订单的EIP-712主类型
+	orderPrimaryType = "Order"
+)
+
+// orderTypes 市场订单的EIP-712类型定义，orderType与合约一致(0:限价卖单,1:限价买单)
+var orderTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	orderPrimaryType: {
+		{Name: "maker", Type: "address"},
+		{Name: "nftContract", Type: "address"},
+		{Name: "tokenId", Type: "uint256"},
+		{Name: "price", Type: "uint256"},
+		{Name: "expiration", Type: "uint256"},
+		{Name: "orderType", Type: "uint8"},
+		{Name: "salt", Type: "uint256"},
+	},
+}
+
+// SignedOrder 链下签名订单的EIP-712消息内容
+type SignedOrder struct {
+	Maker       common.Address
+	NftContract common.Address
+	TokenId     *big.Int
+	Price       *big.Int
+	Expiration  int64
+	OrderType   uint8
+	Salt        *big.Int
+}
+
+// OrderTypedData 构建供钱包eth_signTypedData_v4签名的订单类型化数据，域绑定链ID和市场合约地址
+func OrderTypedData(chainID *big.Int, verifyingContract common.Address, order *SignedOrder) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       orderTypes,
+		PrimaryType: orderPrimaryType,
+		Domain: apitypes.TypedDataDomain{
+			Name:              orderDomainName,
+			Version:           orderDomainVersion,
+			ChainId:           (*math.HexOrDecimal256)(chainID),
+			VerifyingContract: verifyingContract.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"maker":       order.Maker.Hex(),
+			"nftContract": order.NftContract.Hex(),
+			"tokenId":     order.TokenId.String(),
+			"price":       order.Price.String(),
+			"expiration":  big.NewInt(order.Expiration).String(),
+			"orderType":   big.NewInt(int64(order.OrderType)).String(),
+			"salt":        order.Salt.String(),
+		},
+	}
+}
+
+// HashOrder 计算订单的EIP-712摘要，同时作为链下订单的唯一标识
+func HashOrder(typedData apitypes.TypedData) (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("计算订单EIP-712摘要失败: %v", err)
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// RecoverOrderSigner 从EIP-712签名中恢复订单签名者地址
+func RecoverOrderSigner(typedData apitypes.TypedData, signature string) (common.Address, error) {
+	hash, err := HashOrder(typedData)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	sig, err := hexutil.Decode(strings.TrimSpace(signature))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("签名格式错误: %v", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("签名长度错误: %d", len(sig))
+	}
+
+	// 钱包返回的v值为27/28，SigToPub要求0/1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(hash.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("恢复签名公钥失败: %v", err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
@@ -0,0 +1,130 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func testTypedData(maker common.Address) apitypes.TypedData {
+	return OrderTypedData(big.NewInt(1), common.HexToAddress("0x00000000000000000000000000000000000000cc"), &SignedOrder{
+		Maker:       maker,
+		NftContract: common.HexToAddress("0x00000000000000000000000000000000000000bb"),
+		TokenId:     big.NewInt(42),
+		Price:       new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil),
+		Expiration:  1700000000,
+		OrderType:   1,
+		Salt:        big.NewInt(7),
+	})
+}
+
+// signTypedData 按钱包eth_signTypedData_v4的格式签名，v值为27/28
+func signTypedData(t *testing.T, key *ecdsa.PrivateKey, typedData apitypes.TypedData) []byte {
+	t.Helper()
+	hash, err := HashOrder(typedData)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig
+}
+
+func TestHashOrder(t *testing.T) {
+	maker := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	hash, err := HashOrder(testTypedData(maker))
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	// 按EIP-712规范手工编码：keccak256("\x19\x01" || domainSeparator || hashStruct(order))
+	word := func(v *big.Int) []byte { return math.U256Bytes(new(big.Int).Set(v)) }
+	address := func(a common.Address) []byte { return common.LeftPadBytes(a.Bytes(), 32) }
+
+	domainSeparator := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)")),
+		crypto.Keccak256([]byte("NFTMarketplace")),
+		crypto.Keccak256([]byte("1")),
+		word(big.NewInt(1)),
+		address(common.HexToAddress("0x00000000000000000000000000000000000000cc")),
+	)
+	structHash := crypto.Keccak256(
+		crypto.Keccak256([]byte("Order(address maker,address nftContract,uint256 tokenId,uint256 price,uint256 expiration,uint8 orderType,uint256 salt)")),
+		address(maker),
+		address(common.HexToAddress("0x00000000000000000000000000000000000000bb")),
+		word(big.NewInt(42)),
+		word(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)),
+		word(big.NewInt(1700000000)),
+		word(big.NewInt(1)),
+		word(big.NewInt(7)),
+	)
+	want := crypto.Keccak256Hash([]byte("\x19\x01"), domainSeparator, structHash)
+	if hash != want {
+		t.Fatalf("hash = %s, want %s", hash.Hex(), want.Hex())
+	}
+
+	// 域绑定链ID，其他链上的签名不能重放
+	other := testTypedData(maker)
+	other.Domain.ChainId = math.NewHexOrDecimal256(5)
+	otherHash, err := HashOrder(other)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if otherHash == hash {
+		t.Fatal("hash does not depend on chain id")
+	}
+}
+
+func TestRecoverOrderSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	maker := crypto.PubkeyToAddress(key.PublicKey)
+	typedData := testTypedData(maker)
+	sig := signTypedData(t, key, typedData)
+
+	// 钱包返回27/28，部分签名库返回0/1，两种v值都应恢复出同一地址
+	for _, v := range []byte{sig[crypto.RecoveryIDOffset], sig[crypto.RecoveryIDOffset] - 27} {
+		s := append([]byte(nil), sig...)
+		s[crypto.RecoveryIDOffset] = v
+		signer, err := RecoverOrderSigner(typedData, hexutil.Encode(s))
+		if err != nil {
+			t.Fatalf("v=%d: %v", v, err)
+		}
+		if signer != maker {
+			t.Fatalf("v=%d: signer = %s, want %s", v, signer.Hex(), maker.Hex())
+		}
+	}
+
+	// 其他账户的签名恢复出的是该账户，而不是订单maker
+	otherKey, _ := crypto.GenerateKey()
+	signer, err := RecoverOrderSigner(typedData, hexutil.Encode(signTypedData(t, otherKey, typedData)))
+	if err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	if signer == maker || signer != crypto.PubkeyToAddress(otherKey.PublicKey) {
+		t.Fatalf("wrong signer recovered as %s", signer.Hex())
+	}
+
+	// 修改订单内容后原签名不再对应maker
+	tampered := testTypedData(maker)
+	tampered.Message["price"] = "1"
+	signer, err = RecoverOrderSigner(tampered, hexutil.Encode(sig))
+	if err == nil && signer == maker {
+		t.Fatal("signature over a different price recovered the maker")
+	}
+
+	if _, err := RecoverOrderSigner(typedData, "0x1234"); err == nil {
+		t.Fatal("expected error for short signature")
+	}
+	if _, err := RecoverOrderSigner(typedData, "not-hex"); err == nil {
+		t.Fatal("expected error for malformed signature")
+	}
+}
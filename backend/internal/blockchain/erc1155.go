@@ -0,0 +1,264 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ERC1155ABI 校验挂单、索引转移事件和元数据所需的ERC-1155方法与事件，以及ERC-165接口检测
+const ERC1155ABI = `[
+	{
+		"inputs": [{"name": "id", "type": "uint256"}],
+		"name": "uri",
+		"outputs": [{"name": "", "type": "string"}],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [{"name": "interfaceId", "type": "bytes4"}],
+		"name": "supportsInterface",
+		"outputs": [{"name": "", "type": "bool"}],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{"name": "account", "type": "address"},
+			{"name": "id", "type": "uint256"}
+		],
+		"name": "balanceOf",
+		"outputs": [{"name": "", "type": "uint256"}],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{"name": "account", "type": "address"},
+			{"name": "operator", "type": "address"}
+		],
+		"name": "isApprovedForAll",
+		"outputs": [{"name": "", "type": "bool"}],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "operator", "type": "address"},
+			{"indexed": true, "name": "from", "type": "address"},
+			{"indexed": true, "name": "to", "type": "address"},
+			{"indexed": false, "name": "id", "type": "uint256"},
+			{"indexed": false, "name": "value", "type": "uint256"}
+		],
+		"name": "TransferSingle",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "operator", "type": "address"},
+			{"indexed": true, "name": "from", "type": "address"},
+			{"indexed": true, "name": "to", "type": "address"},
+			{"indexed": false, "name": "ids", "type": "uint256[]"},
+			{"indexed": false, "name": "values", "type": "uint256[]"}
+		],
+		"name": "TransferBatch",
+		"type": "event"
+	}
+]`
+
+// erc1155ABI 解析后的ERC-1155 ABI
+var erc1155ABI = mustParseABI(ERC1155ABI)
+
+var (
+	// erc721InterfaceID ERC-721的ERC-165接口ID
+	erc721InterfaceID = [4]byte{0x80, 0xac, 0x58, 0xcd}
+	// erc1155InterfaceID ERC-1155的ERC-165接口ID
+	erc1155InterfaceID = [4]byte{0xd9, 0xb6, 0x7a, 0x26}
+)
+
+// SupportsInterface 通过ERC-165 supportsInterface查询合约是否实现指定接口
+func (c *NFTMarketplaceContract) SupportsInterface(nftContract common.Address, interfaceID [4]byte) (bool, error) {
+	result, err := c.callABI(erc1155ABI, nftContract, "supportsInterface", interfaceID)
+	if err != nil {
+		return false, err
+	}
+	return result[0].(bool), nil
+}
+
+// DetectTokenStandard 通过ERC-165检测集合合约的代币标准，两种接口都未声明时按ERC-721处理
+func (c *NFTMarketplaceContract) DetectTokenStandard(nftContract common.Address) (models.TokenStandard, error) {
+	is1155, err := c.SupportsInterface(nftContract, erc1155InterfaceID)
+	if err != nil {
+		return models.TokenStandardUnknown, err
+	}
+	if is1155 {
+		return models.TokenStandardERC1155, nil
+	}
+
+	if _, err := c.SupportsInterface(nftContract, erc721InterfaceID); err != nil {
+		return models.TokenStandardUnknown, err
+	}
+	return models.TokenStandardERC721, nil
+}
+
+// BalanceOf1155 通过eth_call查询账户持有的ERC-1155 token数量
+func (c *NFTMarketplaceContract) BalanceOf1155(nftContract, account common.Address, tokenID *big.Int) (*big.Int, error) {
+	result, err := c.callABI(erc1155ABI, nftContract, "balanceOf", account, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	return result[0].(*big.Int), nil
+}
+
+// IsApprovedForAll1155 检查拥有者是否已授权市场合约转移其全部ERC-1155 token
+func (c *NFTMarketplaceContract) IsApprovedForAll1155(nftContract, owner common.Address) (bool, error) {
+	result, err := c.callABI(erc1155ABI, nftContract, "isApprovedForAll", owner, c.contractAddress)
+	if err != nil {
+		return false, err
+	}
+	return result[0].(bool), nil
+}
+
+// ResolveTokenStandard 返回集合的代币标准：已记录时直接使用，未记录时通过ERC-165检测并保存
+// 集合未登记、链上不可读或检测失败时按ERC-721处理且不保存，下次重新检测
+func ResolveTokenStandard(db *gorm.DB, contract *NFTMarketplaceContract, collectionAddress string) models.TokenStandard {
+	var collection models.Collection
+	err := db.Select("id", "standard").Where("address = ?", collectionAddress).First(&collection).Error
+	if err == nil && collection.Standard != models.TokenStandardUnknown {
+		return collection.Standard
+	}
+	if contract == nil || !common.IsHexAddress(collectionAddress) {
+		return models.TokenStandardERC721
+	}
+
+	standard, detectErr := contract.DetectTokenStandard(common.HexToAddress(collectionAddress))
+	if detectErr != nil {
+		logger.Warn("检测集合代币标准失败", logrus.Fields{
+			"collection": collectionAddress,
+			"error":      detectErr.Error(),
+		})
+		return models.TokenStandardERC721
+	}
+	if err == nil {
+		if err := db.Model(&models.Collection{}).Where("id = ?", collection.ID).Update("standard", standard).Error; err != nil {
+			logger.Error("保存集合代币标准失败", err, logrus.Fields{
+				"collection": collectionAddress,
+			})
+		}
+	}
+	return standard
+}
+
+// detectTokenStandards 检测尚未记录代币标准的集合，确定是否需要按ERC-1155索引
+func (el *EventListener) detectTokenStandards() {
+	var addresses []string
+	err := el.db.Model(&models.Collection{}).
+		Where("standard = ?", models.TokenStandardUnknown).
+		Pluck("address", &addresses).Error
+	if err != nil {
+		logger.Error("查询待检测集合失败", err)
+		return
+	}
+	for _, address := range addresses {
+		ResolveTokenStandard(el.db, el.contract, address)
+	}
+}
+
+// GetItemBalance 查询拥有者持有的ERC-1155 token数量
+func GetItemBalance(db *gorm.DB, collectionAddress, tokenID, owner string) (int64, error) {
+	var balance models.ItemBalance
+	err := db.Where("collection_address = ? AND token_id = ? AND owner = ?", collectionAddress, tokenID, owner).
+		First(&balance).Error
+	switch err {
+	case nil:
+		return balance.Balance, nil
+	case gorm.ErrRecordNotFound:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("查询持有数量失败: %v", err)
+	}
+}
+
+// AdjustItemBalance 按变化量调整拥有者持有的ERC-1155 token数量，结果不低于0
+func AdjustItemBalance(db *gorm.DB, collectionAddress, tokenID, owner string, delta int64) error {
+	now := time.Now().Unix()
+	initial := delta
+	if initial < 0 {
+		initial = 0
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "collection_address"}, {Name: "token_id"}, {Name: "owner"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"balance":     gorm.Expr("GREATEST(balance + ?, 0)", delta),
+			"update_time": now,
+			"updated_at":  time.Now(),
+		}),
+	}).Create(&models.ItemBalance{
+		CollectionAddress: collectionAddress,
+		TokenID:           tokenID,
+		Owner:             owner,
+		Balance:           initial,
+		CreateTime:        &now,
+		UpdateTime:        &now,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("更新持有数量失败: %v", err)
+	}
+	return nil
+}
+
+// UpdateListingBalanceValidity 按拥有者当前持有数量校验其ERC-1155挂单：剩余数量超过持有数量时标记失效，持有数量恢复后重新生效
+func UpdateListingBalanceValidity(db *gorm.DB, collectionAddress, tokenID, owner string) error {
+	balance, err := GetItemBalance(db, collectionAddress, tokenID, owner)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	invalidated := db.Model(&models.Order{}).
+		Where("collection_address = ? AND token_id = ? AND order_type = ? AND order_status IN ? AND maker = ? AND quantity_remaining > ? AND invalid = ?",
+			collectionAddress, tokenID, models.OrderTypeListing,
+			[]models.OrderStatus{models.OrderStatusActive, models.OrderStatusPending}, owner, balance, false).
+		Updates(map[string]interface{}{
+			"invalid":        true,
+			"invalid_reason": models.OrderInvalidReasonNotOwner,
+			"update_time":    now,
+		})
+	if invalidated.Error != nil {
+		return fmt.Errorf("标记失效挂单失败: %v", invalidated.Error)
+	}
+
+	restored := db.Model(&models.Order{}).
+		Where("collection_address = ? AND token_id = ? AND order_type = ? AND maker = ? AND quantity_remaining <= ? AND invalid = ? AND invalid_reason = ?",
+			collectionAddress, tokenID, models.OrderTypeListing, owner, balance, true, models.OrderInvalidReasonNotOwner).
+		Updates(map[string]interface{}{
+			"invalid":        false,
+			"invalid_reason": nil,
+			"update_time":    now,
+		})
+	if restored.Error != nil {
+		return fmt.Errorf("恢复挂单失败: %v", restored.Error)
+	}
+
+	if invalidated.RowsAffected > 0 || restored.RowsAffected > 0 {
+		logger.Info("持有数量变化，已更新挂单有效性", logrus.Fields{
+			"collection":  collectionAddress,
+			"token_id":    tokenID,
+			"owner":       owner,
+			"balance":     balance,
+			"invalidated": invalidated.RowsAffected,
+			"restored":    restored.RowsAffected,
+		})
+		return refreshItemListPrice(db, itemKey{collectionAddress, tokenID})
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ERC721ABI 校验挂单和索引元数据所需的ERC-721只读方法
+const ERC721ABI = `[
+	{
+		"inputs": [{"name": "tokenId", "type": "uint256"}],
+		"name": "tokenURI",
+		"outputs": [{"name": "", "type": "string"}],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [{"name": "tokenId", "type": "uint256"}],
+		"name": "ownerOf",
+		"outputs": [{"name": "", "type": "address"}],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [{"name": "tokenId", "type": "uint256"}],
+		"name": "getApproved",
+		"outputs": [{"name": "", "type": "address"}],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{"name": "owner", "type": "address"},
+			{"name": "operator", "type": "address"}
+		],
+		"name": "isApprovedForAll",
+		"outputs": [{"name": "", "type": "bool"}],
+		"stateMutability": "view",
+		"type": "function"
+	}
+]`
+
+// erc721ABI 解析后的ERC-721 ABI
+var erc721ABI = mustParseABI(ERC721ABI)
+
+// mustParseABI 解析内置ABI，格式错误属于编码问题直接panic
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(fmt.Sprintf("解析内置ABI失败: %v", err))
+	}
+	return parsed
+}
+
+// OwnerOf 通过eth_call查询NFT当前拥有者
+func (c *NFTMarketplaceContract) OwnerOf(nftContract common.Address, tokenID *big.Int) (common.Address, error) {
+	result, err := c.callERC721(nftContract, "ownerOf", tokenID)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return result[0].(common.Address), nil
+}
+
+// IsApprovedForMarketplace 检查拥有者是否已授权市场合约转移该NFT
+// 优先检查isApprovedForAll，未全部授权时再检查单个token的getApproved
+func (c *NFTMarketplaceContract) IsApprovedForMarketplace(nftContract, owner common.Address, tokenID *big.Int) (bool, error) {
+	result, err := c.callERC721(nftContract, "isApprovedForAll", owner, c.contractAddress)
+	if err != nil {
+		return false, err
+	}
+	if result[0].(bool) {
+		return true, nil
+	}
+
+	result, err = c.callERC721(nftContract, "getApproved", tokenID)
+	if err != nil {
+		return false, err
+	}
+	return result[0].(common.Address) == c.contractAddress, nil
+}
+
+// BalanceAt 查询账户最新区块的ETH余额
+func (c *NFTMarketplaceContract) BalanceAt(account common.Address) (*big.Int, error) {
+	balance, err := c.client.BalanceAt(context.Background(), account, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询账户余额失败: %v", err)
+	}
+	return balance, nil
+}
+
+// callERC721 调用ERC-721合约只读方法
+func (c *NFTMarketplaceContract) callERC721(nftContract common.Address, method string, params ...interface{}) ([]interface{}, error) {
+	return c.callABI(erc721ABI, nftContract, method, params...)
+}
+
+// callABI 按给定ABI调用集合合约只读方法
+func (c *NFTMarketplaceContract) callABI(contractABI abi.ABI, nftContract common.Address, method string, params ...interface{}) ([]interface{}, error) {
+	data, err := contractABI.Pack(method, params...)
+	if err != nil {
+		return nil, fmt.Errorf("编码%s调用失败: %v", method, err)
+	}
+
+	output, err := c.client.CallContract(context.Background(), ethereum.CallMsg{
+		To:   &nftContract,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("调用%s失败: %v", method, err)
+	}
+
+	result, err := contractABI.Unpack(method, output)
+	if err != nil {
+		return nil, fmt.Errorf("解码%s返回值失败: %v", method, err)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%s返回值为空", method)
+	}
+	return result, nil
+}
@@ -2,28 +2,105 @@ package blockchain
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"nft-market/internal/logger"
 	"nft-market/internal/models"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const (
+	// defaultLogBlockRange 未配置时每次查询日志的区块数量
+	defaultLogBlockRange = uint64(1000)
+	// defaultPollInterval 未配置时轮询模式的查询间隔
+	defaultPollInterval = 15 * time.Second
+	// maxBatchRetries 单批次最大尝试次数
+	maxBatchRetries = 5
+	// initialRetryBackoff 首次重试等待时间，之后指数增长
+	initialRetryBackoff = time.Second
+	// maxRetryBackoff 重试等待时间上限
+	maxRetryBackoff = 30 * time.Second
+	// catchUpInterval 无新事件时定期推进同步进度的间隔
+	catchUpInterval = 15 * time.Second
+	// methodNotFoundCode JSON-RPC方法不存在的错误码
+	methodNotFoundCode = -32601
+	// reorgHistoryBlocks 保留用于检测链重组的已索引区块数量
+	reorgHistoryBlocks = 256
+)
+
+// 事件获取方式
+const (
+	IngestionModeSubscription = "subscription" // 通过eth_subscribe订阅新日志触发同步
+	IngestionModePolling      = "polling"      // 节点不支持订阅时定期按区块区间查询日志
+)
+
+// ListenerConfig 事件监听器配置
+type ListenerConfig struct {
+	// StartBlock 数据库中没有同步记录时的起始区块（通常为合约部署区块）
+	StartBlock uint64
+	// ConfirmationDepth 确认深度，只处理距链头至少该数量区块的事件
+	ConfirmationDepth uint64
+	// PollInterval 轮询模式的查询间隔，订阅模式下也按该间隔推进同步进度，0使用默认值
+	PollInterval time.Duration
+	// LogBlockRange 每次FilterLogs查询的最大区块数量，0使用默认值
+	LogBlockRange uint64
+}
+
 // EventListener 事件监听器
 type EventListener struct {
 	client          *ethclient.Client
 	contractAddress common.Address
 	contract        *NFTMarketplaceContract
 	db              *gorm.DB
-	stopChan        chan bool
+	config          ListenerConfig
+	chainID         uint64
+	stopChan        chan struct{}
 	isRunning       bool
+
+	mu            sync.RWMutex
+	nextBlock     uint64 // 下一个待处理的区块
+	syncing       bool
+	mode          string
+	reconnects    uint64
+	lastSyncTime  int64
+	lastError     string
+	lastErrorTime int64
+	reorgCount    uint64
+	lastReorgFrom int64
+	lastReorgTime int64
+}
+
+// IndexerStatus 索引器同步状态
+type IndexerStatus struct {
+	Running         bool   `json:"running"`
+	Syncing         bool   `json:"syncing"`
+	Mode            string `json:"mode"`       // 事件获取方式: subscription或polling
+	Reconnects      uint64 `json:"reconnects"` // 订阅中断后重连的次数
+	ContractAddress string `json:"contract_address"`
+	LastSyncedBlock int64  `json:"last_synced_block"` // -1表示尚未同步任何区块
+	ChainHead       uint64 `json:"chain_head"`
+	Lag             uint64 `json:"lag"`
+	// ConfirmationDepth 确认深度，Lag不会小于该值
+	ConfirmationDepth uint64 `json:"confirmation_depth"`
+	ReorgCount        uint64 `json:"reorg_count"`
+	LastReorgFrom     int64  `json:"last_reorg_from,omitempty"` // 最近一次重组回滚的起始区块
+	LastReorgTime     int64  `json:"last_reorg_time,omitempty"`
+	LastSyncTime      int64  `json:"last_sync_time,omitempty"`
+	LastError         string `json:"last_error,omitempty"`
+	LastErrorTime     int64  `json:"last_error_time,omitempty"`
 }
 
 // EventHandler 事件处理接口
@@ -31,29 +108,47 @@ type EventHandler interface {
 	HandleOrderCreated(event *OrderCreatedEvent, tx *types.Transaction, receipt *types.Receipt) error
 	HandleOrderFilled(event *OrderFilledEvent, tx *types.Transaction, receipt *types.Receipt) error
 	HandleOrderCancelled(event *OrderCancelledEvent, tx *types.Transaction, receipt *types.Receipt) error
+	HandleOrderExpired(event *OrderExpiredEvent, tx *types.Transaction, receipt *types.Receipt) error
 }
 
 // NewEventListener 创建新的事件监听器
-func NewEventListener(client *ethclient.Client, contractAddress string, contract *NFTMarketplaceContract, db *gorm.DB) *EventListener {
+func NewEventListener(client *ethclient.Client, contractAddress string, contract *NFTMarketplaceContract, db *gorm.DB, config ListenerConfig) *EventListener {
 	return &EventListener{
 		client:          client,
 		contractAddress: common.HexToAddress(contractAddress),
 		contract:        contract,
 		db:              db,
-		stopChan:        make(chan bool),
+		config:          config,
+		stopChan:        make(chan struct{}),
 		isRunning:       false,
+		nextBlock:       config.StartBlock,
 	}
 }
 
 // Start 开始监听事件
 func (el *EventListener) Start() error {
-	if el.isRunning {
+	if el.IsRunning() {
 		return fmt.Errorf("事件监听器已在运行")
 	}
 
+	chainID, err := el.client.ChainID(context.Background())
+	if err != nil {
+		return fmt.Errorf("获取链ID失败: %v", err)
+	}
+	el.chainID = chainID.Uint64()
+
+	// 从数据库恢复同步进度
+	if err := el.loadSyncState(); err != nil {
+		return err
+	}
+
+	el.mu.Lock()
 	el.isRunning = true
+	el.mu.Unlock()
+	el.stopChan = make(chan struct{})
 	logger.Info("开始监听合约事件", logrus.Fields{
 		"contract_address": el.contractAddress.Hex(),
+		"from_block":       el.getNextBlock(),
 	})
 
 	go el.listenForEvents()
@@ -62,96 +157,274 @@ func (el *EventListener) Start() error {
 
 // Stop 停止监听事件
 func (el *EventListener) Stop() {
+	el.mu.Lock()
 	if !el.isRunning {
+		el.mu.Unlock()
 		return
 	}
+	el.isRunning = false
+	el.mu.Unlock()
 
 	logger.Info("停止事件监听器")
-	el.stopChan <- true
-	el.isRunning = false
+	close(el.stopChan)
+}
+
+// IsRunning 事件监听器是否正在运行，请求处理中会并发调用，需在锁内读取
+func (el *EventListener) IsRunning() bool {
+	el.mu.RLock()
+	defer el.mu.RUnlock()
+	return el.isRunning
+}
+
+// Status 获取索引器同步状态
+func (el *EventListener) Status(chainHead uint64) IndexerStatus {
+	el.mu.RLock()
+	defer el.mu.RUnlock()
+
+	status := IndexerStatus{
+		Running:           el.isRunning,
+		Syncing:           el.syncing,
+		Mode:              el.mode,
+		Reconnects:        el.reconnects,
+		ContractAddress:   el.contractAddress.Hex(),
+		LastSyncedBlock:   int64(el.nextBlock) - 1,
+		ChainHead:         chainHead,
+		ConfirmationDepth: el.config.ConfirmationDepth,
+		ReorgCount:        el.reorgCount,
+		LastReorgFrom:     el.lastReorgFrom,
+		LastReorgTime:     el.lastReorgTime,
+		LastSyncTime:      el.lastSyncTime,
+		LastError:         el.lastError,
+		LastErrorTime:     el.lastErrorTime,
+	}
+	if chainHead+1 > el.nextBlock {
+		status.Lag = chainHead + 1 - el.nextBlock
+	}
+	return status
 }
 
 // listenForEvents 监听合约事件
+// 优先订阅新日志，订阅中断时按指数退避重连；节点不支持订阅（如HTTP端点）时改为轮询
 func (el *EventListener) listenForEvents() {
-	// 创建事件查询
+	// 先补齐历史事件
+	el.catchUp()
+
+	backoff := initialRetryBackoff
+	for {
+		subscribed, err := el.subscribeForEvents()
+		if err == nil {
+			return
+		}
+		if isSubscriptionUnsupported(err) {
+			logger.Info("节点不支持事件订阅，改为轮询获取事件", logrus.Fields{
+				"poll_interval": el.pollInterval().String(),
+				"block_range":   el.logBlockRange(),
+			})
+			el.pollForEvents()
+			return
+		}
+
+		el.setLastError(err)
+		if subscribed {
+			backoff = initialRetryBackoff
+		}
+		logger.Warn("事件订阅中断，准备重连", logrus.Fields{
+			"backoff": backoff.String(),
+			"error":   err.Error(),
+		})
+
+		// 等待重连期间继续按区块区间推进同步，避免遗漏事件
+		select {
+		case <-time.After(backoff):
+		case <-el.stopChan:
+			return
+		}
+		el.catchUp()
+		el.mu.Lock()
+		el.reconnects++
+		el.mu.Unlock()
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// subscribeForEvents 订阅合约日志直到停止或订阅出错
+// 新日志只作为触发信号，实际处理统一走按区块区间的同步，保证进度连续；
+// 返回的subscribed表示订阅是否建立成功，停止时返回nil错误
+func (el *EventListener) subscribeForEvents() (bool, error) {
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{el.contractAddress},
 	}
 
-	// 订阅日志
 	logs := make(chan types.Log)
 	sub, err := el.client.SubscribeFilterLogs(context.Background(), query, logs)
 	if err != nil {
-		logger.Error("订阅合约事件失败", err)
-		return
+		return false, fmt.Errorf("订阅合约事件失败: %w", err)
 	}
 	defer sub.Unsubscribe()
 
-	// 获取当前最新区块号
-	latestBlock, err := el.client.BlockNumber(context.Background())
-	if err != nil {
-		logger.Error("获取最新区块号失败", err)
-		return
-	}
-
-	// 同步历史事件
-	go el.syncHistoricalEvents(latestBlock)
-
+	el.setMode(IngestionModeSubscription)
 	logger.Info("事件监听器启动成功", logrus.Fields{
-		"latest_block": latestBlock,
+		"mode":       IngestionModeSubscription,
+		"next_block": el.getNextBlock(),
 	})
 
+	ticker := time.NewTicker(el.pollInterval())
+	defer ticker.Stop()
+
 	for {
 		select {
 		case err := <-sub.Err():
-			logger.Error("事件订阅错误", err)
-			return
+			if err == nil {
+				err = fmt.Errorf("事件订阅已关闭")
+			}
+			return true, err
 
 		case vLog := <-logs:
-			if err := el.processLog(vLog); err != nil {
-				logger.Error("处理事件日志失败", err, logrus.Fields{
-					"tx_hash":    vLog.TxHash.Hex(),
-					"block_hash": vLog.BlockHash.Hex(),
-					"log_index":  vLog.Index,
+			if vLog.Removed {
+				// 日志所在区块被重组移出主链，catchUp会校验区块hash并回滚
+				logger.Warn("收到被重组移除的日志", logrus.Fields{
+					"block_number": vLog.BlockNumber,
+					"block_hash":   vLog.BlockHash.Hex(),
+					"tx_hash":      vLog.TxHash.Hex(),
 				})
+				el.catchUp()
+				continue
+			}
+			if vLog.BlockNumber >= el.getNextBlock() {
+				el.catchUp()
 			}
 
+		case <-ticker.C:
+			el.catchUp()
+
 		case <-el.stopChan:
 			logger.Info("收到停止信号，退出事件监听")
+			return true, nil
+		}
+	}
+}
+
+// pollForEvents 定期按区块区间查询日志，用于不支持订阅的节点
+func (el *EventListener) pollForEvents() {
+	el.setMode(IngestionModePolling)
+	logger.Info("事件监听器启动成功", logrus.Fields{
+		"mode":       IngestionModePolling,
+		"next_block": el.getNextBlock(),
+	})
+
+	ticker := time.NewTicker(el.pollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			el.catchUp()
+		case <-el.stopChan:
+			logger.Info("收到停止信号，退出事件轮询")
 			return
 		}
 	}
 }
 
+// isSubscriptionUnsupported 判断错误是否表示节点不支持订阅
+func isSubscriptionUnsupported(err error) bool {
+	if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+		return true
+	}
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode
+}
+
+// pollInterval 轮询间隔
+func (el *EventListener) pollInterval() time.Duration {
+	if el.config.PollInterval > 0 {
+		return el.config.PollInterval
+	}
+	return defaultPollInterval
+}
+
+// logBlockRange 每次查询日志的最大区块数量
+func (el *EventListener) logBlockRange() uint64 {
+	if el.config.LogBlockRange > 0 {
+		return el.config.LogBlockRange
+	}
+	return defaultLogBlockRange
+}
+
+// catchUp 检查链重组后回溯新登记集合的历史转移，再从同步进度处理到已确认的最新区块
+func (el *EventListener) catchUp() {
+	if err := el.checkReorg(); err != nil {
+		logger.Error("检查链重组失败", err)
+		el.setLastError(err)
+		return
+	}
+
+	el.backfillCollections()
+
+	latestBlock, err := el.client.BlockNumber(context.Background())
+	if err != nil {
+		logger.Error("获取最新区块号失败", err)
+		el.setLastError(err)
+		return
+	}
+
+	if latestBlock < el.config.ConfirmationDepth {
+		return
+	}
+	confirmedBlock := latestBlock - el.config.ConfirmationDepth
+
+	if el.getNextBlock() > confirmedBlock {
+		return
+	}
+
+	el.syncHistoricalEvents(confirmedBlock)
+}
+
 // syncHistoricalEvents 同步历史事件
 func (el *EventListener) syncHistoricalEvents(toBlock uint64) {
+	fromBlock := el.getNextBlock()
 	logger.Info("开始同步历史事件", logrus.Fields{
-		"to_block": toBlock,
+		"from_block": fromBlock,
+		"to_block":   toBlock,
 	})
 
-	// 获取数据库中最后处理的区块号
-	var lastSyncedBlock uint64 = 0
-
-	// 这里可以从数据库中获取上次同步的区块号
-	// 暂时从创世块开始同步
-	fromBlock := lastSyncedBlock
-
-	// 分批同步，每次同步1000个区块
-	batchSize := uint64(1000)
+	el.setSyncing(true)
+	defer el.setSyncing(false)
 
-	for from := fromBlock; from <= toBlock; from += batchSize {
-		to := from + batchSize - 1
+	// 分批同步，每批成功后保存进度；某批重试耗尽则停止，下次从该批重新开始
+	blockRange := el.logBlockRange()
+	for from := fromBlock; from <= toBlock; from += blockRange {
+		to := from + blockRange - 1
 		if to > toBlock {
 			to = toBlock
 		}
 
-		if err := el.syncEventsBatch(from, to); err != nil {
-			logger.Error("同步历史事件批次失败", err, logrus.Fields{
+		blockHashes, err := el.syncBatchWithRetry(from, to)
+		if err != nil {
+			logger.Error("同步历史事件批次失败，等待下次重试", err, logrus.Fields{
 				"from_block": from,
 				"to_block":   to,
 			})
-			// 继续处理下一批
-			continue
+			return
+		}
+
+		if err := el.saveSyncState(to, blockHashes); err != nil {
+			logger.Error("保存同步进度失败", err, logrus.Fields{
+				"block": to,
+			})
+			el.setLastError(err)
+			return
+		}
+		// 更新失败的集合会落后于主同步进度，之后由回溯补齐
+		if err := el.advanceCollectionSync(from, to); err != nil {
+			logger.Error("推进集合同步进度失败", err, logrus.Fields{
+				"block": to,
+			})
+			el.setLastError(err)
 		}
 
 		logger.Debug("同步历史事件批次完成", logrus.Fields{
@@ -159,8 +432,11 @@ func (el *EventListener) syncHistoricalEvents(toBlock uint64) {
 			"to_block":   to,
 		})
 
-		// 短暂休息，避免过度请求
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-el.stopChan:
+			return
+		default:
+		}
 	}
 
 	logger.Info("历史事件同步完成", logrus.Fields{
@@ -169,78 +445,336 @@ func (el *EventListener) syncHistoricalEvents(toBlock uint64) {
 	})
 }
 
-// syncEventsBatch 同步事件批次
-func (el *EventListener) syncEventsBatch(fromBlock, toBlock uint64) error {
+// syncBatchWithRetry 同步一个批次，失败时按指数退避重试
+func (el *EventListener) syncBatchWithRetry(fromBlock, toBlock uint64) (map[uint64]common.Hash, error) {
+	backoff := initialRetryBackoff
+	for attempt := 1; ; attempt++ {
+		blockHashes, err := el.syncEventsBatch(fromBlock, toBlock)
+		if err == nil {
+			return blockHashes, nil
+		}
+		el.setLastError(err)
+
+		if attempt >= maxBatchRetries {
+			return nil, fmt.Errorf("重试%d次后仍失败: %v", attempt, err)
+		}
+
+		logger.Warn("同步事件批次失败，准备重试", logrus.Fields{
+			"from_block": fromBlock,
+			"to_block":   toBlock,
+			"attempt":    attempt,
+			"backoff":    backoff.String(),
+			"error":      err.Error(),
+		})
+
+		select {
+		case <-time.After(backoff):
+		case <-el.stopChan:
+			return nil, fmt.Errorf("事件监听器已停止")
+		}
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// syncEventsBatch 同步事件批次，任一日志处理失败且未达到重试上限则整批视为失败
+// 返回批次内包含事件的区块hash，用于之后检测链重组
+func (el *EventListener) syncEventsBatch(fromBlock, toBlock uint64) (map[uint64]common.Hash, error) {
 	query := ethereum.FilterQuery{
-		FromBlock: big.NewInt(int64(fromBlock)),
-		ToBlock:   big.NewInt(int64(toBlock)),
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
 		Addresses: []common.Address{el.contractAddress},
 	}
 
 	logs, err := el.client.FilterLogs(context.Background(), query)
 	if err != nil {
-		return fmt.Errorf("获取历史日志失败: %v", err)
+		return nil, fmt.Errorf("获取历史日志失败: %v", err)
 	}
 
+	// 同一区块区间内已登记集合的ERC-721和ERC-1155转移事件，与市场合约事件按链上顺序一起处理
+	el.detectTokenStandards()
+	collections, err := el.collectionAddresses(fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) > 0 {
+		transferLogs, err := el.client.FilterLogs(context.Background(), ethereum.FilterQuery{
+			FromBlock: query.FromBlock,
+			ToBlock:   query.ToBlock,
+			Addresses: collections,
+			Topics:    [][]common.Hash{{transferEventTopic, transferSingleTopic, transferBatchTopic}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取转移日志失败: %v", err)
+		}
+		logs = append(logs, transferLogs...)
+		sort.SliceStable(logs, func(i, j int) bool {
+			if logs[i].BlockNumber != logs[j].BlockNumber {
+				return logs[i].BlockNumber < logs[j].BlockNumber
+			}
+			return logs[i].Index < logs[j].Index
+		})
+	}
+
+	// 同一区块的日志hash不一致说明查询期间发生了重组，整批重试
+	blockHashes := make(map[uint64]common.Hash)
 	for _, vLog := range logs {
-		if err := el.processLog(vLog); err != nil {
-			logger.Error("处理历史事件失败", err, logrus.Fields{
-				"tx_hash":    vLog.TxHash.Hex(),
-				"block_hash": vLog.BlockHash.Hex(),
-			})
+		if hash, ok := blockHashes[vLog.BlockNumber]; ok && hash != vLog.BlockHash {
+			return nil, fmt.Errorf("区块%d的日志hash不一致，可能发生了链重组", vLog.BlockNumber)
 		}
+		blockHashes[vLog.BlockNumber] = vLog.BlockHash
 	}
 
-	return nil
+	// 单条日志反复失败时记为死信并跳过，避免一条异常日志（如第三方集合的畸形转移事件）永久阻塞同步进度
+	failedLogs, err := el.loadFailedLogs(fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	for _, vLog := range logs {
+		if err := el.processLogIsolated(vLog, failedLogs[logKey{vLog.TxHash.Hex(), vLog.Index}]); err != nil {
+			return nil, fmt.Errorf("处理事件失败 (tx: %s, log_index: %d): %v", vLog.TxHash.Hex(), vLog.Index, err)
+		}
+	}
+
+	return blockHashes, nil
 }
 
-// processLog 处理日志事件
-func (el *EventListener) processLog(vLog types.Log) error {
-	// 获取交易和收据信息
-	tx, _, err := el.client.TransactionByHash(context.Background(), vLog.TxHash)
+// loadSyncState 从数据库加载同步进度
+func (el *EventListener) loadSyncState() error {
+	var state models.SyncState
+	err := el.db.Where("contract_address = ?", el.contractAddress.Hex()).First(&state).Error
+	if err == gorm.ErrRecordNotFound {
+		logger.Info("未找到同步进度记录，从配置的起始区块开始", logrus.Fields{
+			"start_block": el.config.StartBlock,
+		})
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("获取交易信息失败: %v", err)
+		return fmt.Errorf("加载同步进度失败: %v", err)
 	}
 
-	receipt, err := el.client.TransactionReceipt(context.Background(), vLog.TxHash)
+	el.mu.Lock()
+	el.nextBlock = state.LastSyncedBlock + 1
+	el.mu.Unlock()
+	return nil
+}
+
+// saveSyncState 保存同步进度，同时记录批次内区块hash
+func (el *EventListener) saveSyncState(lastSyncedBlock uint64, blockHashes map[uint64]common.Hash) error {
+	// 进度所在区块总是记录hash，作为下次检测重组的基准
+	if _, ok := blockHashes[lastSyncedBlock]; !ok {
+		header, err := el.client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(lastSyncedBlock))
+		if err != nil {
+			return fmt.Errorf("获取区块头失败: %v", err)
+		}
+		blockHashes[lastSyncedBlock] = header.Hash()
+	}
+
+	now := time.Now().Unix()
+	contractHex := el.contractAddress.Hex()
+	indexedBlocks := make([]models.IndexedBlock, 0, len(blockHashes))
+	for number, hash := range blockHashes {
+		indexedBlocks = append(indexedBlocks, models.IndexedBlock{
+			ContractAddress: contractHex,
+			BlockNumber:     number,
+			BlockHash:       hash.Hex(),
+			CreateTime:      &now,
+		})
+	}
+
+	state := models.SyncState{
+		ContractAddress: contractHex,
+		LastSyncedBlock: lastSyncedBlock,
+		UpdateTime:      &now,
+	}
+
+	err := el.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "contract_address"}, {Name: "block_number"}},
+			DoUpdates: clause.AssignmentColumns([]string{"block_hash", "create_time"}),
+		}).Create(&indexedBlocks).Error; err != nil {
+			return err
+		}
+
+		// 清理超出重组检测窗口的旧记录
+		if lastSyncedBlock > reorgHistoryBlocks {
+			if err := tx.Where("contract_address = ? AND block_number < ?", contractHex, lastSyncedBlock-reorgHistoryBlocks).
+				Delete(&models.IndexedBlock{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "contract_address"}},
+			DoUpdates: clause.AssignmentColumns([]string{"last_synced_block", "update_time", "updated_at"}),
+		}).Create(&state).Error
+	})
 	if err != nil {
-		return fmt.Errorf("获取交易收据失败: %v", err)
+		return err
 	}
 
+	el.mu.Lock()
+	el.nextBlock = lastSyncedBlock + 1
+	el.lastSyncTime = now
+	el.mu.Unlock()
+	return nil
+}
+
+// getNextBlock 获取下一个待处理的区块
+func (el *EventListener) getNextBlock() uint64 {
+	el.mu.RLock()
+	defer el.mu.RUnlock()
+	return el.nextBlock
+}
+
+// setSyncing 设置同步中标记
+func (el *EventListener) setSyncing(syncing bool) {
+	el.mu.Lock()
+	el.syncing = syncing
+	el.mu.Unlock()
+}
+
+// setMode 设置当前事件获取方式
+func (el *EventListener) setMode(mode string) {
+	el.mu.Lock()
+	el.mode = mode
+	el.mu.Unlock()
+}
+
+// setLastError 记录最近一次同步错误
+func (el *EventListener) setLastError(err error) {
+	el.mu.Lock()
+	el.lastError = err.Error()
+	el.lastErrorTime = time.Now().Unix()
+	el.mu.Unlock()
+}
+
+// processLog 处理日志事件
+func (el *EventListener) processLog(vLog types.Log) error {
 	// 根据事件主题处理不同类型的事件
 	if len(vLog.Topics) == 0 {
 		return fmt.Errorf("事件主题为空")
 	}
 
-	eventSignature := vLog.Topics[0]
-
-	// OrderCreated事件签名: keccak256("OrderCreated(uint256,address,address,uint256,uint256,uint8)")
-	orderCreatedSig := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef") // 实际需要计算
-
-	// OrderFilled事件签名: keccak256("OrderFilled(uint256,address,address,uint256)")
-	orderFilledSig := common.HexToHash("0xabcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890") // 实际需要计算
+	// 集合合约的转移事件不需要交易和收据，直接按日志处理
+	if vLog.Address != el.contractAddress {
+		if vLog.Topics[0] == transferSingleTopic || vLog.Topics[0] == transferBatchTopic {
+			eventName, events, err := parseTransfer1155Events(vLog)
+			if err != nil {
+				return err
+			}
+			return el.processOnce(vLog, eventName, func(db *gorm.DB) error {
+				return el.handleTransfer1155Events(db, vLog, events)
+			})
+		}
 
-	// OrderCancelled事件签名: keccak256("OrderCancelled(uint256,address)")
-	orderCancelledSig := common.HexToHash("0xfedcba0987654321fedcba0987654321fedcba0987654321fedcba0987654321") // 实际需要计算
+		event := parseTransferEvent(vLog)
+		if event == nil {
+			return nil
+		}
+		return el.processOnce(vLog, transferEventName, func(db *gorm.DB) error {
+			return el.handleTransferEvent(db, vLog, event)
+		})
+	}
 
-	switch eventSignature {
-	case orderCreatedSig:
-		return el.handleOrderCreatedEvent(vLog, tx, receipt)
-	case orderFilledSig:
-		return el.handleOrderFilledEvent(vLog, tx, receipt)
-	case orderCancelledSig:
-		return el.handleOrderCancelledEvent(vLog, tx, receipt)
-	default:
+	// 事件签名取自合约ABI: keccak256("OrderCreated(uint256,address,address,uint256,uint256,uint8)")等
+	contractABI := el.contract.ContractABI()
+	event, err := contractABI.EventByID(vLog.Topics[0])
+	if err != nil {
 		logger.Debug("未知事件类型", logrus.Fields{
-			"event_signature": eventSignature.Hex(),
+			"event_signature": vLog.Topics[0].Hex(),
 			"tx_hash":         vLog.TxHash.Hex(),
 		})
 		return nil
 	}
+
+	var handler func(db *gorm.DB, vLog types.Log, tx *types.Transaction, receipt *types.Receipt) error
+	switch event.Name {
+	case "OrderCreated":
+		handler = el.handleOrderCreatedEvent
+	case "OrderFilled":
+		handler = el.handleOrderFilledEvent
+	case "OrderCancelled":
+		handler = el.handleOrderCancelledEvent
+	case "OrderExpired":
+		handler = el.handleOrderExpiredEvent
+	default:
+		logger.Debug("未处理的事件类型", logrus.Fields{
+			"event_name": event.Name,
+			"tx_hash":    vLog.TxHash.Hex(),
+		})
+		return nil
+	}
+
+	// 已处理过的日志直接跳过，避免实时订阅与历史同步重叠或重放区块时重复写入
+	processed, err := el.isLogProcessed(vLog)
+	if err != nil {
+		return err
+	}
+	if processed {
+		logger.Debug("日志已处理，跳过", logrus.Fields{
+			"tx_hash":   vLog.TxHash.Hex(),
+			"log_index": vLog.Index,
+		})
+		return nil
+	}
+
+	// 获取交易和收据信息
+	tx, _, err := el.client.TransactionByHash(context.Background(), vLog.TxHash)
+	if err != nil {
+		return fmt.Errorf("获取交易信息失败: %v", err)
+	}
+
+	receipt, err := el.client.TransactionReceipt(context.Background(), vLog.TxHash)
+	if err != nil {
+		return fmt.Errorf("获取交易收据失败: %v", err)
+	}
+
+	return el.processOnce(vLog, event.Name, func(db *gorm.DB) error {
+		return handler(db, vLog, tx, receipt)
+	})
+}
+
+// processOnce 登记日志与业务写入放在同一事务中，保证每条日志恰好生效一次
+func (el *EventListener) processOnce(vLog types.Log, eventName string, handle func(db *gorm.DB) error) error {
+	return el.db.Transaction(func(db *gorm.DB) error {
+		now := time.Now().Unix()
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ProcessedLog{
+			ChainID:     el.chainID,
+			TxHash:      vLog.TxHash.Hex(),
+			LogIndex:    vLog.Index,
+			BlockNumber: vLog.BlockNumber,
+			BlockHash:   vLog.BlockHash.Hex(),
+			EventName:   eventName,
+			CreateTime:  &now,
+		})
+		if result.Error != nil {
+			return fmt.Errorf("登记已处理日志失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return handle(db)
+	})
+}
+
+// isLogProcessed 检查日志是否已处理
+func (el *EventListener) isLogProcessed(vLog types.Log) (bool, error) {
+	var count int64
+	err := el.db.Model(&models.ProcessedLog{}).
+		Where("chain_id = ? AND tx_hash = ? AND log_index = ?", el.chainID, vLog.TxHash.Hex(), vLog.Index).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("查询已处理日志失败: %v", err)
+	}
+	return count > 0, nil
 }
 
 // handleOrderCreatedEvent 处理订单创建事件
-func (el *EventListener) handleOrderCreatedEvent(vLog types.Log, tx *types.Transaction, receipt *types.Receipt) error {
+func (el *EventListener) handleOrderCreatedEvent(db *gorm.DB, vLog types.Log, tx *types.Transaction, receipt *types.Receipt) error {
 	// 解析事件数据
 	event, err := el.parseOrderCreatedEvent(vLog)
 	if err != nil {
@@ -259,7 +793,7 @@ func (el *EventListener) handleOrderCreatedEvent(vLog types.Log, tx *types.Trans
 
 	// 检查订单是否已存在
 	var existingOrder models.Order
-	result := el.db.Where("order_id = ?", fmt.Sprintf("0x%x", event.OrderId)).First(&existingOrder)
+	result := db.Where("order_id = ?", fmt.Sprintf("0x%x", event.OrderId)).First(&existingOrder)
 	if result.Error == nil {
 		logger.Debug("订单已存在，跳过处理", logrus.Fields{
 			"order_id": event.OrderId.String(),
@@ -267,15 +801,37 @@ func (el *EventListener) handleOrderCreatedEvent(vLog types.Log, tx *types.Trans
 		return nil
 	}
 
-	// 创建新的订单记录
 	now := time.Now().Unix()
+	blockHashHex := vLog.BlockHash.Hex()
+	txHashHex := vLog.TxHash.Hex()
+
+	// 用户通过未签名交易创建的订单在数据库中处于待上链状态，匹配后激活
+	promoted, err := el.activatePendingOrder(db, event, vLog, now)
+	if err != nil {
+		return err
+	}
+	if promoted != nil {
+		if err := el.createOrUpdateItemFromEvent(db, event, vLog, now); err != nil {
+			return fmt.Errorf("从事件创建Item记录失败: %v", err)
+		}
+		if err := el.createActivityFromOrderEvent(db, event, vLog, tx, receipt, now); err != nil {
+			return fmt.Errorf("创建活动记录失败: %v", err)
+		}
+		logger.Info("待上链订单已激活", logrus.Fields{
+			"order_id": event.OrderId.String(),
+			"db_id":    promoted.ID,
+		})
+		return nil
+	}
+
+	// 创建新的订单记录
 	order := &models.Order{
 		OrderID:           fmt.Sprintf("0x%x", event.OrderId),
 		OrderType:         models.OrderType(event.OrderType + 1), // 合约从0开始，模型从1开始
 		OrderStatus:       models.OrderStatusActive,
 		CollectionAddress: event.NftContract.Hex(),
 		TokenID:           event.TokenId.String(),
-		Price:             float64(event.Price.Int64()) / 1e18, // 转换为ETH
+		Price:             models.NewWei(event.Price),
 		Maker:             event.Maker.Hex(),
 		EventTime:         &now,
 		CreateTime:        &now,
@@ -283,22 +839,24 @@ func (el *EventListener) handleOrderCreatedEvent(vLog types.Log, tx *types.Trans
 		QuantityRemaining: 1,
 		Size:              1,
 		CurrencyAddress:   "0x0000000000000000000000000000000000000000",
+		BlockNumber:       int64(vLog.BlockNumber),
+		BlockHash:         &blockHashHex,
+		TxHash:            &txHashHex,
 	}
 
 	// 保存到数据库
-	if err := el.db.Create(order).Error; err != nil {
+	if err := db.Create(order).Error; err != nil {
 		return fmt.Errorf("保存订单到数据库失败: %v", err)
 	}
 
 	// 创建对应的Item记录
-	if err := el.createOrUpdateItemFromEvent(event, now); err != nil {
-		logger.Error("从事件创建Item记录失败", err)
-		// 不返回错误，因为订单已经创建成功
+	if err := el.createOrUpdateItemFromEvent(db, event, vLog, now); err != nil {
+		return fmt.Errorf("从事件创建Item记录失败: %v", err)
 	}
 
 	// 创建活动记录
-	if err := el.createActivityFromOrderEvent(event, tx, receipt, now); err != nil {
-		logger.Error("创建活动记录失败", err)
+	if err := el.createActivityFromOrderEvent(db, event, vLog, tx, receipt, now); err != nil {
+		return fmt.Errorf("创建活动记录失败: %v", err)
 	}
 
 	logger.Info("OrderCreated事件处理完成", logrus.Fields{
@@ -309,8 +867,55 @@ func (el *EventListener) handleOrderCreatedEvent(vLog types.Log, tx *types.Trans
 	return nil
 }
 
+// activatePendingOrder 查找与事件对应的待上链订单并激活，没有匹配时返回nil
+// 先按创建交易hash匹配由发件箱提交的订单，再按订单内容匹配用户钱包提交的最早待上链订单
+func (el *EventListener) activatePendingOrder(db *gorm.DB, event *OrderCreatedEvent, vLog types.Log, now int64) (*models.Order, error) {
+	var pending models.Order
+	err := db.Where("tx_hash = ? AND block_hash IS NULL", vLog.TxHash.Hex()).First(&pending).Error
+	if err == gorm.ErrRecordNotFound {
+		err = db.Where("order_status = ? AND maker = ? AND collection_address = ? AND token_id = ? AND order_type = ? AND price = ?",
+			models.OrderStatusPending,
+			event.Maker.Hex(),
+			event.NftContract.Hex(),
+			event.TokenId.String(),
+			models.OrderType(event.OrderType+1),
+			models.NewWei(event.Price),
+		).Order("id ASC").First(&pending).Error
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询待上链订单失败: %v", err)
+	}
+
+	updates := map[string]interface{}{
+		"order_id":     fmt.Sprintf("0x%x", event.OrderId),
+		"block_number": vLog.BlockNumber,
+		"block_hash":   vLog.BlockHash.Hex(),
+		"tx_hash":      vLog.TxHash.Hex(),
+		"event_time":   now,
+		"update_time":  now,
+	}
+	// 发件箱订单在确认前可能已在数据库中被取消或成交，此时保留原状态
+	if pending.OrderStatus == models.OrderStatusPending {
+		updates["order_status"] = models.OrderStatusActive
+	}
+	if err := db.Model(&pending).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("激活待上链订单失败: %v", err)
+	}
+	// editOrder创建的新订单，把被替换的原订单关联到新订单
+	if pending.ReplacesID != nil {
+		if err := db.Model(&models.Order{}).Where("id = ?", *pending.ReplacesID).
+			Update("replaced_by_id", pending.ID).Error; err != nil {
+			return nil, fmt.Errorf("关联被替换的原订单失败: %v", err)
+		}
+	}
+	return &pending, nil
+}
+
 // handleOrderFilledEvent 处理订单成交事件
-func (el *EventListener) handleOrderFilledEvent(vLog types.Log, tx *types.Transaction, receipt *types.Receipt) error {
+func (el *EventListener) handleOrderFilledEvent(db *gorm.DB, vLog types.Log, tx *types.Transaction, receipt *types.Receipt) error {
 	// 解析事件数据
 	event, err := el.parseOrderFilledEvent(vLog)
 	if err != nil {
@@ -325,35 +930,66 @@ func (el *EventListener) handleOrderFilledEvent(vLog types.Log, tx *types.Transa
 		"tx_hash":  tx.Hash().Hex(),
 	})
 
-	// 更新订单状态
-	result := el.db.Model(&models.Order{}).
-		Where("order_id = ?", fmt.Sprintf("0x%x", event.OrderId)).
-		Update("order_status", models.OrderStatusFilled)
+	// 热钱包代为执行的订单在购买时已在数据库中成交并记录了购买活动，链上确认后不再重复记录，
+	// 也不记录状态变更区块，重组时保留数据库中的成交结果
+	order, err := loadEventOrder(db, event.OrderId)
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{
+		"invalid":        false, // 成交时NFT的Transfer事件先于本事件处理，会把订单标记为失效
+		"invalid_reason": nil,
+	}
+	filledInDB := order != nil && order.OrderStatus == models.OrderStatusFilled
+	if !filledInDB {
+		// 更新订单状态，记录状态变更所在区块以便重组时回滚
+		updates["order_status"] = models.OrderStatusFilled
+		updates["status_block_number"] = vLog.BlockNumber
+	}
 
+	result := db.Model(&models.Order{}).
+		Where("order_id = ?", fmt.Sprintf("0x%x", event.OrderId)).
+		Updates(updates)
 	if result.Error != nil {
 		return fmt.Errorf("更新订单状态失败: %v", result.Error)
 	}
+	if filledInDB {
+		logger.Info("订单已在数据库中成交，跳过成交活动记录", logrus.Fields{
+			"order_id": event.OrderId.String(),
+		})
+		return nil
+	}
 
 	// 创建活动记录
+	collectionAddress, tokenID, err := el.orderActivityToken(order, event.OrderId)
+	if err != nil {
+		return err
+	}
 	now := time.Now().Unix()
 	sellerHex := event.Seller.Hex()
 	buyerHex := event.Buyer.Hex()
 	txHashHex := tx.Hash().Hex()
+	blockHashHex := vLog.BlockHash.Hex()
+	logIndex := vLog.Index
 	activity := &models.Activity{
-		ActivityType:    models.ActivityTypeBuy,
-		Maker:           &sellerHex,
-		Taker:           &buyerHex,
-		Price:           float64(event.Price.Int64()) / 1e18,
-		TxHash:          &txHashHex,
-		BlockNumber:     int64(receipt.BlockNumber.Uint64()),
-		EventTime:       &now,
-		CreateTime:      &now,
-		UpdateTime:      &now,
-		CurrencyAddress: "1", // ETH
+		ActivityType:      models.ActivityTypeBuy,
+		Maker:             &sellerHex,
+		Taker:             &buyerHex,
+		CollectionAddress: &collectionAddress,
+		TokenID:           &tokenID,
+		Price:             models.NewWei(event.Price),
+		TxHash:            &txHashHex,
+		LogIndex:          &logIndex,
+		BlockHash:         &blockHashHex,
+		BlockNumber:       int64(receipt.BlockNumber.Uint64()),
+		EventTime:         &now,
+		CreateTime:        &now,
+		UpdateTime:        &now,
+		CurrencyAddress:   "1", // ETH
 	}
 
-	if err := el.db.Create(activity).Error; err != nil {
-		logger.Error("创建成交活动记录失败", err)
+	if err := createActivity(db, activity); err != nil {
+		return fmt.Errorf("创建成交活动记录失败: %v", err)
 	}
 
 	logger.Info("OrderFilled事件处理完成", logrus.Fields{
@@ -364,7 +1000,7 @@ func (el *EventListener) handleOrderFilledEvent(vLog types.Log, tx *types.Transa
 }
 
 // handleOrderCancelledEvent 处理订单取消事件
-func (el *EventListener) handleOrderCancelledEvent(vLog types.Log, tx *types.Transaction, receipt *types.Receipt) error {
+func (el *EventListener) handleOrderCancelledEvent(db *gorm.DB, vLog types.Log, tx *types.Transaction, receipt *types.Receipt) error {
 	// 解析事件数据
 	event, err := el.parseOrderCancelledEvent(vLog)
 	if err != nil {
@@ -377,31 +1013,48 @@ func (el *EventListener) handleOrderCancelledEvent(vLog types.Log, tx *types.Tra
 		"tx_hash":  tx.Hash().Hex(),
 	})
 
-	// 更新订单状态
-	result := el.db.Model(&models.Order{}).
+	// 更新订单状态，记录状态变更所在区块以便重组时回滚
+	result := db.Model(&models.Order{}).
 		Where("order_id = ?", fmt.Sprintf("0x%x", event.OrderId)).
-		Update("order_status", models.OrderStatusCancelled)
+		Updates(map[string]interface{}{
+			"order_status":        models.OrderStatusCancelled,
+			"status_block_number": vLog.BlockNumber,
+		})
 
 	if result.Error != nil {
 		return fmt.Errorf("更新订单状态失败: %v", result.Error)
 	}
 
 	// 创建活动记录
+	order, err := loadEventOrder(db, event.OrderId)
+	if err != nil {
+		return err
+	}
+	collectionAddress, tokenID, err := el.orderActivityToken(order, event.OrderId)
+	if err != nil {
+		return err
+	}
 	now := time.Now().Unix()
 	makerHex := event.Maker.Hex()
 	txHashHex := tx.Hash().Hex()
+	blockHashHex := vLog.BlockHash.Hex()
+	logIndex := vLog.Index
 	activity := &models.Activity{
-		ActivityType: models.ActivityTypeCancelListing,
-		Maker:        &makerHex,
-		TxHash:       &txHashHex,
-		BlockNumber:  int64(receipt.BlockNumber.Uint64()),
-		EventTime:    &now,
-		CreateTime:   &now,
-		UpdateTime:   &now,
+		ActivityType:      models.ActivityTypeCancelListing,
+		Maker:             &makerHex,
+		CollectionAddress: &collectionAddress,
+		TokenID:           &tokenID,
+		TxHash:            &txHashHex,
+		LogIndex:          &logIndex,
+		BlockHash:         &blockHashHex,
+		BlockNumber:       int64(receipt.BlockNumber.Uint64()),
+		EventTime:         &now,
+		CreateTime:        &now,
+		UpdateTime:        &now,
 	}
 
-	if err := el.db.Create(activity).Error; err != nil {
-		logger.Error("创建取消活动记录失败", err)
+	if err := createActivity(db, activity); err != nil {
+		return fmt.Errorf("创建取消活动记录失败: %v", err)
 	}
 
 	logger.Info("OrderCancelled事件处理完成", logrus.Fields{
@@ -411,72 +1064,117 @@ func (el *EventListener) handleOrderCancelledEvent(vLog types.Log, tx *types.Tra
 	return nil
 }
 
-// parseOrderCreatedEvent 解析OrderCreated事件
-func (el *EventListener) parseOrderCreatedEvent(vLog types.Log) (*OrderCreatedEvent, error) {
-	// 这里需要根据实际的ABI来解析事件
-	// 简化处理，假设事件数据在Topics和Data中
-	if len(vLog.Topics) < 4 {
-		return nil, fmt.Errorf("OrderCreated事件Topics数量不足")
+// handleOrderExpiredEvent 处理订单过期事件
+func (el *EventListener) handleOrderExpiredEvent(db *gorm.DB, vLog types.Log, tx *types.Transaction, receipt *types.Receipt) error {
+	// 解析事件数据
+	event, err := el.parseOrderExpiredEvent(vLog)
+	if err != nil {
+		return fmt.Errorf("解析OrderExpired事件失败: %v", err)
 	}
 
-	event := &OrderCreatedEvent{
-		OrderId:     new(big.Int).SetBytes(vLog.Topics[1][:]),
-		Maker:       common.BytesToAddress(vLog.Topics[2][:]),
-		NftContract: common.BytesToAddress(vLog.Topics[3][:]),
-	}
+	logger.Info("处理OrderExpired事件", logrus.Fields{
+		"order_id": event.OrderId.String(),
+		"tx_hash":  tx.Hash().Hex(),
+	})
 
-	// 解析Data中的其他字段（TokenId, Price, OrderType）
-	// 这里需要根据实际ABI进行解析
-	// 简化处理
-	if len(vLog.Data) >= 96 { // 3个uint256字段
-		event.TokenId = new(big.Int).SetBytes(vLog.Data[0:32])
-		event.Price = new(big.Int).SetBytes(vLog.Data[32:64])
-		event.OrderType = uint8(vLog.Data[95]) // 最后一个字节
+	// 更新订单状态，已成交或已取消的订单不覆盖
+	now := time.Now().Unix()
+	result := db.Model(&models.Order{}).
+		Where("order_id = ? AND order_status = ?", fmt.Sprintf("0x%x", event.OrderId), models.OrderStatusActive).
+		Updates(map[string]interface{}{
+			"order_status":        models.OrderStatusExpired,
+			"status_block_number": vLog.BlockNumber,
+			"update_time":         now,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("更新订单状态失败: %v", result.Error)
 	}
 
-	return event, nil
+	logger.Info("OrderExpired事件处理完成", logrus.Fields{
+		"order_id":      event.OrderId.String(),
+		"block_number":  receipt.BlockNumber.Uint64(),
+		"rows_affected": result.RowsAffected,
+	})
+
+	return nil
 }
 
-// parseOrderFilledEvent 解析OrderFilled事件
-func (el *EventListener) parseOrderFilledEvent(vLog types.Log) (*OrderFilledEvent, error) {
-	if len(vLog.Topics) < 4 {
-		return nil, fmt.Errorf("OrderFilled事件Topics数量不足")
+// unpackEvent 按合约ABI解码事件：非indexed字段从Data解码，indexed字段从Topics解码
+func (el *EventListener) unpackEvent(out interface{}, eventName string, vLog types.Log) error {
+	contractABI := el.contract.ContractABI()
+	event, ok := contractABI.Events[eventName]
+	if !ok {
+		return fmt.Errorf("合约ABI中不存在事件: %s", eventName)
+	}
+	if len(vLog.Topics) == 0 || vLog.Topics[0] != event.ID {
+		return fmt.Errorf("事件签名与%s不匹配", eventName)
 	}
 
-	event := &OrderFilledEvent{
-		OrderId: new(big.Int).SetBytes(vLog.Topics[1][:]),
-		Buyer:   common.BytesToAddress(vLog.Topics[2][:]),
-		Seller:  common.BytesToAddress(vLog.Topics[3][:]),
+	if len(event.Inputs.NonIndexed()) > 0 {
+		if err := contractABI.UnpackIntoInterface(out, eventName, vLog.Data); err != nil {
+			return fmt.Errorf("解码事件数据失败: %v", err)
+		}
 	}
 
-	// 解析Price
-	if len(vLog.Data) >= 32 {
-		event.Price = new(big.Int).SetBytes(vLog.Data[0:32])
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if len(vLog.Topics)-1 != len(indexed) {
+		return fmt.Errorf("%s事件Topics数量错误: 期望%d, 实际%d", eventName, len(indexed), len(vLog.Topics)-1)
+	}
+	if err := abi.ParseTopics(out, indexed, vLog.Topics[1:]); err != nil {
+		return fmt.Errorf("解码事件Topics失败: %v", err)
+	}
+
+	return nil
+}
+
+// parseOrderCreatedEvent 解析OrderCreated事件
+func (el *EventListener) parseOrderCreatedEvent(vLog types.Log) (*OrderCreatedEvent, error) {
+	event := &OrderCreatedEvent{}
+	if err := el.unpackEvent(event, "OrderCreated", vLog); err != nil {
+		return nil, err
 	}
+	return event, nil
+}
 
+// parseOrderFilledEvent 解析OrderFilled事件
+func (el *EventListener) parseOrderFilledEvent(vLog types.Log) (*OrderFilledEvent, error) {
+	event := &OrderFilledEvent{}
+	if err := el.unpackEvent(event, "OrderFilled", vLog); err != nil {
+		return nil, err
+	}
 	return event, nil
 }
 
 // parseOrderCancelledEvent 解析OrderCancelled事件
 func (el *EventListener) parseOrderCancelledEvent(vLog types.Log) (*OrderCancelledEvent, error) {
-	if len(vLog.Topics) < 3 {
-		return nil, fmt.Errorf("OrderCancelled事件Topics数量不足")
+	event := &OrderCancelledEvent{}
+	if err := el.unpackEvent(event, "OrderCancelled", vLog); err != nil {
+		return nil, err
 	}
+	return event, nil
+}
 
-	event := &OrderCancelledEvent{
-		OrderId: new(big.Int).SetBytes(vLog.Topics[1][:]),
-		Maker:   common.BytesToAddress(vLog.Topics[2][:]),
+// parseOrderExpiredEvent 解析OrderExpired事件
+func (el *EventListener) parseOrderExpiredEvent(vLog types.Log) (*OrderExpiredEvent, error) {
+	event := &OrderExpiredEvent{}
+	if err := el.unpackEvent(event, "OrderExpired", vLog); err != nil {
+		return nil, err
 	}
-
 	return event, nil
 }
 
 // createOrUpdateItemFromEvent 从事件创建或更新Item记录
-func (el *EventListener) createOrUpdateItemFromEvent(event *OrderCreatedEvent, now int64) error {
+func (el *EventListener) createOrUpdateItemFromEvent(db *gorm.DB, event *OrderCreatedEvent, vLog types.Log, now int64) error {
 	var item models.Item
 
 	// 检查Item是否已存在
-	err := el.db.Where("collection_address = ? AND token_id = ?",
+	err := db.Where("collection_address = ? AND token_id = ?",
 		event.NftContract.Hex(), event.TokenId.String()).First(&item).Error
 
 	if err == gorm.ErrRecordNotFound {
@@ -491,18 +1189,18 @@ func (el *EventListener) createOrUpdateItemFromEvent(event *OrderCreatedEvent, n
 			CollectionAddress: &contractHex,
 			Creator:           event.Maker.Hex(),
 			Supply:            1,
+			BlockNumber:       int64(vLog.BlockNumber),
 			CreateTime:        &now,
 			UpdateTime:        &now,
 		}
 
 		// 如果是上架订单，设置价格
 		if event.OrderType == 0 { // LimitSell
-			price := float64(event.Price.Int64()) / 1e18
-			item.ListPrice = &price
+			item.ListPrice = models.NewWeiPtr(event.Price)
 			item.ListTime = &now
 		}
 
-		return el.db.Create(&item).Error
+		return db.Create(&item).Error
 	} else if err != nil {
 		return err
 	} else {
@@ -512,17 +1210,16 @@ func (el *EventListener) createOrUpdateItemFromEvent(event *OrderCreatedEvent, n
 		}
 
 		if event.OrderType == 0 { // LimitSell
-			price := float64(event.Price.Int64()) / 1e18
-			updateData["list_price"] = price
+			updateData["list_price"] = models.NewWei(event.Price)
 			updateData["list_time"] = now
 		}
 
-		return el.db.Model(&item).Updates(updateData).Error
+		return db.Model(&item).Updates(updateData).Error
 	}
 }
 
 // createActivityFromOrderEvent 从订单事件创建活动记录
-func (el *EventListener) createActivityFromOrderEvent(event *OrderCreatedEvent, tx *types.Transaction, receipt *types.Receipt, now int64) error {
+func (el *EventListener) createActivityFromOrderEvent(db *gorm.DB, event *OrderCreatedEvent, vLog types.Log, tx *types.Transaction, receipt *types.Receipt, now int64) error {
 	var activityType models.ActivityType
 
 	switch event.OrderType {
@@ -538,13 +1235,17 @@ func (el *EventListener) createActivityFromOrderEvent(event *OrderCreatedEvent,
 	contractHex := event.NftContract.Hex()
 	tokenIdStr := event.TokenId.String()
 	txHashHex := tx.Hash().Hex()
+	blockHashHex := vLog.BlockHash.Hex()
+	logIndex := vLog.Index
 	activity := &models.Activity{
 		ActivityType:      activityType,
 		Maker:             &makerHex,
 		CollectionAddress: &contractHex,
 		TokenID:           &tokenIdStr,
-		Price:             float64(event.Price.Int64()) / 1e18,
+		Price:             models.NewWei(event.Price),
 		TxHash:            &txHashHex,
+		LogIndex:          &logIndex,
+		BlockHash:         &blockHashHex,
 		BlockNumber:       int64(receipt.BlockNumber.Uint64()),
 		EventTime:         &now,
 		CreateTime:        &now,
@@ -552,5 +1253,36 @@ func (el *EventListener) createActivityFromOrderEvent(event *OrderCreatedEvent,
 		CurrencyAddress:   "1", // ETH
 	}
 
-	return el.db.Create(activity).Error
+	return createActivity(db, activity)
+}
+
+// loadEventOrder 查询订单事件对应的数据库订单，不存在时返回nil
+func loadEventOrder(db *gorm.DB, orderID *big.Int) (*models.Order, error) {
+	var order models.Order
+	err := db.Where("order_id = ?", fmt.Sprintf("0x%x", orderID)).First(&order).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %v", err)
+	}
+	return &order, nil
+}
+
+// orderActivityToken 返回订单事件活动的集合地址和token ID，数据库中没有该订单时从合约读取
+// 活动按(tx_hash, log_index, token_id)去重，token ID为空时唯一索引不生效
+func (el *EventListener) orderActivityToken(order *models.Order, orderID *big.Int) (string, string, error) {
+	if order != nil {
+		return order.CollectionAddress, order.TokenID, nil
+	}
+	chainOrder, err := el.contract.GetOrder(orderID.Uint64())
+	if err != nil {
+		return "", "", err
+	}
+	return chainOrder.NftContract.Hex(), chainOrder.TokenId.String(), nil
+}
+
+// createActivity 保存事件生成的活动，(tx_hash, log_index, token_id)已存在时忽略
+func createActivity(db *gorm.DB, activity *models.Activity) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(activity).Error
 }
@@ -0,0 +1,194 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxLogAttempts 单条日志最大处理次数，跨同步批次累计，耗尽后记为死信并跳过
+	maxLogAttempts = 10
+	// maxFailedLogErrorLength 失败原因最大保存长度
+	maxFailedLogErrorLength = 1024
+)
+
+// logKey 日志的唯一标识
+type logKey struct {
+	TxHash   string
+	LogIndex uint
+}
+
+// loadFailedLogs 查询区块区间内已有失败记录的日志
+func (el *EventListener) loadFailedLogs(fromBlock, toBlock uint64) (map[logKey]*models.FailedLog, error) {
+	var failedLogs []models.FailedLog
+	err := el.db.Where("chain_id = ? AND block_number BETWEEN ? AND ?", el.chainID, fromBlock, toBlock).
+		Find(&failedLogs).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询失败日志记录失败: %v", err)
+	}
+
+	result := make(map[logKey]*models.FailedLog, len(failedLogs))
+	for i := range failedLogs {
+		result[logKey{failedLogs[i].TxHash, failedLogs[i].LogIndex}] = &failedLogs[i]
+	}
+	return result, nil
+}
+
+// processLogIsolated 处理单条日志并隔离失败：重试次数未耗尽时返回错误使整批重试，
+// 耗尽后记为死信并跳过，同步进度继续推进
+func (el *EventListener) processLogIsolated(vLog types.Log, failed *models.FailedLog) error {
+	// 已记为死信的日志不再自动处理，由管理员人工重试
+	if failed != nil && failed.Status != models.FailedLogStatusRetrying {
+		return nil
+	}
+
+	processErr := el.processLog(vLog)
+	if processErr == nil {
+		if failed != nil {
+			if err := el.db.Delete(&models.FailedLog{}, failed.ID).Error; err != nil {
+				logger.Warn("删除已恢复的失败日志记录失败", logrus.Fields{
+					"id":    failed.ID,
+					"error": err.Error(),
+				})
+			}
+		}
+		return nil
+	}
+
+	attempts, err := el.recordLogFailure(vLog, processErr)
+	if err != nil {
+		return fmt.Errorf("%v（记录失败日志时出错: %v）", processErr, err)
+	}
+	if attempts < maxLogAttempts {
+		return processErr
+	}
+
+	logger.Error("日志处理重试耗尽，已记为死信并跳过", processErr, logrus.Fields{
+		"tx_hash":   vLog.TxHash.Hex(),
+		"log_index": vLog.Index,
+		"block":     vLog.BlockNumber,
+		"contract":  vLog.Address.Hex(),
+		"attempts":  attempts,
+	})
+	el.setLastError(processErr)
+	return nil
+}
+
+// recordLogFailure 累加日志的失败次数，达到上限时标记为死信，返回累计次数
+func (el *EventListener) recordLogFailure(vLog types.Log, processErr error) (int, error) {
+	raw, err := json.Marshal(vLog)
+	if err != nil {
+		return 0, err
+	}
+	topic := ""
+	if len(vLog.Topics) > 0 {
+		topic = vLog.Topics[0].Hex()
+	}
+	lastError := truncateError(processErr)
+
+	now := time.Now().Unix()
+	var failed models.FailedLog
+	err = el.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "chain_id"}, {Name: "tx_hash"}, {Name: "log_index"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"attempts":    gorm.Expr("attempts + 1"),
+				"last_error":  lastError,
+				"update_time": now,
+				"updated_at":  time.Now(),
+			}),
+		}).Create(&models.FailedLog{
+			ChainID:         el.chainID,
+			TxHash:          vLog.TxHash.Hex(),
+			LogIndex:        vLog.Index,
+			BlockNumber:     vLog.BlockNumber,
+			ContractAddress: vLog.Address.Hex(),
+			Topic:           topic,
+			RawLog:          string(raw),
+			Status:          models.FailedLogStatusRetrying,
+			Attempts:        1,
+			LastError:       lastError,
+			CreateTime:      &now,
+			UpdateTime:      &now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("chain_id = ? AND tx_hash = ? AND log_index = ?", el.chainID, vLog.TxHash.Hex(), vLog.Index).
+			First(&failed).Error
+		if err != nil {
+			return err
+		}
+		if failed.Attempts >= maxLogAttempts && failed.Status == models.FailedLogStatusRetrying {
+			failed.Status = models.FailedLogStatusDead
+			return tx.Model(&failed).Update("status", models.FailedLogStatusDead).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return failed.Attempts, nil
+}
+
+// RetryFailedLog 人工重试已记为死信的日志，成功后标记为已解决
+func (el *EventListener) RetryFailedLog(id uint64) (*models.FailedLog, error) {
+	var failed models.FailedLog
+	if err := el.db.First(&failed, id).Error; err != nil {
+		return nil, err
+	}
+	if failed.Status == models.FailedLogStatusResolved {
+		return &failed, nil
+	}
+
+	var vLog types.Log
+	if err := json.Unmarshal([]byte(failed.RawLog), &vLog); err != nil {
+		return nil, fmt.Errorf("解析日志失败: %v", err)
+	}
+
+	now := time.Now().Unix()
+	updates := map[string]interface{}{
+		"attempts":    failed.Attempts + 1,
+		"update_time": now,
+	}
+	processErr := el.processLog(vLog)
+	if processErr == nil {
+		updates["status"] = models.FailedLogStatusResolved
+	} else {
+		updates["last_error"] = truncateError(processErr)
+	}
+	if err := el.db.Model(&models.FailedLog{}).Where("id = ?", failed.ID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("更新失败日志记录失败: %v", err)
+	}
+	if err := el.db.First(&failed, id).Error; err != nil {
+		return nil, err
+	}
+	if processErr != nil {
+		return &failed, fmt.Errorf("重试处理日志失败: %v", processErr)
+	}
+
+	logger.Info("死信日志重试成功", logrus.Fields{
+		"id":        failed.ID,
+		"tx_hash":   failed.TxHash,
+		"log_index": failed.LogIndex,
+	})
+	return &failed, nil
+}
+
+// truncateError 按字符截断失败原因以适应列长度
+func truncateError(err error) string {
+	message := []rune(err.Error())
+	if len(message) > maxFailedLogErrorLength {
+		message = message[:maxFailedLogErrorLength]
+	}
+	return string(message)
+}
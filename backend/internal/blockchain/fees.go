@@ -0,0 +1,132 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// defaultGasLimitMarginPercent 未配置或配置为负数时gas上限在估算值基础上增加的百分比
+const defaultGasLimitMarginPercent = 20
+
+// FeeConfig 交易gas与手续费配置
+type FeeConfig struct {
+	GasLimitMarginPercent int64    // gas上限在估算值基础上增加的百分比，0表示不加余量，负数使用默认值
+	MaxFeeCap             *big.Int // 单位gas最高费用(wei)，nil表示不限制
+}
+
+// txFees 一笔交易的gas参数，GasTipCap为nil时表示使用传统交易
+type txFees struct {
+	GasLimit  uint64
+	GasPrice  *big.Int
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// feeClient 计算手续费所需的节点接口
+type feeClient interface {
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+}
+
+// withGasMargin 在估算的gas上增加安全余量
+func (fc FeeConfig) withGasMargin(estimated uint64) uint64 {
+	margin := uint64(defaultGasLimitMarginPercent)
+	if fc.GasLimitMarginPercent >= 0 {
+		margin = uint64(fc.GasLimitMarginPercent)
+	}
+	return estimated + estimated*margin/100
+}
+
+// suggestFees 估算gas上限并给出手续费
+func (fc FeeConfig) suggestFees(ctx context.Context, client feeClient, from, to common.Address, value *big.Int, data []byte) (*txFees, error) {
+	estimated, err := client.EstimateGas(ctx, ethereum.CallMsg{
+		From:  from,
+		To:    &to,
+		Value: value,
+		Data:  data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("估算gas失败: %v", err)
+	}
+
+	fees, err := fc.suggestGasPrices(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	fees.GasLimit = fc.withGasMargin(estimated)
+	return fees, nil
+}
+
+// suggestGasPrices 按链是否支持EIP-1559给出手续费，不含gas上限
+// 支持时费用上限为2倍基础费用加小费，超过MaxFeeCap时截断；不支持时回退到传统gasPrice
+func (fc FeeConfig) suggestGasPrices(ctx context.Context, client feeClient) (*txFees, error) {
+	fees := &txFees{}
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取最新区块头失败: %v", err)
+	}
+
+	if header.BaseFee == nil {
+		gasPrice, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取gas价格失败: %v", err)
+		}
+		if fc.MaxFeeCap != nil && gasPrice.Cmp(fc.MaxFeeCap) > 0 {
+			return nil, fmt.Errorf("当前gas价格%s超过上限%s", gasPrice, fc.MaxFeeCap)
+		}
+		fees.GasPrice = gasPrice
+		return fees, nil
+	}
+
+	if fc.MaxFeeCap != nil && header.BaseFee.Cmp(fc.MaxFeeCap) > 0 {
+		return nil, fmt.Errorf("当前基础费用%s超过上限%s", header.BaseFee, fc.MaxFeeCap)
+	}
+
+	tip, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取小费建议失败: %v", err)
+	}
+
+	feeCap := new(big.Int).Add(new(big.Int).Mul(header.BaseFee, big.NewInt(2)), tip)
+	if fc.MaxFeeCap != nil && feeCap.Cmp(fc.MaxFeeCap) > 0 {
+		feeCap = new(big.Int).Set(fc.MaxFeeCap)
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+
+	fees.GasTipCap = tip
+	fees.GasFeeCap = feeCap
+	return fees, nil
+}
+
+// newTransaction 按手续费类型构造动态费用交易或传统交易
+func (f *txFees) newTransaction(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, data []byte) *types.Transaction {
+	if f.GasTipCap == nil {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			To:       &to,
+			Value:    value,
+			Gas:      f.GasLimit,
+			GasPrice: f.GasPrice,
+			Data:     data,
+		})
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		To:        &to,
+		Value:     value,
+		Gas:       f.GasLimit,
+		GasTipCap: f.GasTipCap,
+		GasFeeCap: f.GasFeeCap,
+		Data:      data,
+	})
+}
@@ -0,0 +1,182 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"nft-market/internal/models"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// ipfsGateway 解析ipfs://元数据地址使用的网关
+	ipfsGateway = "https://ipfs.io/ipfs/"
+	// metadataFetchTimeout 获取元数据的超时时间
+	metadataFetchTimeout = 15 * time.Second
+	// maxMetadataSize 元数据最大字节数
+	maxMetadataSize = 1 << 20
+	// maxTraitTypeLength 特征名最大字符数，与物品特征表列长度一致
+	maxTraitTypeLength = 128
+	// maxTraitValueLength 特征值最大字符数，与物品特征表列长度一致
+	maxTraitValueLength = 256
+)
+
+// metadataHTTPClient 获取元数据的HTTP客户端，只允许连接公网地址
+var metadataHTTPClient = &http.Client{
+	Timeout: metadataFetchTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: metadataFetchTimeout, Control: rejectPrivateAddress}).DialContext,
+	},
+}
+
+// rejectPrivateAddress 拒绝连接本机和内网地址，元数据地址由集合合约决定，不能借此访问内部服务
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return fmt.Errorf("拒绝访问内网地址: %s", host)
+	}
+	return nil
+}
+
+// TokenMetadata NFT元数据中用于特征出价的部分
+type TokenMetadata struct {
+	Attributes []models.Trait
+}
+
+// TokenURI 通过eth_call查询token的元数据地址，ERC-721调用tokenURI，ERC-1155调用uri并替换{id}
+func (c *NFTMarketplaceContract) TokenURI(nftContract common.Address, tokenID *big.Int, standard models.TokenStandard) (string, error) {
+	if standard == models.TokenStandardERC1155 {
+		result, err := c.callABI(erc1155ABI, nftContract, "uri", tokenID)
+		if err != nil {
+			return "", err
+		}
+		// ERC-1155规定{id}替换为64位小写十六进制token ID
+		return strings.ReplaceAll(result[0].(string), "{id}", fmt.Sprintf("%064x", tokenID)), nil
+	}
+
+	result, err := c.callERC721(nftContract, "tokenURI", tokenID)
+	if err != nil {
+		return "", err
+	}
+	return result[0].(string), nil
+}
+
+// FetchTokenMetadata 获取并解析元数据，支持http(s)、ipfs://和data:application/json地址
+func FetchTokenMetadata(ctx context.Context, uri string) (*TokenMetadata, error) {
+	body, err := readMetadata(ctx, strings.TrimSpace(uri))
+	if err != nil {
+		return nil, err
+	}
+	return ParseTokenMetadata(body)
+}
+
+// ParseTokenMetadata 解析元数据JSON中的attributes，数值和布尔特征值按字面转换为字符串，缺少特征名或超长的条目跳过
+func ParseTokenMetadata(body []byte) (*TokenMetadata, error) {
+	var raw struct {
+		Attributes []struct {
+			TraitType string      `json:"trait_type"`
+			Value     interface{} `json:"value"`
+		} `json:"attributes"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("解析元数据失败: %v", err)
+	}
+
+	metadata := &TokenMetadata{Attributes: make([]models.Trait, 0, len(raw.Attributes))}
+	for _, attribute := range raw.Attributes {
+		traitType := strings.TrimSpace(attribute.TraitType)
+		if traitType == "" || attribute.Value == nil {
+			continue
+		}
+		var value string
+		switch v := attribute.Value.(type) {
+		case string:
+			value = strings.TrimSpace(v)
+		case json.Number, bool:
+			value = fmt.Sprint(v)
+		default:
+			continue
+		}
+		if value == "" || utf8.RuneCountInString(traitType) > maxTraitTypeLength || utf8.RuneCountInString(value) > maxTraitValueLength {
+			continue
+		}
+		metadata.Attributes = append(metadata.Attributes, models.Trait{TraitType: traitType, Value: value})
+	}
+	return metadata, nil
+}
+
+// readMetadata 读取元数据地址的内容
+func readMetadata(ctx context.Context, uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		return decodeDataURI(uri)
+	}
+	if strings.HasPrefix(uri, "ipfs://") {
+		uri = ipfsGateway + strings.TrimPrefix(strings.TrimPrefix(uri, "ipfs://"), "ipfs/")
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("不支持的元数据地址: %s", uri)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("构建元数据请求失败: %v", err)
+	}
+	resp, err := metadataHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取元数据失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取元数据失败: HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取元数据失败: %v", err)
+	}
+	if len(body) > maxMetadataSize {
+		return nil, fmt.Errorf("元数据超过%d字节", maxMetadataSize)
+	}
+	return body, nil
+}
+
+// decodeDataURI 解码链上生成的data:application/json元数据，支持base64和URL编码
+func decodeDataURI(uri string) ([]byte, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok || !strings.HasPrefix(header, "application/json") {
+		return nil, fmt.Errorf("不支持的元数据地址: %.64s", uri)
+	}
+	if strings.HasSuffix(header, ";base64") {
+		body, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("解码base64元数据失败: %v", err)
+		}
+		return body, nil
+	}
+	body, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, fmt.Errorf("解码元数据失败: %v", err)
+	}
+	return []byte(body), nil
+}
@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"nft-market/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTokenMetadata(t *testing.T) {
+	body := `{
+		"name": "Token #1",
+		"attributes": [
+			{"trait_type": "Background", "value": " Gold "},
+			{"trait_type": "Level", "value": 5},
+			{"trait_type": "Legendary", "value": true},
+			{"trait_type": "", "value": "missing type"},
+			{"value": "no type"},
+			{"trait_type": "Empty", "value": ""},
+			{"trait_type": "Nested", "value": {"a": 1}},
+			{"trait_type": "Long", "value": "` + strings.Repeat("x", maxTraitValueLength+1) + `"}
+		]
+	}`
+
+	metadata, err := ParseTokenMetadata([]byte(body))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []models.Trait{
+		{TraitType: "Background", Value: "Gold"},
+		{TraitType: "Level", Value: "5"},
+		{TraitType: "Legendary", Value: "true"},
+	}
+	if !reflect.DeepEqual(metadata.Attributes, want) {
+		t.Fatalf("attributes = %+v, want %+v", metadata.Attributes, want)
+	}
+
+	if _, err := ParseTokenMetadata([]byte("not json")); err == nil {
+		t.Fatal("expected error for malformed metadata")
+	}
+}
+
+func TestFetchTokenMetadataDataURI(t *testing.T) {
+	body := `{"attributes":[{"trait_type":"Eyes","value":"Laser"}]}`
+	want := []models.Trait{{TraitType: "Eyes", Value: "Laser"}}
+
+	for _, uri := range []string{
+		"data:application/json;base64," + base64.StdEncoding.EncodeToString([]byte(body)),
+		"data:application/json," + url.PathEscape(body),
+	} {
+		metadata, err := FetchTokenMetadata(context.Background(), uri)
+		if err != nil {
+			t.Fatalf("%.40s: %v", uri, err)
+		}
+		if !reflect.DeepEqual(metadata.Attributes, want) {
+			t.Fatalf("%.40s: attributes = %+v", uri, metadata.Attributes)
+		}
+	}
+
+	for _, uri := range []string{"data:text/plain,hello", "file:///etc/passwd", "http://127.0.0.1/metadata.json"} {
+		if _, err := FetchTokenMetadata(context.Background(), uri); err == nil {
+			t.Errorf("%s: expected error", uri)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"nft-market/internal/logger"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// NonceSource 提供账户待处理nonce的节点接口，ethclient.Client与模拟链均实现该接口
+type NonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceManager 热钱包nonce管理器
+// 同一发送方的nonce分配与交易发送串行执行：发送成功后nonce才递增，
+// 发送失败时nonce不被消耗，并在下次发送前与节点重新同步，避免并发发送抢占同一nonce或留下空洞
+type NonceManager struct {
+	source   NonceSource
+	mu       sync.Mutex
+	accounts map[common.Address]*accountNonce
+}
+
+// accountNonce 单个发送方的nonce状态
+type accountNonce struct {
+	mu     sync.Mutex
+	next   uint64
+	synced bool
+}
+
+// NewNonceManager 创建nonce管理器
+func NewNonceManager(source NonceSource) *NonceManager {
+	return &NonceManager{
+		source:   source,
+		accounts: make(map[common.Address]*accountNonce),
+	}
+}
+
+// Sync 从节点同步发送方的nonce，启动时调用以接续节点上已有的待处理交易
+func (m *NonceManager) Sync(ctx context.Context, account common.Address) error {
+	a := m.account(account)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return m.syncLocked(ctx, account, a)
+}
+
+// Next 返回发送方下一个将被分配的nonce
+func (m *NonceManager) Next(ctx context.Context, account common.Address) (uint64, error) {
+	a := m.account(account)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.synced {
+		if err := m.syncLocked(ctx, account, a); err != nil {
+			return 0, err
+		}
+	}
+	return a.next, nil
+}
+
+// Send 为发送方分配nonce并调用send发送交易
+// 同一发送方的调用串行执行；节点返回nonce冲突时重新同步后重试一次
+func (m *NonceManager) Send(ctx context.Context, account common.Address, send func(nonce uint64) error) error {
+	a := m.account(account)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.synced {
+		if err := m.syncLocked(ctx, account, a); err != nil {
+			return err
+		}
+	}
+
+	err := send(a.next)
+	if err == nil || isAlreadyKnown(err) {
+		// already known表示同一笔交易已在节点交易池中，nonce同样已被占用
+		a.next++
+		return nil
+	}
+
+	if !isNonceError(err) {
+		// 交易可能已到达节点（如请求超时），下次发送前以节点为准重新同步
+		a.synced = false
+		return err
+	}
+
+	logger.Warn("nonce冲突，与节点重新同步后重试", logrus.Fields{
+		"account": account.Hex(),
+		"nonce":   a.next,
+		"error":   err.Error(),
+	})
+	if syncErr := m.syncLocked(ctx, account, a); syncErr != nil {
+		return syncErr
+	}
+	if err := send(a.next); err != nil {
+		a.synced = false
+		return err
+	}
+	a.next++
+	return nil
+}
+
+// account 获取发送方的nonce状态，不存在时创建
+func (m *NonceManager) account(account common.Address) *accountNonce {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.accounts[account]
+	if !ok {
+		a = &accountNonce{}
+		m.accounts[account] = a
+	}
+	return a
+}
+
+// syncLocked 以节点的待处理nonce为准重置本地nonce，调用方需持有a.mu
+// 本地nonce高于节点时说明有交易被丢弃，回退到节点nonce即可补上空洞
+func (m *NonceManager) syncLocked(ctx context.Context, account common.Address, a *accountNonce) error {
+	nonce, err := m.source.PendingNonceAt(ctx, account)
+	if err != nil {
+		a.synced = false
+		return fmt.Errorf("获取nonce失败: %v", err)
+	}
+
+	if a.synced && nonce != a.next {
+		logger.Info("nonce已与节点重新同步", logrus.Fields{
+			"account":     account.Hex(),
+			"local_nonce": a.next,
+			"node_nonce":  nonce,
+		})
+	}
+	a.next = nonce
+	a.synced = true
+	return nil
+}
+
+// isNonceError 判断节点返回的错误是否由nonce冲突引起
+func isNonceError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") ||
+		strings.Contains(msg, "nonce too high") ||
+		strings.Contains(msg, "invalid transaction nonce") ||
+		strings.Contains(msg, "replacement transaction underpriced")
+}
+
+// isAlreadyKnown 判断节点是否已收到同一笔交易
+func isAlreadyKnown(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "already known")
+}
@@ -0,0 +1,214 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"nft-market/internal/logger"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.Init(&logger.Config{Level: "error", Format: "text", Output: "console"}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestBackend 创建带有一个已充值账户的模拟链
+func newTestBackend(t *testing.T) (*backends.SimulatedBackend, *ecdsa.PrivateKey, common.Address) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("生成私钥失败: %v", err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	balance := new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{from: {Balance: balance}}, 10_000_000)
+	t.Cleanup(func() { backend.Close() })
+	return backend, key, from
+}
+
+// sendTransfer 以指定nonce发送一笔转账
+func sendTransfer(backend *backends.SimulatedBackend, key *ecdsa.PrivateKey, nonce uint64) error {
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	tx := types.NewTransaction(nonce, to, big.NewInt(1), 21000, big.NewInt(params.GWei), nil)
+	signed, err := types.SignTx(tx, types.HomesteadSigner{}, key)
+	if err != nil {
+		return err
+	}
+	return backend.SendTransaction(context.Background(), signed)
+}
+
+func TestNonceManagerConcurrentSends(t *testing.T) {
+	backend, key, from := newTestBackend(t)
+	manager := NewNonceManager(backend)
+
+	const sends = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, sends)
+	for i := 0; i < sends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- manager.Send(context.Background(), from, func(nonce uint64) error {
+				return sendTransfer(backend, key, nonce)
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("并发发送失败: %v", err)
+		}
+	}
+
+	backend.Commit()
+	nonce, err := backend.NonceAt(context.Background(), from, nil)
+	if err != nil {
+		t.Fatalf("获取nonce失败: %v", err)
+	}
+	if nonce != sends {
+		t.Fatalf("链上nonce = %d, 期望 %d", nonce, sends)
+	}
+}
+
+func TestNonceManagerFailedSendDoesNotConsumeNonce(t *testing.T) {
+	backend, key, from := newTestBackend(t)
+	manager := NewNonceManager(backend)
+	ctx := context.Background()
+
+	sendErr := errors.New("网络错误")
+	var attempted uint64
+	err := manager.Send(ctx, from, func(nonce uint64) error {
+		attempted = nonce
+		return sendErr
+	})
+	if !errors.Is(err, sendErr) {
+		t.Fatalf("期望返回发送错误, 实际 %v", err)
+	}
+
+	var used uint64
+	err = manager.Send(ctx, from, func(nonce uint64) error {
+		used = nonce
+		return sendTransfer(backend, key, nonce)
+	})
+	if err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	if used != attempted {
+		t.Fatalf("失败后的nonce = %d, 期望复用 %d", used, attempted)
+	}
+}
+
+func TestNonceManagerSyncOnStartup(t *testing.T) {
+	backend, key, from := newTestBackend(t)
+	ctx := context.Background()
+
+	// 模拟重启前已发送的交易
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		if err := sendTransfer(backend, key, nonce); err != nil {
+			t.Fatalf("发送交易失败: %v", err)
+		}
+	}
+
+	manager := NewNonceManager(backend)
+	if err := manager.Sync(ctx, from); err != nil {
+		t.Fatalf("同步nonce失败: %v", err)
+	}
+	next, err := manager.Next(ctx, from)
+	if err != nil {
+		t.Fatalf("获取nonce失败: %v", err)
+	}
+	if next != 3 {
+		t.Fatalf("同步后的nonce = %d, 期望 3", next)
+	}
+}
+
+func TestNonceManagerRecoversFromExternalSend(t *testing.T) {
+	backend, key, from := newTestBackend(t)
+	manager := NewNonceManager(backend)
+	ctx := context.Background()
+
+	if err := manager.Sync(ctx, from); err != nil {
+		t.Fatalf("同步nonce失败: %v", err)
+	}
+
+	// 其他进程使用同一私钥占用了nonce 0
+	if err := sendTransfer(backend, key, 0); err != nil {
+		t.Fatalf("发送交易失败: %v", err)
+	}
+
+	var used []uint64
+	err := manager.Send(ctx, from, func(nonce uint64) error {
+		used = append(used, nonce)
+		return sendTransfer(backend, key, nonce)
+	})
+	if err != nil {
+		t.Fatalf("nonce冲突后重试失败: %v", err)
+	}
+	if len(used) != 2 || used[0] != 0 || used[1] != 1 {
+		t.Fatalf("尝试的nonce = %v, 期望 [0 1]", used)
+	}
+}
+
+func TestNonceManagerFillsGapAfterDroppedTransaction(t *testing.T) {
+	backend, key, from := newTestBackend(t)
+	manager := NewNonceManager(backend)
+	ctx := context.Background()
+
+	// 节点接受后又丢弃的交易：本地nonce已递增，节点上没有该交易
+	if err := manager.Send(ctx, from, func(uint64) error { return nil }); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	var used []uint64
+	err := manager.Send(ctx, from, func(nonce uint64) error {
+		used = append(used, nonce)
+		return sendTransfer(backend, key, nonce)
+	})
+	if err != nil {
+		t.Fatalf("补齐nonce空洞失败: %v", err)
+	}
+	if len(used) != 2 || used[1] != 0 {
+		t.Fatalf("尝试的nonce = %v, 期望最终使用0", used)
+	}
+
+	next, err := manager.Next(ctx, from)
+	if err != nil {
+		t.Fatalf("获取nonce失败: %v", err)
+	}
+	if next != 1 {
+		t.Fatalf("下一个nonce = %d, 期望 1", next)
+	}
+}
+
+func TestIsNonceError(t *testing.T) {
+	cases := map[string]bool{
+		"nonce too low":                              true,
+		"nonce too high":                             true,
+		"replacement transaction underpriced":        true,
+		"invalid transaction nonce: got 1, want 0":   true,
+		"insufficient funds for gas * price + value": false,
+		"already known":                              false,
+	}
+	for msg, want := range cases {
+		if got := isNonceError(errors.New(msg)); got != want {
+			t.Errorf("isNonceError(%q) = %v, 期望 %v", msg, got, want)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// itemKey 物品唯一标识
+type itemKey struct {
+	CollectionAddress string
+	TokenID           string
+}
+
+// checkReorg 对比已索引区块hash与当前主链，发现分叉时回滚到共同祖先
+func (el *EventListener) checkReorg() error {
+	nextBlock := el.getNextBlock()
+	if nextBlock == 0 {
+		return nil
+	}
+
+	var blocks []models.IndexedBlock
+	err := el.db.Where("contract_address = ? AND block_number < ?", el.contractAddress.Hex(), nextBlock).
+		Order("block_number DESC").
+		Limit(reorgHistoryBlocks).
+		Find(&blocks).Error
+	if err != nil {
+		return fmt.Errorf("查询已索引区块失败: %v", err)
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	// 从最新的已索引区块往回找，第一个仍在主链上的区块即共同祖先
+	for i, block := range blocks {
+		header, err := el.client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(block.BlockNumber))
+		if err != nil {
+			return fmt.Errorf("获取区块头失败: %v", err)
+		}
+		if header.Hash().Hex() == block.BlockHash {
+			if i == 0 {
+				return nil
+			}
+			return el.rollbackFrom(block.BlockNumber + 1)
+		}
+	}
+
+	// 窗口内所有区块都已被替换，只能回滚到窗口起点重新同步
+	oldest := blocks[len(blocks)-1].BlockNumber
+	logger.Warn("链重组深度超出检测窗口", logrus.Fields{
+		"oldest_indexed_block": oldest,
+		"window":               reorgHistoryBlocks,
+	})
+	return el.rollbackFrom(oldest)
+}
+
+// rollbackFrom 删除fromBlock及之后区块产生的订单、物品和活动，并把同步进度回退到fromBlock之前
+func (el *EventListener) rollbackFrom(fromBlock uint64) error {
+	contractHex := el.contractAddress.Hex()
+	logger.Warn("检测到链重组，开始回滚", logrus.Fields{
+		"contract_address": contractHex,
+		"from_block":       fromBlock,
+	})
+
+	err := el.db.Transaction(func(tx *gorm.DB) error {
+		// 记录受影响的物品，回滚后重新计算上架价格
+		var affectedOrders []models.Order
+		if err := tx.Unscoped().
+			Select("collection_address", "token_id").
+			Where("(block_number >= ? AND block_hash IS NOT NULL) OR (status_block_number >= ? AND status_block_number > 0)", fromBlock, fromBlock).
+			Find(&affectedOrders).Error; err != nil {
+			return fmt.Errorf("查询受影响订单失败: %v", err)
+		}
+
+		// 删除在被重组区块中创建的订单
+		ordersResult := tx.Unscoped().
+			Where("block_number >= ? AND block_hash IS NOT NULL", fromBlock).
+			Delete(&models.Order{})
+		if ordersResult.Error != nil {
+			return fmt.Errorf("删除重组订单失败: %v", ordersResult.Error)
+		}
+
+		// 状态变更发生在被重组区块中的订单恢复为有效（成交/取消/过期都只能从有效状态转入）
+		statusResult := tx.Model(&models.Order{}).
+			Where("status_block_number >= ? AND status_block_number > 0", fromBlock).
+			Updates(map[string]interface{}{
+				"order_status":        models.OrderStatusActive,
+				"status_block_number": 0,
+				"update_time":         time.Now().Unix(),
+			})
+		if statusResult.Error != nil {
+			return fmt.Errorf("恢复订单状态失败: %v", statusResult.Error)
+		}
+
+		// 按转移活动倒序把物品拥有者恢复为转出方，活动删除后无法再追溯
+		erc1155, err := erc1155Collections(tx)
+		if err != nil {
+			return err
+		}
+		transferredItems, err := restoreTransferredOwners(tx, fromBlock, erc1155)
+		if err != nil {
+			return err
+		}
+		balanceOwners, err := restoreTransferredBalances(tx, fromBlock, erc1155)
+		if err != nil {
+			return err
+		}
+
+		// 删除由被重组区块中事件生成的活动
+		activitiesResult := tx.Unscoped().
+			Where("block_number >= ? AND block_hash IS NOT NULL", fromBlock).
+			Delete(&models.Activity{})
+		if activitiesResult.Error != nil {
+			return fmt.Errorf("删除重组活动失败: %v", activitiesResult.Error)
+		}
+
+		// 删除由被重组区块中事件创建的物品
+		itemsResult := tx.Unscoped().
+			Where("block_number >= ? AND block_number > 0", fromBlock).
+			Delete(&models.Item{})
+		if itemsResult.Error != nil {
+			return fmt.Errorf("删除重组物品失败: %v", itemsResult.Error)
+		}
+
+		affectedItems := make(map[itemKey]struct{})
+		for _, order := range affectedOrders {
+			affectedItems[itemKey{order.CollectionAddress, order.TokenID}] = struct{}{}
+		}
+		for key := range affectedItems {
+			if err := refreshItemListPrice(tx, key); err != nil {
+				return err
+			}
+		}
+		for key, owner := range transferredItems {
+			if err := updateListingValidity(tx, key, owner, time.Now().Unix()); err != nil {
+				return err
+			}
+		}
+		for key, owners := range balanceOwners {
+			for owner := range owners {
+				if err := UpdateListingBalanceValidity(tx, key.CollectionAddress, key.TokenID, owner); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 删除已处理日志记录，新主链上的日志才能重新处理
+		if err := tx.Where("chain_id = ? AND block_number >= ?", el.chainID, fromBlock).
+			Delete(&models.ProcessedLog{}).Error; err != nil {
+			return fmt.Errorf("删除重组日志记录失败: %v", err)
+		}
+		if err := tx.Where("chain_id = ? AND block_number >= ?", el.chainID, fromBlock).
+			Delete(&models.FailedLog{}).Error; err != nil {
+			return fmt.Errorf("删除重组失败日志记录失败: %v", err)
+		}
+
+		// 集合的转移同步进度随主同步进度一起回退
+		if err := tx.Model(&models.Collection{}).Where("sync_block > ?", fromBlock).
+			Update("sync_block", fromBlock).Error; err != nil {
+			return fmt.Errorf("回退集合同步进度失败: %v", err)
+		}
+
+		if err := tx.Where("contract_address = ? AND block_number >= ?", contractHex, fromBlock).
+			Delete(&models.IndexedBlock{}).Error; err != nil {
+			return fmt.Errorf("删除重组区块记录失败: %v", err)
+		}
+
+		if fromBlock == 0 {
+			return tx.Where("contract_address = ?", contractHex).Delete(&models.SyncState{}).Error
+		}
+		return tx.Model(&models.SyncState{}).
+			Where("contract_address = ?", contractHex).
+			Updates(map[string]interface{}{
+				"last_synced_block": fromBlock - 1,
+				"update_time":       time.Now().Unix(),
+			}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("回滚重组数据失败: %v", err)
+	}
+
+	now := time.Now().Unix()
+	el.mu.Lock()
+	el.nextBlock = fromBlock
+	el.reorgCount++
+	el.lastReorgFrom = int64(fromBlock)
+	el.lastReorgTime = now
+	el.mu.Unlock()
+
+	logger.Info("链重组回滚完成", logrus.Fields{
+		"from_block": fromBlock,
+	})
+	return nil
+}
+
+// RefreshItemListPrice 订单有效性变化后重新计算物品上架价格
+func RefreshItemListPrice(db *gorm.DB, collectionAddress, tokenID string) error {
+	return refreshItemListPrice(db, itemKey{collectionAddress, tokenID})
+}
+
+// refreshItemListPrice 按剩余的有效上架订单重新计算物品上架价格
+func refreshItemListPrice(tx *gorm.DB, key itemKey) error {
+	var lowest models.Order
+	err := tx.Where("collection_address = ? AND token_id = ? AND order_type = ? AND order_status = ? AND invalid = ?",
+		key.CollectionAddress, key.TokenID, models.OrderTypeListing, models.OrderStatusActive, false).
+		Order("price ASC").
+		First(&lowest).Error
+
+	updates := map[string]interface{}{
+		"update_time": time.Now().Unix(),
+	}
+	switch err {
+	case nil:
+		updates["list_price"] = lowest.Price
+		updates["list_time"] = lowest.CreateTime
+	case gorm.ErrRecordNotFound:
+		updates["list_price"] = nil
+		updates["list_time"] = nil
+	default:
+		return fmt.Errorf("查询物品有效上架订单失败: %v", err)
+	}
+
+	if err := tx.Model(&models.Item{}).
+		Where("collection_address = ? AND token_id = ?", key.CollectionAddress, key.TokenID).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("更新物品上架价格失败: %v", err)
+	}
+	return nil
+}
+
+// restoreTransferredOwners 撤销fromBlock及之后区块中的ERC-721转移，返回受影响物品恢复后的拥有者
+func restoreTransferredOwners(tx *gorm.DB, fromBlock uint64, erc1155 map[string]bool) (map[itemKey]string, error) {
+	var transfers []models.Activity
+	if err := tx.Where("block_number >= ? AND block_hash IS NOT NULL AND activity_type IN ?", fromBlock,
+		[]models.ActivityType{models.ActivityTypeTransfer, models.ActivityTypeMint}).
+		Order("block_number DESC, log_index DESC").
+		Find(&transfers).Error; err != nil {
+		return nil, fmt.Errorf("查询重组转移活动失败: %v", err)
+	}
+
+	owners := make(map[itemKey]string)
+	for _, transfer := range transfers {
+		if transfer.CollectionAddress == nil || transfer.TokenID == nil || transfer.Maker == nil {
+			continue
+		}
+		if erc1155[strings.ToLower(*transfer.CollectionAddress)] {
+			continue
+		}
+		key := itemKey{*transfer.CollectionAddress, *transfer.TokenID}
+		owners[key] = *transfer.Maker
+		if err := tx.Model(&models.Item{}).
+			Where("collection_address = ? AND token_id = ?", key.CollectionAddress, key.TokenID).
+			Updates(map[string]interface{}{
+				"owner":       *transfer.Maker,
+				"update_time": time.Now().Unix(),
+			}).Error; err != nil {
+			return nil, fmt.Errorf("恢复物品拥有者失败: %v", err)
+		}
+	}
+	return owners, nil
+}
+
+// restoreTransferredBalances 撤销fromBlock及之后区块中的ERC-1155转移：持有数量和供应量按转移数量反向调整，返回受影响的物品及拥有者
+func restoreTransferredBalances(tx *gorm.DB, fromBlock uint64, erc1155 map[string]bool) (map[itemKey]map[string]struct{}, error) {
+	affected := make(map[itemKey]map[string]struct{})
+	if len(erc1155) == 0 {
+		return affected, nil
+	}
+
+	var transfers []models.Activity
+	if err := tx.Where("block_number >= ? AND block_hash IS NOT NULL AND activity_type IN ?", fromBlock,
+		[]models.ActivityType{models.ActivityTypeTransfer, models.ActivityTypeMint}).
+		Order("block_number DESC, log_index DESC").
+		Find(&transfers).Error; err != nil {
+		return nil, fmt.Errorf("查询重组转移活动失败: %v", err)
+	}
+
+	zero := common.Address{}.Hex()
+	for _, transfer := range transfers {
+		if transfer.CollectionAddress == nil || transfer.TokenID == nil || transfer.Maker == nil || transfer.Taker == nil {
+			continue
+		}
+		if !erc1155[strings.ToLower(*transfer.CollectionAddress)] {
+			continue
+		}
+		key := itemKey{*transfer.CollectionAddress, *transfer.TokenID}
+		if affected[key] == nil {
+			affected[key] = make(map[string]struct{})
+		}
+
+		from, to := *transfer.Maker, *transfer.Taker
+		if from != zero {
+			if err := AdjustItemBalance(tx, key.CollectionAddress, key.TokenID, from, transfer.Amount); err != nil {
+				return nil, err
+			}
+			affected[key][from] = struct{}{}
+		}
+		if to != zero {
+			if err := AdjustItemBalance(tx, key.CollectionAddress, key.TokenID, to, -transfer.Amount); err != nil {
+				return nil, err
+			}
+			affected[key][to] = struct{}{}
+		}
+
+		// 撤销铸造减少供应量，撤销销毁恢复供应量
+		var supply interface{}
+		if from == zero {
+			supply = gorm.Expr("GREATEST(supply - ?, 0)", transfer.Amount)
+		} else if to == zero {
+			supply = gorm.Expr("supply + ?", transfer.Amount)
+		} else {
+			continue
+		}
+		if err := tx.Model(&models.Item{}).
+			Where("collection_address = ? AND token_id = ?", key.CollectionAddress, key.TokenID).
+			Updates(map[string]interface{}{
+				"supply":      supply,
+				"update_time": time.Now().Unix(),
+			}).Error; err != nil {
+			return nil, fmt.Errorf("恢复物品供应量失败: %v", err)
+		}
+	}
+	return affected, nil
+}
+
+// erc1155Collections 已检测为ERC-1155的集合地址（小写）
+func erc1155Collections(tx *gorm.DB) (map[string]bool, error) {
+	var addresses []string
+	if err := tx.Model(&models.Collection{}).
+		Where("standard = ?", models.TokenStandardERC1155).
+		Pluck("address", &addresses).Error; err != nil {
+		return nil, fmt.Errorf("查询ERC-1155集合失败: %v", err)
+	}
+	result := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		result[strings.ToLower(address)] = true
+	}
+	return result, nil
+}
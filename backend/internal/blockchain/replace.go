@@ -0,0 +1,108 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// replacementBumpPercent 替换交易的手续费涨幅，节点要求至少10%
+	replacementBumpPercent = 12
+	// cancelGasLimit 取消交易（0金额自转账）的gas上限
+	cancelGasLimit = 21000
+)
+
+// ReplaceTransaction 以相同nonce重新发送热钱包交易
+// cancel为false时按原交易内容加速，为true时发送0金额自转账以取消原交易；
+// 手续费取原交易上调12%与当前建议值中的较高者
+func (c *NFTMarketplaceContract) ReplaceTransaction(txHash common.Hash, cancel bool) (*types.Transaction, error) {
+	if c.signer == nil {
+		return nil, ErrSignerNotConfigured
+	}
+
+	ctx := context.Background()
+	original, isPending, err := c.client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("获取原交易失败: %v", err)
+	}
+	if !isPending {
+		return nil, fmt.Errorf("交易已上链，无需替换")
+	}
+
+	signer := types.LatestSignerForChainID(c.chainID)
+	sender, err := types.Sender(signer, original)
+	if err != nil {
+		return nil, fmt.Errorf("解析交易发送方失败: %v", err)
+	}
+	if sender != c.fromAddress {
+		return nil, fmt.Errorf("只能替换热钱包发送的交易")
+	}
+
+	to := *original.To()
+	value := original.Value()
+	data := original.Data()
+	gasLimit := original.Gas()
+	if cancel {
+		to = c.fromAddress
+		value = big.NewInt(0)
+		data = nil
+		gasLimit = cancelGasLimit
+	}
+
+	fees, err := c.feeConfig.suggestGasPrices(ctx, c.client)
+	if err != nil {
+		return nil, err
+	}
+	fees.GasLimit = gasLimit
+	if err := fees.bumpOver(original, c.feeConfig.MaxFeeCap); err != nil {
+		return nil, err
+	}
+
+	replacement, err := c.signer.SignTx(fees.newTransaction(c.chainID, original.Nonce(), to, value, data), c.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("签名交易失败: %v", err)
+	}
+	if err := c.client.SendTransaction(ctx, replacement); err != nil {
+		return nil, fmt.Errorf("发送替换交易失败: %v", err)
+	}
+	return replacement, nil
+}
+
+// bumpOver 把手续费提高到足以替换原交易的水平，超过上限时返回错误
+func (f *txFees) bumpOver(original *types.Transaction, maxFeeCap *big.Int) error {
+	if f.GasTipCap == nil {
+		f.GasPrice = maxBig(f.GasPrice, bumped(original.GasPrice()))
+		if maxFeeCap != nil && f.GasPrice.Cmp(maxFeeCap) > 0 {
+			return fmt.Errorf("替换交易所需gas价格%s超过上限%s", f.GasPrice, maxFeeCap)
+		}
+		return nil
+	}
+
+	f.GasTipCap = maxBig(f.GasTipCap, bumped(original.GasTipCap()))
+	f.GasFeeCap = maxBig(f.GasFeeCap, bumped(original.GasFeeCap()))
+	if f.GasFeeCap.Cmp(f.GasTipCap) < 0 {
+		f.GasFeeCap = new(big.Int).Set(f.GasTipCap)
+	}
+	if maxFeeCap != nil && f.GasFeeCap.Cmp(maxFeeCap) > 0 {
+		return fmt.Errorf("替换交易所需费用上限%s超过配置上限%s", f.GasFeeCap, maxFeeCap)
+	}
+	return nil
+}
+
+// bumped 返回上调replacementBumpPercent后的费用
+func bumped(v *big.Int) *big.Int {
+	out := new(big.Int).Mul(v, big.NewInt(100+replacementBumpPercent))
+	return out.Div(out, big.NewInt(100))
+}
+
+// maxBig 返回较大值
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
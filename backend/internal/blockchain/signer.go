@@ -0,0 +1,213 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"nft-market/internal/logger"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/sirupsen/logrus"
+)
+
+// 签名器类型
+const (
+	SignerTypePrivateKey = "private_key" // 明文私钥，仅用于开发环境
+	SignerTypeKeystore   = "keystore"    // 加密的keystore文件
+	SignerTypeClef       = "clef"        // 外部签名服务(Clef JSON-RPC)
+)
+
+// ErrSignerNotConfigured 未配置交易签名器，服务以只读模式运行
+var ErrSignerNotConfigured = errors.New("未配置交易签名器")
+
+// clefSignTimeout 外部签名请求超时时间，Clef可能需要人工确认
+const clefSignTimeout = 2 * time.Minute
+
+// Signer 热钱包交易签名器
+type Signer interface {
+	// Address 返回签名账户地址
+	Address() common.Address
+	// SignTx 按链ID签名交易
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// SignerConfig 签名器配置
+type SignerConfig struct {
+	Type         string // 签名器类型，为空时若配置了私钥则使用明文私钥
+	PrivateKey   string // 十六进制私钥
+	KeystorePath string // keystore JSON文件路径
+	PasswordFile string // keystore密码文件路径
+	ClefURL      string // Clef JSON-RPC地址
+	ClefAddress  string // Clef中用于签名的账户地址
+}
+
+// NewSigner 按配置创建签名器
+func NewSigner(config SignerConfig) (Signer, error) {
+	signerType := config.Type
+	if signerType == "" && config.PrivateKey != "" {
+		signerType = SignerTypePrivateKey
+	}
+
+	switch signerType {
+	case SignerTypePrivateKey:
+		return NewPrivateKeySigner(config.PrivateKey)
+	case SignerTypeKeystore:
+		return NewKeystoreSigner(config.KeystorePath, config.PasswordFile)
+	case SignerTypeClef:
+		clef, err := NewClefSigner(config.ClefURL, config.ClefAddress)
+		if err != nil {
+			return nil, err
+		}
+		return clef, nil
+	case "":
+		return nil, ErrSignerNotConfigured
+	default:
+		return nil, fmt.Errorf("未知的签名器类型: %s", signerType)
+	}
+}
+
+// keySigner 使用内存中的私钥签名
+type keySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// Address 返回签名账户地址
+func (s *keySigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx 按链ID签名交易
+func (s *keySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// NewPrivateKeySigner 由十六进制私钥创建签名器，仅建议在开发环境使用
+func NewPrivateKeySigner(privateKeyHex string) (Signer, error) {
+	// 移除0x前缀如果存在
+	if strings.HasPrefix(privateKeyHex, "0x") || strings.HasPrefix(privateKeyHex, "0X") {
+		privateKeyHex = privateKeyHex[2:]
+	}
+	key, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %v", err)
+	}
+
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	logger.Warn("使用明文私钥签名交易，生产环境请改用keystore或外部签名服务", logrus.Fields{
+		"address": address.Hex(),
+	})
+	return &keySigner{key: key, address: address}, nil
+}
+
+// NewKeystoreSigner 使用密码文件解密go-ethereum keystore JSON文件创建签名器
+func NewKeystoreSigner(keystorePath, passwordFile string) (Signer, error) {
+	keyJSON, err := os.ReadFile(keystorePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取keystore文件失败: %v", err)
+	}
+	password, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取keystore密码文件失败: %v", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, strings.TrimRight(string(password), "\r\n"))
+	if err != nil {
+		return nil, fmt.Errorf("解密keystore失败: %v", err)
+	}
+	return &keySigner{key: key.PrivateKey, address: key.Address}, nil
+}
+
+// ClefSigner 通过Clef的account_signTransaction接口签名，私钥不进入本进程
+type ClefSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// clefSignResult account_signTransaction返回结果
+type clefSignResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// NewClefSigner 连接外部签名服务
+func NewClefSigner(endpoint, address string) (*ClefSigner, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("无效的签名账户地址: %s", address)
+	}
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("连接外部签名服务失败: %v", err)
+	}
+	return &ClefSigner{client: client, address: common.HexToAddress(address)}, nil
+}
+
+// Address 返回签名账户地址
+func (s *ClefSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx 请求外部签名服务签名交易，并校验返回交易的签名账户
+func (s *ClefSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	data := hexutil.Bytes(tx.Data())
+	args := apitypes.SendTxArgs{
+		From:    common.NewMixedcaseAddress(s.address),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   hexutil.Big(*tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    &data,
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.DynamicFeeTxType:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	default:
+		return nil, fmt.Errorf("不支持的交易类型: %d", tx.Type())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clefSignTimeout)
+	defer cancel()
+
+	var result clefSignResult
+	if err := s.client.CallContext(ctx, &result, "account_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("外部签名失败: %v", err)
+	}
+	if result.Tx == nil {
+		return nil, fmt.Errorf("外部签名服务未返回交易")
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), result.Tx)
+	if err != nil {
+		return nil, fmt.Errorf("解析外部签名失败: %v", err)
+	}
+	if sender != s.address {
+		return nil, fmt.Errorf("外部签名账户不匹配: 期望%s, 实际%s", s.address.Hex(), sender.Hex())
+	}
+	if result.Tx.Nonce() != tx.Nonce() {
+		return nil, fmt.Errorf("外部签名返回的nonce不匹配: 期望%d, 实际%d", tx.Nonce(), result.Tx.Nonce())
+	}
+	return result.Tx, nil
+}
+
+// Close 关闭与外部签名服务的连接
+func (s *ClefSigner) Close() {
+	s.client.Close()
+}
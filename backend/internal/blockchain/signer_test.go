@@ -0,0 +1,164 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// clefStub 模拟Clef的account_signTransaction接口
+type clefStub struct {
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+}
+
+func (s *clefStub) SignTransaction(args apitypes.SendTxArgs) (*clefSignResult, error) {
+	tx, err := types.SignTx(args.ToTransaction(), types.LatestSignerForChainID(s.chainID), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &clefSignResult{Raw: raw, Tx: tx}, nil
+}
+
+func newTestTx(nonce uint64) *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1337),
+		Nonce:     nonce,
+		To:        &common.Address{1},
+		Value:     big.NewInt(1),
+		Gas:       21000,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+	})
+}
+
+func assertSignedBy(t *testing.T, tx *types.Transaction, chainID *big.Int, want common.Address) {
+	t.Helper()
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		t.Fatalf("recover sender: %v", err)
+	}
+	if sender != want {
+		t.Fatalf("sender = %s, want %s", sender.Hex(), want.Hex())
+	}
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{PrivateKey: key, Address: address},
+		"secret", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("encrypt key: %v", err)
+	}
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key.json")
+	passwordPath := filepath.Join(dir, "password")
+	if err := os.WriteFile(keyPath, keyJSON, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(passwordPath, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := NewSigner(SignerConfig{Type: SignerTypeKeystore, KeystorePath: keyPath, PasswordFile: passwordPath})
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	if signer.Address() != address {
+		t.Fatalf("address = %s, want %s", signer.Address().Hex(), address.Hex())
+	}
+
+	chainID := big.NewInt(1337)
+	signed, err := signer.SignTx(newTestTx(3), chainID)
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	assertSignedBy(t, signed, chainID, address)
+
+	if err := os.WriteFile(passwordPath, []byte("wrong"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewKeystoreSigner(keyPath, passwordPath); err == nil {
+		t.Fatal("expected error for wrong password")
+	}
+}
+
+func TestClefSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	chainID := big.NewInt(1337)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", &clefStub{key: key, chainID: chainID}); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	defer server.Stop()
+
+	signer, err := NewSigner(SignerConfig{Type: SignerTypeClef, ClefURL: httpServer.URL, ClefAddress: address.Hex()})
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	defer signer.(*ClefSigner).Close()
+
+	tx := newTestTx(7)
+	signed, err := signer.SignTx(tx, chainID)
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	assertSignedBy(t, signed, chainID, address)
+	if signed.Nonce() != tx.Nonce() || signed.GasFeeCap().Cmp(tx.GasFeeCap()) != 0 {
+		t.Fatalf("signed tx does not match request")
+	}
+
+	// 外部签名服务使用了其他账户签名时拒绝
+	other, _ := crypto.GenerateKey()
+	mismatched, err := NewClefSigner(httpServer.URL, crypto.PubkeyToAddress(other.PublicKey).Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mismatched.Close()
+	if _, err := mismatched.SignTx(tx, chainID); err == nil {
+		t.Fatal("expected error for mismatched signer")
+	}
+}
+
+func TestNewSignerSelection(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	hexKey := common.Bytes2Hex(crypto.FromECDSA(key))
+
+	signer, err := NewSigner(SignerConfig{PrivateKey: "0x" + hexKey})
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	if signer.Address() != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatal("unexpected private key signer address")
+	}
+
+	if _, err := NewSigner(SignerConfig{}); err == nil {
+		t.Fatal("expected error when no signer is configured")
+	}
+	if _, err := NewSigner(SignerConfig{Type: "hsm"}); err == nil {
+		t.Fatal("expected error for unknown signer type")
+	}
+	if _, err := NewSigner(SignerConfig{Type: SignerTypeClef, ClefAddress: "not-an-address"}); err == nil {
+		t.Fatal("expected error for invalid clef address")
+	}
+}
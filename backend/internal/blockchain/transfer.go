@@ -0,0 +1,367 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// transferEventName ERC-721 Transfer事件登记到已处理日志时使用的名称
+const transferEventName = "Transfer"
+
+// transferEventTopic keccak256("Transfer(address,address,uint256)")，ERC-20与ERC-721相同，按Topics数量区分
+var transferEventTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+var (
+	// transferSingleTopic ERC-1155 TransferSingle事件签名
+	transferSingleTopic = erc1155ABI.Events["TransferSingle"].ID
+	// transferBatchTopic ERC-1155 TransferBatch事件签名
+	transferBatchTopic = erc1155ABI.Events["TransferBatch"].ID
+)
+
+// TransferEvent NFT转移事件，from为零地址表示铸造；ERC-1155批量转移按token拆分为多条
+type TransferEvent struct {
+	Collection common.Address
+	From       common.Address
+	To         common.Address
+	TokenId    *big.Int
+	Amount     *big.Int // ERC-1155转移数量，ERC-721为nil
+}
+
+// collectionAddresses 获取转移事件已同步到fromBlock、随市场合约事件一起跟踪的集合合约地址
+// 仍在回溯历史的集合不在其中，避免新转移先于历史转移处理
+func (el *EventListener) collectionAddresses(fromBlock uint64) ([]common.Address, error) {
+	var addresses []string
+	err := el.db.Model(&models.Collection{}).Where("sync_block = ?", fromBlock).Pluck("address", &addresses).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询集合地址失败: %v", err)
+	}
+
+	result := make([]common.Address, 0, len(addresses))
+	for _, address := range addresses {
+		if common.IsHexAddress(address) {
+			result = append(result, common.HexToAddress(address))
+		}
+	}
+	return result, nil
+}
+
+// advanceCollectionSync 批次同步完成后推进随主同步跟踪的集合的转移同步进度
+func (el *EventListener) advanceCollectionSync(fromBlock, toBlock uint64) error {
+	err := el.db.Model(&models.Collection{}).
+		Where("sync_block = ?", fromBlock).
+		Update("sync_block", toBlock+1).Error
+	if err != nil {
+		return fmt.Errorf("更新集合同步进度失败: %v", err)
+	}
+	return nil
+}
+
+// parseTransferEvent 解析ERC-721 Transfer日志，ERC-20的Transfer（tokenId未indexed）返回nil
+func parseTransferEvent(vLog types.Log) *TransferEvent {
+	if len(vLog.Topics) != 4 || vLog.Topics[0] != transferEventTopic {
+		return nil
+	}
+	return &TransferEvent{
+		Collection: vLog.Address,
+		From:       common.BytesToAddress(vLog.Topics[1].Bytes()),
+		To:         common.BytesToAddress(vLog.Topics[2].Bytes()),
+		TokenId:    new(big.Int).SetBytes(vLog.Topics[3].Bytes()),
+	}
+}
+
+// parseTransfer1155Events 解析ERC-1155 TransferSingle/TransferBatch日志，返回事件名和按token拆分的转移
+func parseTransfer1155Events(vLog types.Log) (string, []TransferEvent, error) {
+	if len(vLog.Topics) != 4 {
+		return "", nil, fmt.Errorf("ERC-1155转移事件主题数量错误: %d", len(vLog.Topics))
+	}
+	base := TransferEvent{
+		Collection: vLog.Address,
+		From:       common.BytesToAddress(vLog.Topics[2].Bytes()),
+		To:         common.BytesToAddress(vLog.Topics[3].Bytes()),
+	}
+
+	eventName := "TransferSingle"
+	if vLog.Topics[0] == transferBatchTopic {
+		eventName = "TransferBatch"
+	}
+	values, err := erc1155ABI.Unpack(eventName, vLog.Data)
+	if err != nil {
+		return "", nil, fmt.Errorf("解析%s事件失败: %v", eventName, err)
+	}
+
+	if eventName == "TransferSingle" {
+		event := base
+		event.TokenId = values[0].(*big.Int)
+		event.Amount = values[1].(*big.Int)
+		return eventName, []TransferEvent{event}, nil
+	}
+
+	ids := values[0].([]*big.Int)
+	amounts := values[1].([]*big.Int)
+	if len(ids) != len(amounts) {
+		return "", nil, fmt.Errorf("TransferBatch事件ids与values数量不一致")
+	}
+	events := make([]TransferEvent, 0, len(ids))
+	for i := range ids {
+		event := base
+		event.TokenId = ids[i]
+		event.Amount = amounts[i]
+		events = append(events, event)
+	}
+	return eventName, events, nil
+}
+
+// handleTransfer1155Events 处理ERC-1155转移事件：更新拥有者持有数量和物品供应量、记录转移或铸造活动并校验双方挂单
+func (el *EventListener) handleTransfer1155Events(db *gorm.DB, vLog types.Log, events []TransferEvent) error {
+	for i := range events {
+		if err := el.handleTransfer1155Event(db, vLog, &events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleTransfer1155Event 处理ERC-1155单个token的转移
+func (el *EventListener) handleTransfer1155Event(db *gorm.DB, vLog types.Log, event *TransferEvent) error {
+	now := time.Now().Unix()
+	collectionHex := event.Collection.Hex()
+	tokenID := event.TokenId.String()
+	fromHex := event.From.Hex()
+	toHex := event.To.Hex()
+	minted := event.From == (common.Address{})
+	burned := event.To == (common.Address{})
+
+	if !event.Amount.IsInt64() {
+		logger.Warn("ERC-1155转移数量超出范围，跳过", logrus.Fields{
+			"collection": collectionHex,
+			"token_id":   tokenID,
+			"amount":     event.Amount.String(),
+			"tx_hash":    vLog.TxHash.Hex(),
+		})
+		return nil
+	}
+	amount := event.Amount.Int64()
+
+	logger.Debug("处理ERC-1155转移事件", logrus.Fields{
+		"collection": collectionHex,
+		"token_id":   tokenID,
+		"from":       fromHex,
+		"to":         toHex,
+		"amount":     amount,
+		"tx_hash":    vLog.TxHash.Hex(),
+	})
+
+	var item models.Item
+	err := db.Where("collection_address = ? AND token_id = ?", collectionHex, tokenID).First(&item).Error
+	switch err {
+	case nil:
+		updates := map[string]interface{}{
+			"update_time": now,
+		}
+		if minted {
+			updates["supply"] = gorm.Expr("supply + ?", amount)
+		} else if burned {
+			updates["supply"] = gorm.Expr("GREATEST(supply - ?, 0)", amount)
+		}
+		if err := db.Model(&item).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新物品供应量失败: %v", err)
+		}
+	case gorm.ErrRecordNotFound:
+		// ERC-1155物品由多个拥有者按数量持有，拥有者记录在持有数量表
+		item = models.Item{
+			ChainID:           models.ChainIDEthereum,
+			TokenID:           tokenID,
+			Name:              fmt.Sprintf("NFT #%s", tokenID),
+			CollectionAddress: &collectionHex,
+			Creator:           toHex,
+			Supply:            amount,
+			BlockNumber:       int64(vLog.BlockNumber),
+			CreateTime:        &now,
+			UpdateTime:        &now,
+		}
+		if !minted {
+			item.Creator = fromHex
+		}
+		if err := db.Create(&item).Error; err != nil {
+			return fmt.Errorf("创建物品记录失败: %v", err)
+		}
+	default:
+		return fmt.Errorf("查询物品失败: %v", err)
+	}
+
+	if !minted {
+		if err := AdjustItemBalance(db, collectionHex, tokenID, fromHex, -amount); err != nil {
+			return err
+		}
+	}
+	if !burned {
+		if err := AdjustItemBalance(db, collectionHex, tokenID, toHex, amount); err != nil {
+			return err
+		}
+	}
+
+	activityType := models.ActivityTypeTransfer
+	if minted {
+		activityType = models.ActivityTypeMint
+	}
+	txHashHex := vLog.TxHash.Hex()
+	blockHashHex := vLog.BlockHash.Hex()
+	logIndex := vLog.Index
+	activity := &models.Activity{
+		ActivityType:      activityType,
+		Maker:             &fromHex,
+		Taker:             &toHex,
+		CollectionAddress: &collectionHex,
+		TokenID:           &tokenID,
+		Amount:            amount,
+		TxHash:            &txHashHex,
+		LogIndex:          &logIndex,
+		BlockHash:         &blockHashHex,
+		BlockNumber:       int64(vLog.BlockNumber),
+		EventTime:         &now,
+		CreateTime:        &now,
+		UpdateTime:        &now,
+	}
+	if err := createActivity(db, activity); err != nil {
+		return fmt.Errorf("创建转移活动记录失败: %v", err)
+	}
+
+	if !minted {
+		if err := UpdateListingBalanceValidity(db, collectionHex, tokenID, fromHex); err != nil {
+			return err
+		}
+	}
+	if !burned {
+		if err := UpdateListingBalanceValidity(db, collectionHex, tokenID, toHex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleTransferEvent 处理ERC-721转移事件：更新物品拥有者、记录转移或铸造活动并校验相关挂单
+func (el *EventListener) handleTransferEvent(db *gorm.DB, vLog types.Log, event *TransferEvent) error {
+	now := time.Now().Unix()
+	collectionHex := event.Collection.Hex()
+	tokenID := event.TokenId.String()
+	fromHex := event.From.Hex()
+	toHex := event.To.Hex()
+
+	activityType := models.ActivityTypeTransfer
+	if event.From == (common.Address{}) {
+		activityType = models.ActivityTypeMint
+	}
+
+	logger.Debug("处理Transfer事件", logrus.Fields{
+		"collection": collectionHex,
+		"token_id":   tokenID,
+		"from":       fromHex,
+		"to":         toHex,
+		"tx_hash":    vLog.TxHash.Hex(),
+	})
+
+	var item models.Item
+	err := db.Where("collection_address = ? AND token_id = ?", collectionHex, tokenID).First(&item).Error
+	switch err {
+	case nil:
+		if err := db.Model(&item).Updates(map[string]interface{}{
+			"owner":       toHex,
+			"update_time": now,
+		}).Error; err != nil {
+			return fmt.Errorf("更新物品拥有者失败: %v", err)
+		}
+	case gorm.ErrRecordNotFound:
+		item = models.Item{
+			ChainID:           models.ChainIDEthereum,
+			TokenID:           tokenID,
+			Name:              fmt.Sprintf("NFT #%s", tokenID),
+			Owner:             &toHex,
+			CollectionAddress: &collectionHex,
+			Creator:           toHex,
+			Supply:            1,
+			BlockNumber:       int64(vLog.BlockNumber),
+			CreateTime:        &now,
+			UpdateTime:        &now,
+		}
+		if activityType == models.ActivityTypeTransfer {
+			item.Creator = fromHex
+		}
+		if err := db.Create(&item).Error; err != nil {
+			return fmt.Errorf("创建物品记录失败: %v", err)
+		}
+	default:
+		return fmt.Errorf("查询物品失败: %v", err)
+	}
+
+	txHashHex := vLog.TxHash.Hex()
+	blockHashHex := vLog.BlockHash.Hex()
+	logIndex := vLog.Index
+	activity := &models.Activity{
+		ActivityType:      activityType,
+		Maker:             &fromHex,
+		Taker:             &toHex,
+		CollectionAddress: &collectionHex,
+		TokenID:           &tokenID,
+		TxHash:            &txHashHex,
+		LogIndex:          &logIndex,
+		BlockHash:         &blockHashHex,
+		BlockNumber:       int64(vLog.BlockNumber),
+		EventTime:         &now,
+		CreateTime:        &now,
+		UpdateTime:        &now,
+	}
+	if err := createActivity(db, activity); err != nil {
+		return fmt.Errorf("创建转移活动记录失败: %v", err)
+	}
+
+	return updateListingValidity(db, itemKey{collectionHex, tokenID}, toHex, now)
+}
+
+// updateListingValidity 按当前拥有者校验物品的有效挂单：挂单方不再持有时标记失效，重新持有时恢复
+func updateListingValidity(db *gorm.DB, key itemKey, owner string, now int64) error {
+	invalidated := db.Model(&models.Order{}).
+		Where("collection_address = ? AND token_id = ? AND order_type = ? AND order_status IN ? AND maker <> ? AND invalid = ?",
+			key.CollectionAddress, key.TokenID, models.OrderTypeListing,
+			[]models.OrderStatus{models.OrderStatusActive, models.OrderStatusPending}, owner, false).
+		Updates(map[string]interface{}{
+			"invalid":        true,
+			"invalid_reason": models.OrderInvalidReasonNotOwner,
+			"update_time":    now,
+		})
+	if invalidated.Error != nil {
+		return fmt.Errorf("标记失效挂单失败: %v", invalidated.Error)
+	}
+
+	restored := db.Model(&models.Order{}).
+		Where("collection_address = ? AND token_id = ? AND order_type = ? AND maker = ? AND invalid = ? AND invalid_reason = ?",
+			key.CollectionAddress, key.TokenID, models.OrderTypeListing, owner, true, models.OrderInvalidReasonNotOwner).
+		Updates(map[string]interface{}{
+			"invalid":        false,
+			"invalid_reason": nil,
+			"update_time":    now,
+		})
+	if restored.Error != nil {
+		return fmt.Errorf("恢复挂单失败: %v", restored.Error)
+	}
+
+	if invalidated.RowsAffected > 0 || restored.RowsAffected > 0 {
+		logger.Info("物品拥有者变化，已更新挂单有效性", logrus.Fields{
+			"collection":  key.CollectionAddress,
+			"token_id":    key.TokenID,
+			"owner":       owner,
+			"invalidated": invalidated.RowsAffected,
+			"restored":    restored.RowsAffected,
+		})
+		return refreshItemListPrice(db, key)
+	}
+	return nil
+}
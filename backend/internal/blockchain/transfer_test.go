@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// newTransfer1155Log 构造ERC-1155转移日志，data按事件的非indexed参数编码
+func newTransfer1155Log(t *testing.T, eventName string, from, to common.Address, data ...interface{}) types.Log {
+	t.Helper()
+	event := erc1155ABI.Events[eventName]
+	encoded, err := event.Inputs.NonIndexed().Pack(data...)
+	if err != nil {
+		t.Fatalf("编码%s失败: %v", eventName, err)
+	}
+	return types.Log{
+		Address: common.HexToAddress("0x00000000000000000000000000000000000000c1"),
+		Topics: []common.Hash{
+			event.ID,
+			common.BytesToHash(common.HexToAddress("0x00000000000000000000000000000000000000aa").Bytes()),
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		},
+		Data: encoded,
+	}
+}
+
+func TestParseTransferSingle(t *testing.T) {
+	from := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	to := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	vLog := newTransfer1155Log(t, "TransferSingle", from, to, big.NewInt(7), big.NewInt(3))
+
+	name, events, err := parseTransfer1155Events(vLog)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if name != "TransferSingle" || len(events) != 1 {
+		t.Fatalf("事件 = %s %d条，期望 TransferSingle 1条", name, len(events))
+	}
+	event := events[0]
+	if event.From != from || event.To != to {
+		t.Fatalf("from/to = %s/%s，期望 %s/%s", event.From.Hex(), event.To.Hex(), from.Hex(), to.Hex())
+	}
+	if event.TokenId.Int64() != 7 || event.Amount.Int64() != 3 {
+		t.Fatalf("token/amount = %s/%s，期望 7/3", event.TokenId, event.Amount)
+	}
+	if event.Collection != vLog.Address {
+		t.Fatalf("集合地址 = %s，期望 %s", event.Collection.Hex(), vLog.Address.Hex())
+	}
+}
+
+func TestParseTransferBatch(t *testing.T) {
+	to := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(5)}
+	amounts := []*big.Int{big.NewInt(10), big.NewInt(1), big.NewInt(4)}
+	vLog := newTransfer1155Log(t, "TransferBatch", common.Address{}, to, ids, amounts)
+
+	name, events, err := parseTransfer1155Events(vLog)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if name != "TransferBatch" || len(events) != len(ids) {
+		t.Fatalf("事件 = %s %d条，期望 TransferBatch %d条", name, len(events), len(ids))
+	}
+	for i, event := range events {
+		if event.TokenId.Cmp(ids[i]) != 0 || event.Amount.Cmp(amounts[i]) != 0 {
+			t.Fatalf("第%d条 token/amount = %s/%s，期望 %s/%s", i, event.TokenId, event.Amount, ids[i], amounts[i])
+		}
+		if event.From != (common.Address{}) || event.To != to {
+			t.Fatalf("第%d条 from/to错误", i)
+		}
+	}
+}
+
+func TestParseTransfer1155RejectsMissingTopics(t *testing.T) {
+	vLog := types.Log{Topics: []common.Hash{transferSingleTopic}}
+	if _, _, err := parseTransfer1155Events(vLog); err == nil {
+		t.Fatal("主题数量错误的日志应解析失败")
+	}
+}
@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// UnsignedTransaction 待用户钱包签名的交易
+type UnsignedTransaction struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Data    string `json:"data"`     // ABI编码的calldata
+	Value   string `json:"value"`    // 十进制wei字符串
+	Gas     uint64 `json:"gas"`      // eth_estimateGas估算值
+	ChainID string `json:"chain_id"` // 十进制链ID
+	Method  string `json:"method"`
+}
+
+// BuildCreateLimitSellOrderTx 构建创建限价卖单交易
+func (c *NFTMarketplaceContract) BuildCreateLimitSellOrderTx(from common.Address, nftContract, tokenID string, priceWei *big.Int, expiration int64) (*UnsignedTransaction, error) {
+	tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的Token ID: %s", tokenID)
+	}
+	return c.BuildTransaction(from, nil, "createLimitSellOrder",
+		common.HexToAddress(nftContract), tokenIDBig, priceWei, big.NewInt(expiration))
+}
+
+// BuildCreateLimitBuyOrderTx 构建创建限价买单交易，出价金额随交易发送
+func (c *NFTMarketplaceContract) BuildCreateLimitBuyOrderTx(from common.Address, nftContract, tokenID string, priceWei *big.Int, expiration int64) (*UnsignedTransaction, error) {
+	tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的Token ID: %s", tokenID)
+	}
+	return c.BuildTransaction(from, priceWei, "createLimitBuyOrder",
+		common.HexToAddress(nftContract), tokenIDBig, big.NewInt(expiration))
+}
+
+// BuildCancelOrderTx 构建取消订单交易
+func (c *NFTMarketplaceContract) BuildCancelOrderTx(from common.Address, orderID *big.Int) (*UnsignedTransaction, error) {
+	return c.BuildTransaction(from, nil, "cancelOrder", orderID)
+}
+
+// BuildExecuteOrderTx 构建执行订单交易，执行卖单时value为支付金额，执行买单时为0
+func (c *NFTMarketplaceContract) BuildExecuteOrderTx(from common.Address, orderID *big.Int, valueWei *big.Int) (*UnsignedTransaction, error) {
+	return c.BuildTransaction(from, valueWei, "executeOrder", orderID)
+}
+
+// BuildEditOrderTx 构建修改订单交易，修改买单时value为新的出价金额
+func (c *NFTMarketplaceContract) BuildEditOrderTx(from common.Address, orderID, newPriceWei *big.Int, newExpiration int64, valueWei *big.Int) (*UnsignedTransaction, error) {
+	return c.BuildTransaction(from, valueWei, "editOrder", orderID, newPriceWei, big.NewInt(newExpiration))
+}
+
+// BuildCreateMarketBuyOrderTx 构建市价买入交易，合约与价格最低的卖单成交并退还多付的ETH
+func (c *NFTMarketplaceContract) BuildCreateMarketBuyOrderTx(from common.Address, nftContract, tokenID string, valueWei *big.Int) (*UnsignedTransaction, error) {
+	tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的Token ID: %s", tokenID)
+	}
+	return c.BuildTransaction(from, valueWei, "createMarketBuyOrder", common.HexToAddress(nftContract), tokenIDBig)
+}
+
+// BuildCreateMarketSellOrderTx 构建市价卖出交易，合约与出价最高的买单成交
+func (c *NFTMarketplaceContract) BuildCreateMarketSellOrderTx(from common.Address, nftContract, tokenID string) (*UnsignedTransaction, error) {
+	tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的Token ID: %s", tokenID)
+	}
+	return c.BuildTransaction(from, nil, "createMarketSellOrder", common.HexToAddress(nftContract), tokenIDBig)
+}
+
+// BuildTransaction 编码合约调用并估算gas，返回未签名交易
+// gas估算会在节点上模拟执行，合约revert时直接返回错误，避免用户签名必然失败的交易
+func (c *NFTMarketplaceContract) BuildTransaction(from common.Address, valueWei *big.Int, method string, params ...interface{}) (*UnsignedTransaction, error) {
+	data, err := c.contractABI.Pack(method, params...)
+	if err != nil {
+		return nil, fmt.Errorf("编码合约调用失败: %v", err)
+	}
+
+	value := valueWei
+	if value == nil {
+		value = big.NewInt(0)
+	}
+
+	chainID, err := c.client.ChainID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取链ID失败: %v", err)
+	}
+
+	gas, err := c.client.EstimateGas(context.Background(), ethereum.CallMsg{
+		From:  from,
+		To:    &c.contractAddress,
+		Value: value,
+		Data:  data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("估算gas失败: %v", err)
+	}
+	gas = c.feeConfig.withGasMargin(gas)
+
+	return &UnsignedTransaction{
+		From:    from.Hex(),
+		To:      c.contractAddress.Hex(),
+		Data:    hexutil.Encode(data),
+		Value:   value.String(),
+		Gas:     gas,
+		ChainID: chainID.String(),
+		Method:  method,
+	}, nil
+}
+
+// ParseChainOrderID 解析数据库中保存的链上订单ID（0x开头的十六进制）
+func ParseChainOrderID(orderID string) (*big.Int, error) {
+	id, err := hexutil.DecodeBig(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("无效的链上订单ID: %s", orderID)
+	}
+	return id, nil
+}
@@ -0,0 +1,298 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// receiptPollInterval 交易收据轮询间隔
+	receiptPollInterval = 10 * time.Second
+	// receiptPollBatchSize 每次轮询的最大交易数
+	receiptPollBatchSize = 50
+	// receiptDropTimeout 交易发送后超过该时间仍无收据且nonce已被占用时标记为已作废
+	receiptDropTimeout = 30 * time.Minute
+)
+
+// TransactionTracker 记录后端发送的交易并轮询收据更新其状态
+type TransactionTracker struct {
+	db        *gorm.DB
+	contract  *NFTMarketplaceContract
+	stopChan  chan struct{}
+	isRunning bool
+}
+
+// NewTransactionTracker 创建交易跟踪器
+func NewTransactionTracker(db *gorm.DB, contract *NFTMarketplaceContract) *TransactionTracker {
+	return &TransactionTracker{
+		db:       db,
+		contract: contract,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Record 保存已发送的交易，orderID为空表示交易发送时尚无链上订单ID
+func (t *TransactionTracker) Record(tx *types.Transaction, from common.Address, method string, orderID *big.Int) error {
+	record := newTransactionRecord(tx, from, method)
+	if orderID != nil {
+		id := fmt.Sprintf("0x%x", orderID)
+		record.OrderID = &id
+	}
+
+	if err := t.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record).Error; err != nil {
+		return fmt.Errorf("保存交易记录失败: %v", err)
+	}
+	return nil
+}
+
+// RecordReplacement 保存同nonce的替换交易，并把原交易标记为已替换
+func (t *TransactionTracker) RecordReplacement(replacement *types.Transaction, original *models.Transaction, method string) (*models.Transaction, error) {
+	record := newTransactionRecord(replacement, common.HexToAddress(original.FromAddress), method)
+	record.OrderID = original.OrderID
+	record.Replaces = &original.TxHash
+
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("保存替换交易记录失败: %v", err)
+		}
+		return tx.Model(&models.Transaction{}).
+			Where("id = ? AND status IN ?", original.ID, []models.TransactionStatus{models.TransactionStatusPending, models.TransactionStatusReplaced}).
+			Updates(map[string]interface{}{
+				"status":      models.TransactionStatusReplaced,
+				"replaced_by": record.TxHash,
+				"update_time": *record.CreateTime,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// newTransactionRecord 由已签名交易构造交易记录
+func newTransactionRecord(tx *types.Transaction, from common.Address, method string) *models.Transaction {
+	now := time.Now().Unix()
+	record := &models.Transaction{
+		TxHash:      tx.Hash().Hex(),
+		Nonce:       tx.Nonce(),
+		FromAddress: from.Hex(),
+		Method:      method,
+		Value:       models.NewWei(tx.Value()),
+		GasLimit:    tx.Gas(),
+		GasPrice:    models.NewWei(tx.GasPrice()),
+		Status:      models.TransactionStatusPending,
+		CreateTime:  &now,
+		UpdateTime:  &now,
+	}
+	if tx.ChainId() != nil {
+		record.ChainID = tx.ChainId().Uint64()
+	}
+	if tx.To() != nil {
+		record.ToAddress = tx.To().Hex()
+	}
+	return record
+}
+
+// Start 启动收据轮询
+func (t *TransactionTracker) Start() {
+	if t.isRunning {
+		return
+	}
+	t.isRunning = true
+	t.stopChan = make(chan struct{})
+	go t.run()
+}
+
+// Stop 停止收据轮询
+func (t *TransactionTracker) Stop() {
+	if !t.isRunning {
+		return
+	}
+	close(t.stopChan)
+	t.isRunning = false
+}
+
+// run 定期轮询待打包交易的收据
+func (t *TransactionTracker) run() {
+	ticker := time.NewTicker(receiptPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.pollReceipts()
+		case <-t.stopChan:
+			return
+		}
+	}
+}
+
+// pollReceipts 查询待打包交易（含已被替换、可能仍会上链的交易）的收据并更新状态
+// 按最近查询时间轮转，长期查不到收据的交易不会挤占较新交易的轮询名额
+func (t *TransactionTracker) pollReceipts() {
+	var pending []models.Transaction
+	err := t.db.Where("status IN ?", []models.TransactionStatus{models.TransactionStatusPending, models.TransactionStatusReplaced}).
+		Order("check_time ASC").
+		Order("id ASC").
+		Limit(receiptPollBatchSize).
+		Find(&pending).Error
+	if err != nil {
+		logger.Error("查询待打包交易失败", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	now := time.Now().Unix()
+	ids := make([]uint64, 0, len(pending))
+	// 按发送方缓存已确认的nonce，同一批次只查询一次
+	confirmedNonces := make(map[string]uint64)
+	for i := range pending {
+		record := &pending[i]
+		ids = append(ids, record.ID)
+
+		receipt, err := t.contract.Client().TransactionReceipt(context.Background(), common.HexToHash(record.TxHash))
+		if errors.Is(err, ethereum.NotFound) {
+			if err := t.dropIfNonceUsed(record, confirmedNonces, now); err != nil {
+				logger.Warn("检查未打包交易失败", logrus.Fields{
+					"tx_hash": record.TxHash,
+					"error":   err.Error(),
+				})
+			}
+			continue
+		}
+		if err != nil {
+			logger.Warn("获取交易收据失败", logrus.Fields{
+				"tx_hash": record.TxHash,
+				"error":   err.Error(),
+			})
+			continue
+		}
+
+		if err := t.applyReceipt(record, receipt); err != nil {
+			logger.Error("更新交易记录失败", err, logrus.Fields{
+				"tx_hash": record.TxHash,
+			})
+		}
+	}
+
+	if err := t.db.Model(&models.Transaction{}).Where("id IN ?", ids).Update("check_time", now).Error; err != nil {
+		logger.Error("更新交易查询时间失败", err)
+	}
+}
+
+// dropIfNonceUsed 交易超时仍无收据且发送方已确认的nonce超过交易nonce时，该交易不可能再上链，标记为已作废
+// nonce未被占用的交易保持待打包，由管理员加速或取消
+func (t *TransactionTracker) dropIfNonceUsed(record *models.Transaction, confirmedNonces map[string]uint64, now int64) error {
+	if record.CreateTime == nil || now-*record.CreateTime < int64(receiptDropTimeout/time.Second) {
+		return nil
+	}
+
+	confirmed, ok := confirmedNonces[record.FromAddress]
+	if !ok {
+		nonce, err := t.contract.Client().NonceAt(context.Background(), common.HexToAddress(record.FromAddress), nil)
+		if err != nil {
+			return err
+		}
+		confirmed = nonce
+		confirmedNonces[record.FromAddress] = nonce
+	}
+	if confirmed <= record.Nonce {
+		return nil
+	}
+
+	err := t.db.Model(&models.Transaction{}).
+		Where("id = ? AND status IN ?", record.ID, []models.TransactionStatus{models.TransactionStatusPending, models.TransactionStatusReplaced}).
+		Updates(map[string]interface{}{
+			"status":      models.TransactionStatusDropped,
+			"update_time": now,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	logger.Warn("交易超时未打包且nonce已被占用，标记为已作废", logrus.Fields{
+		"tx_hash": record.TxHash,
+		"method":  record.Method,
+		"nonce":   record.Nonce,
+	})
+	return nil
+}
+
+// applyReceipt 根据收据更新交易记录
+func (t *TransactionTracker) applyReceipt(record *models.Transaction, receipt *types.Receipt) error {
+	now := time.Now().Unix()
+	status := models.TransactionStatusFailed
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		status = models.TransactionStatusSuccess
+	}
+
+	updates := map[string]interface{}{
+		"status":       status,
+		"gas_used":     receipt.GasUsed,
+		"block_number": receipt.BlockNumber.Uint64(),
+		"block_hash":   receipt.BlockHash.Hex(),
+		"confirm_time": now,
+		"update_time":  now,
+	}
+	// 创建订单的交易在发送时没有链上订单ID，从收据中的OrderCreated事件补全
+	if record.OrderID == nil {
+		if orderID := t.createdOrderID(receipt); orderID != nil {
+			updates["order_id"] = fmt.Sprintf("0x%x", orderID)
+		}
+	}
+
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Transaction{}).Where("id = ?", record.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		// 同nonce只会有一笔交易上链，其余的加速或取消交易作废
+		return tx.Model(&models.Transaction{}).
+			Where("from_address = ? AND nonce = ? AND id <> ? AND status IN ?", record.FromAddress, record.Nonce, record.ID,
+				[]models.TransactionStatus{models.TransactionStatusPending, models.TransactionStatusReplaced}).
+			Updates(map[string]interface{}{
+				"status":      models.TransactionStatusDropped,
+				"update_time": now,
+			}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("交易已打包", logrus.Fields{
+		"tx_hash":      record.TxHash,
+		"method":       record.Method,
+		"status":       status,
+		"gas_used":     receipt.GasUsed,
+		"block_number": receipt.BlockNumber.String(),
+	})
+	return nil
+}
+
+// createdOrderID 从收据中解析本合约的OrderCreated事件订单ID
+func (t *TransactionTracker) createdOrderID(receipt *types.Receipt) *big.Int {
+	event, ok := t.contract.ContractABI().Events["OrderCreated"]
+	if !ok {
+		return nil
+	}
+	for _, vLog := range receipt.Logs {
+		if vLog.Address != t.contract.ContractAddress() || len(vLog.Topics) < 2 || vLog.Topics[0] != event.ID {
+			continue
+		}
+		return new(big.Int).SetBytes(vLog.Topics[1].Bytes())
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"nft-market/internal/models"
 
 	"gorm.io/driver/mysql"
@@ -8,6 +9,17 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// legacyPriceColumns 旧版本以ETH为单位存储在decimal(30)中的价格列，现以wei存储在decimal(65,0)中
+var legacyPriceColumns = []struct {
+	model   interface{}
+	columns []string
+}{
+	{&models.Collection{}, []string{"floor_price", "sale_price", "volume_total"}},
+	{&models.Item{}, []string{"list_price", "sale_price"}},
+	{&models.Order{}, []string{"price"}},
+	{&models.Activity{}, []string{"price"}},
+}
+
 // Init 初始化数据库连接
 func Init(databaseURL string) (*gorm.DB, error) {
 	db, err := gorm.Open(mysql.Open(databaseURL), &gorm.Config{
@@ -17,17 +29,86 @@ func Init(databaseURL string) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// 旧版本的价格列需先换算为wei，再按新的列类型迁移
+	if err := migrateLegacyPrices(db); err != nil {
+		return nil, err
+	}
+
 	// 自动迁移数据库表结构
 	err = db.AutoMigrate(
 		&models.Collection{},
 		&models.Item{},
+		&models.ItemAttribute{},
+		&models.ItemBalance{},
 		&models.Order{},
+		&models.OrderCriteria{},
 		&models.Activity{},
 		&models.User{},
+		&models.AuthNonce{},
+		&models.SyncState{},
+		&models.IndexedBlock{},
+		&models.ProcessedLog{},
+		&models.FailedLog{},
+		&models.ChainOutbox{},
+		&models.Transaction{},
+		&models.ExpiryRun{},
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// 活动唯一索引加入token_id以容纳ERC-1155批量转移，删除旧的(tx_hash, log_index)唯一索引
+	if db.Migrator().HasIndex(&models.Activity{}, "index_tx_log") {
+		if err := db.Migrator().DropIndex(&models.Activity{}, "index_tx_log"); err != nil {
+			return nil, err
+		}
+	}
+
 	return db, nil
 }
+
+// migrateLegacyPrices 把旧版本以ETH存储的价格列改为decimal(65,0)并乘以1e18换算为wei
+// 通过列精度识别旧版本，已迁移的列精度为65，换算只执行一次
+func migrateLegacyPrices(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, table := range legacyPriceColumns {
+		if !migrator.HasTable(table.model) {
+			continue
+		}
+		columnTypes, err := migrator.ColumnTypes(table.model)
+		if err != nil {
+			return fmt.Errorf("查询价格列类型失败: %v", err)
+		}
+
+		for _, columnType := range columnTypes {
+			if !isLegacyPriceColumn(columnType, table.columns) {
+				continue
+			}
+			column := columnType.Name()
+			if err := migrator.AlterColumn(table.model, column); err != nil {
+				return fmt.Errorf("修改价格列 %s 类型失败: %v", column, err)
+			}
+			err := db.Unscoped().Model(table.model).
+				Where(column+" IS NOT NULL").
+				UpdateColumn(column, gorm.Expr(column+" * 1000000000000000000")).Error
+			if err != nil {
+				return fmt.Errorf("价格列 %s 换算为wei失败: %v", column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// isLegacyPriceColumn 判断是否为旧版本decimal(30)的价格列
+func isLegacyPriceColumn(columnType gorm.ColumnType, columns []string) bool {
+	precision, scale, ok := columnType.DecimalSize()
+	if !ok || precision != 30 || scale != 0 {
+		return false
+	}
+	for _, column := range columns {
+		if columnType.Name() == column {
+			return true
+		}
+	}
+	return false
+}
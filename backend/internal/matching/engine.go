@@ -0,0 +1,352 @@
+package matching
+
+import (
+	"fmt"
+	"nft-market/internal/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Match 撮合结果：买卖双方价格交叉的一对订单，供执行层提交成交
+type Match struct {
+	Ask               *models.Order `json:"ask"`                // 卖单（挂单）
+	Bid               *models.Order `json:"bid"`                // 买单（出价、物品出价或集合出价）
+	Price             models.Wei    `json:"price"`              // 成交价格，取先进入订单簿的订单价格
+	CollectionAddress string        `json:"collection_address"` // 成交的集合地址
+	TokenID           string        `json:"token_id"`           // 成交的token，集合出价时为挂单的token
+	MatchTime         int64         `json:"match_time"`         // 撮合时间
+}
+
+// tokenKey 单个NFT的订单簿键
+type tokenKey struct {
+	collection string
+	tokenID    string
+}
+
+// entry 订单簿中的订单
+type entry struct {
+	order *models.Order
+	key   tokenKey
+	ask   bool
+	// collectionBid 集合出价挂在集合订单簿上，可与集合内任意token的挂单成交
+	collectionBid bool
+}
+
+// Engine 进程内撮合引擎，按价格优先、时间优先维护每个token和每个集合的订单簿
+// 新订单进入时与最优对手订单比较，价格交叉即生成撮合结果，双方订单从订单簿移除；同一maker的订单不互相成交
+type Engine struct {
+	mu sync.Mutex
+	// asks 每个token的挂单，价格从低到高
+	asks map[tokenKey][]*entry
+	// collectionAsks 每个集合的全部挂单，价格从低到高，用于集合出价撮合
+	collectionAsks map[string][]*entry
+	// bids 每个token的出价，价格从高到低
+	bids map[tokenKey][]*entry
+	// collectionBids 每个集合的集合出价，价格从高到低
+	collectionBids map[string][]*entry
+	// orders 按订单主键索引，用于撤单
+	orders map[uint64]*entry
+	// now 当前时间，测试中可替换
+	now func() int64
+}
+
+// NewEngine 创建撮合引擎
+func NewEngine() *Engine {
+	return &Engine{
+		asks:           make(map[tokenKey][]*entry),
+		collectionAsks: make(map[string][]*entry),
+		bids:           make(map[tokenKey][]*entry),
+		collectionBids: make(map[string][]*entry),
+		orders:         make(map[uint64]*entry),
+		now:            func() int64 { return time.Now().Unix() },
+	}
+}
+
+// Load 按创建时间顺序把订单放入订单簿，返回其中价格交叉的撮合结果
+// 用于启动时或重建时从数据库中的有效订单恢复订单簿
+func (e *Engine) Load(orders []models.Order) []Match {
+	sorted := make([]*models.Order, len(orders))
+	for i := range orders {
+		sorted[i] = &orders[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return earlier(sorted[i], sorted[j])
+	})
+
+	var matches []Match
+	for _, order := range sorted {
+		match, err := e.Add(order)
+		if err != nil {
+			continue
+		}
+		if match != nil {
+			matches = append(matches, *match)
+		}
+	}
+	return matches
+}
+
+// Add 放入一个订单，与最优对手订单价格交叉时返回撮合结果，否则订单进入订单簿并返回nil
+func (e *Engine) Add(order *models.Order) (*Match, error) {
+	if order.Price.Sign() <= 0 {
+		return nil, fmt.Errorf("订单价格必须大于0: %d", order.ID)
+	}
+
+	incoming := &entry{
+		order: copyOrder(order),
+		key:   tokenKey{collection: strings.ToLower(order.CollectionAddress), tokenID: order.TokenID},
+	}
+	switch order.OrderType {
+	case models.OrderTypeListing:
+		incoming.ask = true
+	case models.OrderTypeOffer, models.OrderTypeItemBid:
+	case models.OrderTypeCollectionBid:
+		incoming.collectionBid = true
+		incoming.key.tokenID = ""
+	default:
+		return nil, fmt.Errorf("不支持撮合的订单类型: %d", order.OrderType)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.orders[order.ID]; ok {
+		return nil, fmt.Errorf("订单已在订单簿中: %d", order.ID)
+	}
+
+	now := e.now()
+	if expired(incoming.order, now) {
+		return nil, fmt.Errorf("订单已过期: %d", order.ID)
+	}
+
+	if counter := e.bestCounter(incoming, now); counter != nil {
+		e.remove(counter)
+		return newMatch(incoming, counter, now), nil
+	}
+
+	e.insert(incoming)
+	return nil, nil
+}
+
+// Remove 从订单簿中移除订单（取消、成交或失效），订单不存在时返回false
+func (e *Engine) Remove(id uint64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	target, ok := e.orders[id]
+	if !ok {
+		return false
+	}
+	e.remove(target)
+	return true
+}
+
+// BestAsk 返回指定token价格最低的有效挂单
+func (e *Engine) BestAsk(collectionAddress, tokenID string) *models.Order {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := tokenKey{collection: strings.ToLower(collectionAddress), tokenID: tokenID}
+	if best := firstActive(e.asks[key], "", e.now()); best != nil {
+		return copyOrder(best.order)
+	}
+	return nil
+}
+
+// BestBid 返回指定token出价最高的有效买单，包括该token的出价和所在集合的集合出价
+func (e *Engine) BestBid(collectionAddress, tokenID string) *models.Order {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := tokenKey{collection: strings.ToLower(collectionAddress), tokenID: tokenID}
+	now := e.now()
+	best := betterBid(firstActive(e.bids[key], "", now), firstActive(e.collectionBids[key.collection], "", now))
+	if best != nil {
+		return copyOrder(best.order)
+	}
+	return nil
+}
+
+// Len 订单簿中的订单数量
+func (e *Engine) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.orders)
+}
+
+// bestCounter 查找与新订单价格交叉的最优对手订单，跳过同一maker和已过期的订单
+func (e *Engine) bestCounter(incoming *entry, now int64) *entry {
+	maker := incoming.order.Maker
+	price := incoming.order.Price
+
+	var counter *entry
+	switch {
+	case incoming.ask:
+		counter = betterBid(firstActive(e.bids[incoming.key], maker, now), firstActive(e.collectionBids[incoming.key.collection], maker, now))
+		if counter != nil && counter.order.Price.Cmp(price) < 0 {
+			return nil
+		}
+	case incoming.collectionBid:
+		counter = firstActive(e.collectionAsks[incoming.key.collection], maker, now)
+		if counter != nil && counter.order.Price.Cmp(price) > 0 {
+			return nil
+		}
+	default:
+		counter = firstActive(e.asks[incoming.key], maker, now)
+		if counter != nil && counter.order.Price.Cmp(price) > 0 {
+			return nil
+		}
+	}
+	return counter
+}
+
+// insert 按价格优先、时间优先把订单插入对应的订单簿
+func (e *Engine) insert(target *entry) {
+	e.orders[target.order.ID] = target
+	switch {
+	case target.ask:
+		e.asks[target.key] = insertSorted(e.asks[target.key], target, askBefore)
+		e.collectionAsks[target.key.collection] = insertSorted(e.collectionAsks[target.key.collection], target, askBefore)
+	case target.collectionBid:
+		e.collectionBids[target.key.collection] = insertSorted(e.collectionBids[target.key.collection], target, bidBefore)
+	default:
+		e.bids[target.key] = insertSorted(e.bids[target.key], target, bidBefore)
+	}
+}
+
+// remove 从订单簿中移除订单，空订单簿一并删除
+func (e *Engine) remove(target *entry) {
+	delete(e.orders, target.order.ID)
+	switch {
+	case target.ask:
+		e.asks[target.key] = removeEntry(e.asks[target.key], target)
+		if len(e.asks[target.key]) == 0 {
+			delete(e.asks, target.key)
+		}
+		e.collectionAsks[target.key.collection] = removeEntry(e.collectionAsks[target.key.collection], target)
+		if len(e.collectionAsks[target.key.collection]) == 0 {
+			delete(e.collectionAsks, target.key.collection)
+		}
+	case target.collectionBid:
+		e.collectionBids[target.key.collection] = removeEntry(e.collectionBids[target.key.collection], target)
+		if len(e.collectionBids[target.key.collection]) == 0 {
+			delete(e.collectionBids, target.key.collection)
+		}
+	default:
+		e.bids[target.key] = removeEntry(e.bids[target.key], target)
+		if len(e.bids[target.key]) == 0 {
+			delete(e.bids, target.key)
+		}
+	}
+}
+
+// newMatch 生成撮合结果，成交价格取订单簿中对手订单（先到者）的价格
+func newMatch(incoming, resting *entry, now int64) *Match {
+	ask, bid := incoming, resting
+	if !incoming.ask {
+		ask, bid = resting, incoming
+	}
+	return &Match{
+		Ask:               ask.order,
+		Bid:               bid.order,
+		Price:             models.NewWei(resting.order.Price.BigInt()),
+		CollectionAddress: ask.order.CollectionAddress,
+		TokenID:           ask.order.TokenID,
+		MatchTime:         now,
+	}
+}
+
+// firstActive 返回订单簿中第一个未过期且不属于指定maker的订单
+func firstActive(book []*entry, excludeMaker string, now int64) *entry {
+	for _, candidate := range book {
+		if expired(candidate.order, now) {
+			continue
+		}
+		if excludeMaker != "" && strings.EqualFold(candidate.order.Maker, excludeMaker) {
+			continue
+		}
+		return candidate
+	}
+	return nil
+}
+
+// betterBid 按价格优先、时间优先返回两个买单中更优的一个
+func betterBid(a, b *entry) *entry {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case bidBefore(b, a):
+		return b
+	default:
+		return a
+	}
+}
+
+// askBefore 挂单排序：价格低者优先，同价先到者优先
+func askBefore(a, b *entry) bool {
+	if cmp := a.order.Price.Cmp(b.order.Price); cmp != 0 {
+		return cmp < 0
+	}
+	return earlier(a.order, b.order)
+}
+
+// bidBefore 买单排序：价格高者优先，同价先到者优先
+func bidBefore(a, b *entry) bool {
+	if cmp := a.order.Price.Cmp(b.order.Price); cmp != 0 {
+		return cmp > 0
+	}
+	return earlier(a.order, b.order)
+}
+
+// earlier 按创建时间比较订单先后，时间相同按主键
+func earlier(a, b *models.Order) bool {
+	ta, tb := createTime(a), createTime(b)
+	if ta != tb {
+		return ta < tb
+	}
+	return a.ID < b.ID
+}
+
+// createTime 订单创建时间，未设置时视为最早
+func createTime(order *models.Order) int64 {
+	if order.CreateTime == nil {
+		return 0
+	}
+	return *order.CreateTime
+}
+
+// expired 订单是否已过期
+func expired(order *models.Order, now int64) bool {
+	return order.ExpireTime != nil && *order.ExpireTime <= now
+}
+
+// insertSorted 把订单插入有序订单簿
+func insertSorted(book []*entry, target *entry, before func(a, b *entry) bool) []*entry {
+	i := sort.Search(len(book), func(i int) bool {
+		return before(target, book[i])
+	})
+	book = append(book, nil)
+	copy(book[i+1:], book[i:])
+	book[i] = target
+	return book
+}
+
+// removeEntry 从订单簿中删除订单
+func removeEntry(book []*entry, target *entry) []*entry {
+	for i, candidate := range book {
+		if candidate == target {
+			return append(book[:i], book[i+1:]...)
+		}
+	}
+	return book
+}
+
+// copyOrder 复制订单，订单簿不与调用方共享数据
+func copyOrder(order *models.Order) *models.Order {
+	copied := *order
+	return &copied
+}
@@ -0,0 +1,290 @@
+package matching
+
+import (
+	"math/big"
+	"nft-market/internal/models"
+	"testing"
+)
+
+const (
+	testCollection = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	testNow        = int64(1_700_000_000)
+	alice          = "0x00000000000000000000000000000000000A11CE"
+	bob            = "0x0000000000000000000000000000000000000B0B"
+	carol          = "0x00000000000000000000000000000000000CA401"
+)
+
+// newTestEngine 创建时间固定的撮合引擎
+func newTestEngine() *Engine {
+	e := NewEngine()
+	e.now = func() int64 { return testNow }
+	return e
+}
+
+// newOrder 创建测试订单，created为相对testNow的秒数
+func newOrder(id uint64, orderType models.OrderType, tokenID string, price int64, maker string, created int64) *models.Order {
+	createTime := testNow + created
+	return &models.Order{
+		ID:                id,
+		OrderType:         orderType,
+		OrderStatus:       models.OrderStatusActive,
+		CollectionAddress: testCollection,
+		TokenID:           tokenID,
+		Price:             models.NewWei(big.NewInt(price)),
+		Maker:             maker,
+		CreateTime:        &createTime,
+	}
+}
+
+// mustAdd 放入订单并检查没有错误
+func mustAdd(t *testing.T, e *Engine, order *models.Order) *Match {
+	t.Helper()
+	match, err := e.Add(order)
+	if err != nil {
+		t.Fatalf("放入订单%d失败: %v", order.ID, err)
+	}
+	return match
+}
+
+// assertMatch 检查撮合结果的双方订单和成交价格
+func assertMatch(t *testing.T, match *Match, askID, bidID uint64, price int64) {
+	t.Helper()
+	if match == nil {
+		t.Fatalf("期望订单%d与%d成交，实际没有撮合", askID, bidID)
+	}
+	if match.Ask.ID != askID || match.Bid.ID != bidID {
+		t.Fatalf("期望订单%d与%d成交，实际为%d与%d", askID, bidID, match.Ask.ID, match.Bid.ID)
+	}
+	if match.Price.BigInt().Int64() != price {
+		t.Fatalf("期望成交价格%d，实际为%s", price, match.Price.String())
+	}
+}
+
+func TestCrossingOfferMatchesListingAtRestingPrice(t *testing.T) {
+	e := newTestEngine()
+
+	if match := mustAdd(t, e, newOrder(1, models.OrderTypeListing, "1", 100, alice, 0)); match != nil {
+		t.Fatalf("订单簿为空时不应撮合")
+	}
+	match := mustAdd(t, e, newOrder(2, models.OrderTypeOffer, "1", 120, bob, 1))
+	assertMatch(t, match, 1, 2, 100)
+
+	if match.TokenID != "1" || match.MatchTime != testNow {
+		t.Fatalf("撮合结果token或时间错误: %+v", match)
+	}
+	if e.Len() != 0 {
+		t.Fatalf("成交后订单簿应为空，实际剩余%d个订单", e.Len())
+	}
+}
+
+func TestCrossingListingMatchesBidAtRestingPrice(t *testing.T) {
+	e := newTestEngine()
+
+	mustAdd(t, e, newOrder(1, models.OrderTypeOffer, "1", 150, bob, 0))
+	match := mustAdd(t, e, newOrder(2, models.OrderTypeListing, "1", 100, alice, 1))
+	assertMatch(t, match, 2, 1, 150)
+}
+
+func TestNonCrossingOrdersRestInBook(t *testing.T) {
+	e := newTestEngine()
+
+	mustAdd(t, e, newOrder(1, models.OrderTypeListing, "1", 100, alice, 0))
+	if match := mustAdd(t, e, newOrder(2, models.OrderTypeOffer, "1", 99, bob, 1)); match != nil {
+		t.Fatalf("价格未交叉不应撮合: %+v", match)
+	}
+	// 其他token的出价不与该token的挂单撮合
+	if match := mustAdd(t, e, newOrder(3, models.OrderTypeOffer, "2", 500, bob, 2)); match != nil {
+		t.Fatalf("不同token不应撮合: %+v", match)
+	}
+
+	if e.Len() != 3 {
+		t.Fatalf("期望订单簿有3个订单，实际%d", e.Len())
+	}
+	if ask := e.BestAsk(testCollection, "1"); ask == nil || ask.ID != 1 {
+		t.Fatalf("最优挂单错误: %+v", ask)
+	}
+	if bid := e.BestBid(testCollection, "1"); bid == nil || bid.ID != 2 {
+		t.Fatalf("最优出价错误: %+v", bid)
+	}
+}
+
+func TestPricePriority(t *testing.T) {
+	e := newTestEngine()
+
+	mustAdd(t, e, newOrder(1, models.OrderTypeOffer, "1", 100, bob, 0))
+	mustAdd(t, e, newOrder(2, models.OrderTypeItemBid, "1", 200, carol, 1))
+	mustAdd(t, e, newOrder(3, models.OrderTypeOffer, "1", 150, bob, 2))
+
+	match := mustAdd(t, e, newOrder(4, models.OrderTypeListing, "1", 50, alice, 3))
+	assertMatch(t, match, 4, 2, 200)
+
+	if bid := e.BestBid(testCollection, "1"); bid == nil || bid.ID != 3 {
+		t.Fatalf("成交后最优出价应为订单3: %+v", bid)
+	}
+}
+
+func TestTimePriorityAtSamePrice(t *testing.T) {
+	e := newTestEngine()
+
+	// 后放入但创建时间更早的订单优先
+	mustAdd(t, e, newOrder(1, models.OrderTypeListing, "1", 100, alice, 10))
+	mustAdd(t, e, newOrder(2, models.OrderTypeListing, "1", 100, carol, 5))
+
+	match := mustAdd(t, e, newOrder(3, models.OrderTypeOffer, "1", 100, bob, 20))
+	assertMatch(t, match, 2, 3, 100)
+
+	// 创建时间相同按主键
+	mustAdd(t, e, newOrder(5, models.OrderTypeListing, "1", 100, carol, 10))
+	match = mustAdd(t, e, newOrder(6, models.OrderTypeOffer, "1", 100, bob, 30))
+	assertMatch(t, match, 1, 6, 100)
+}
+
+func TestCollectionBidMatchesCheapestListingInCollection(t *testing.T) {
+	e := newTestEngine()
+
+	mustAdd(t, e, newOrder(1, models.OrderTypeListing, "1", 300, alice, 0))
+	mustAdd(t, e, newOrder(2, models.OrderTypeListing, "2", 200, carol, 1))
+	mustAdd(t, e, newOrder(3, models.OrderTypeListing, "3", 250, alice, 2))
+
+	match := mustAdd(t, e, newOrder(4, models.OrderTypeCollectionBid, "", 260, bob, 3))
+	assertMatch(t, match, 2, 4, 200)
+	if match.TokenID != "2" {
+		t.Fatalf("集合出价应与token 2成交，实际为%s", match.TokenID)
+	}
+
+	// token 2已从集合订单簿移除，下一笔集合出价与token 3成交
+	match = mustAdd(t, e, newOrder(5, models.OrderTypeCollectionBid, "", 260, bob, 4))
+	assertMatch(t, match, 3, 5, 250)
+}
+
+func TestListingPrefersBetterOfItemAndCollectionBids(t *testing.T) {
+	e := newTestEngine()
+
+	mustAdd(t, e, newOrder(1, models.OrderTypeOffer, "1", 100, bob, 0))
+	mustAdd(t, e, newOrder(2, models.OrderTypeCollectionBid, "", 120, carol, 1))
+
+	if bid := e.BestBid(testCollection, "1"); bid == nil || bid.ID != 2 {
+		t.Fatalf("最优买单应为集合出价: %+v", bid)
+	}
+
+	match := mustAdd(t, e, newOrder(3, models.OrderTypeListing, "1", 90, alice, 2))
+	assertMatch(t, match, 3, 2, 120)
+
+	match = mustAdd(t, e, newOrder(4, models.OrderTypeListing, "1", 90, alice, 3))
+	assertMatch(t, match, 4, 1, 100)
+}
+
+func TestSelfMatchIsSkipped(t *testing.T) {
+	e := newTestEngine()
+
+	mustAdd(t, e, newOrder(1, models.OrderTypeListing, "1", 100, alice, 0))
+	mustAdd(t, e, newOrder(2, models.OrderTypeListing, "1", 110, carol, 1))
+
+	// 地址大小写不同也视为同一maker
+	match := mustAdd(t, e, newOrder(3, models.OrderTypeOffer, "1", 120, "0x00000000000000000000000000000000000a11ce", 2))
+	assertMatch(t, match, 2, 3, 110)
+
+	if ask := e.BestAsk(testCollection, "1"); ask == nil || ask.ID != 1 {
+		t.Fatalf("自己的挂单应保留在订单簿中: %+v", ask)
+	}
+}
+
+func TestExpiredOrdersAreSkipped(t *testing.T) {
+	e := newTestEngine()
+
+	expired := newOrder(1, models.OrderTypeListing, "1", 100, alice, -100)
+	expireTime := testNow
+	expired.ExpireTime = &expireTime
+	mustAdd(t, e, newOrder(2, models.OrderTypeListing, "1", 110, carol, 0))
+
+	if _, err := e.Add(expired); err == nil {
+		t.Fatalf("已过期订单不应进入订单簿")
+	}
+
+	// 进入订单簿后才过期的订单同样跳过
+	later := testNow + 50
+	resting := newOrder(3, models.OrderTypeListing, "1", 90, alice, 1)
+	resting.ExpireTime = &later
+	mustAdd(t, e, resting)
+	e.now = func() int64 { return later }
+
+	match := mustAdd(t, e, newOrder(4, models.OrderTypeOffer, "1", 120, bob, 2))
+	assertMatch(t, match, 2, 4, 110)
+}
+
+func TestRemovedOrdersAreNotMatched(t *testing.T) {
+	e := newTestEngine()
+
+	mustAdd(t, e, newOrder(1, models.OrderTypeListing, "1", 100, alice, 0))
+	mustAdd(t, e, newOrder(2, models.OrderTypeCollectionBid, "", 50, bob, 1))
+
+	if !e.Remove(1) {
+		t.Fatalf("移除订单1失败")
+	}
+	if e.Remove(1) {
+		t.Fatalf("重复移除应返回false")
+	}
+	if match := mustAdd(t, e, newOrder(3, models.OrderTypeOffer, "1", 150, bob, 2)); match != nil {
+		t.Fatalf("已移除的挂单不应成交: %+v", match)
+	}
+
+	if !e.Remove(2) {
+		t.Fatalf("移除集合出价失败")
+	}
+	if match := mustAdd(t, e, newOrder(4, models.OrderTypeListing, "2", 10, alice, 3)); match != nil {
+		t.Fatalf("已移除的集合出价不应成交: %+v", match)
+	}
+}
+
+func TestLoadReplaysOrdersInTimeOrder(t *testing.T) {
+	e := newTestEngine()
+
+	orders := []models.Order{
+		*newOrder(4, models.OrderTypeOffer, "1", 130, carol, 3),
+		*newOrder(1, models.OrderTypeListing, "1", 100, alice, 0),
+		*newOrder(3, models.OrderTypeOffer, "1", 90, bob, 2),
+		*newOrder(2, models.OrderTypeOffer, "1", 120, bob, 1),
+		*newOrder(5, models.OrderTypeListing, "2", 100, alice, 4),
+	}
+
+	matches := e.Load(orders)
+	if len(matches) != 1 {
+		t.Fatalf("期望1个撮合结果，实际%d个", len(matches))
+	}
+	// 订单2先于订单4创建，与挂单1成交
+	assertMatch(t, &matches[0], 1, 2, 100)
+
+	if e.Len() != 3 {
+		t.Fatalf("期望订单簿剩余3个订单，实际%d", e.Len())
+	}
+	if bid := e.BestBid(testCollection, "1"); bid == nil || bid.ID != 4 {
+		t.Fatalf("最优出价应为订单4: %+v", bid)
+	}
+}
+
+func TestAddRejectsInvalidOrders(t *testing.T) {
+	e := newTestEngine()
+
+	if _, err := e.Add(newOrder(1, models.OrderTypeListing, "1", 0, alice, 0)); err == nil {
+		t.Fatalf("价格为0的订单应被拒绝")
+	}
+	if _, err := e.Add(newOrder(2, models.OrderType(9), "1", 100, alice, 0)); err == nil {
+		t.Fatalf("未知类型的订单应被拒绝")
+	}
+
+	mustAdd(t, e, newOrder(3, models.OrderTypeListing, "1", 100, alice, 0))
+	if _, err := e.Add(newOrder(3, models.OrderTypeListing, "1", 100, alice, 0)); err == nil {
+		t.Fatalf("重复放入的订单应被拒绝")
+	}
+}
+
+func TestResultsDoNotShareCallerOrders(t *testing.T) {
+	e := newTestEngine()
+
+	listing := newOrder(1, models.OrderTypeListing, "1", 100, alice, 0)
+	mustAdd(t, e, listing)
+	listing.Price = models.NewWei(big.NewInt(1000))
+
+	match := mustAdd(t, e, newOrder(2, models.OrderTypeOffer, "1", 100, bob, 1))
+	assertMatch(t, match, 1, 2, 100)
+}
@@ -21,6 +21,7 @@ const (
 	OrderStatusFilled    OrderStatus = 1 // 已成交
 	OrderStatusCancelled OrderStatus = 2 // 已取消
 	OrderStatusExpired   OrderStatus = 3 // 已过期
+	OrderStatusPending   OrderStatus = 4 // 待上链（已生成未签名交易，等待链上事件确认）
 )
 
 // OrderType 订单类型枚举
@@ -33,6 +34,57 @@ const (
 	OrderTypeItemBid       OrderType = 4 // 物品出价
 )
 
+// OrderInvalidReason 订单失效原因，失效订单保持原状态但不能成交，链上状态恢复后重新生效
+type OrderInvalidReason string
+
+const (
+	OrderInvalidReasonNotOwner            OrderInvalidReason = "not_owner"            // 挂单方已不再持有该NFT
+	OrderInvalidReasonNotApproved         OrderInvalidReason = "not_approved"         // 挂单方未授权市场合约转移该NFT
+	OrderInvalidReasonInsufficientBalance OrderInvalidReason = "insufficient_balance" // 出价方ETH余额不足
+)
+
+// OrderChainStatus 订单链上同步状态枚举
+type OrderChainStatus int8
+
+const (
+	OrderChainStatusNone      OrderChainStatus = 0 // 无待执行的链上操作
+	OrderChainStatusPending   OrderChainStatus = 1 // 链上操作待提交或待确认
+	OrderChainStatusConfirmed OrderChainStatus = 2 // 链上操作已确认
+	OrderChainStatusFailed    OrderChainStatus = 3 // 链上操作失败
+)
+
+// OutboxAction 链上操作类型
+type OutboxAction string
+
+const (
+	OutboxActionCreateOrder  OutboxAction = "create_order"
+	OutboxActionCancelOrder  OutboxAction = "cancel_order"
+	OutboxActionExecuteOrder OutboxAction = "execute_order"
+	OutboxActionMarkExpired  OutboxAction = "mark_order_expired"
+	OutboxActionEditOrder    OutboxAction = "edit_order"
+)
+
+// OutboxStatus 链上操作发件箱状态枚举
+type OutboxStatus int8
+
+const (
+	OutboxStatusPending   OutboxStatus = 0 // 待提交
+	OutboxStatusSubmitted OutboxStatus = 1 // 已提交，等待确认
+	OutboxStatusConfirmed OutboxStatus = 2 // 已确认
+	OutboxStatusFailed    OutboxStatus = 3 // 失败（重试耗尽或交易revert）
+)
+
+// TransactionStatus 后端发送的链上交易状态枚举
+type TransactionStatus int8
+
+const (
+	TransactionStatusPending  TransactionStatus = 0 // 已发送，等待打包
+	TransactionStatusSuccess  TransactionStatus = 1 // 执行成功
+	TransactionStatusFailed   TransactionStatus = 2 // 执行失败(revert)
+	TransactionStatusReplaced TransactionStatus = 3 // 已发送同nonce替换交易，等待其中一笔上链
+	TransactionStatusDropped  TransactionStatus = 4 // 同nonce的其他交易已上链或nonce已被占用，本交易作废
+)
+
 // ActivityType 活动类型枚举
 type ActivityType int8
 
@@ -47,6 +99,16 @@ const (
 	ActivityTypeTransfer      ActivityType = 8  // 转移
 	ActivityTypeCollectionBid ActivityType = 9  // 集合出价
 	ActivityTypeItemBid       ActivityType = 10 // 物品出价
+	ActivityTypeExpire        ActivityType = 11 // 订单过期
+)
+
+// TokenStandard NFT代币标准，通过ERC-165 supportsInterface检测
+type TokenStandard string
+
+const (
+	TokenStandardUnknown TokenStandard = ""        // 尚未检测
+	TokenStandardERC721  TokenStandard = "erc721"  // 每个token只有一个拥有者
+	TokenStandardERC1155 TokenStandard = "erc1155" // 每个token可由多个拥有者按数量持有
 )
 
 // Collection 集合模型
@@ -59,12 +121,15 @@ type Collection struct {
 	Address     string         `json:"address" gorm:"type:varchar(42);not null;uniqueIndex:index_unique_address;comment:链上合约地址"`
 	OwnerAmount int64          `json:"owner_amount" gorm:"type:bigint;default:0;not null;comment:拥有item人数"`
 	ItemAmount  int64          `json:"item_amount" gorm:"type:bigint;default:0;not null;comment:该项目NFT的发行总量"`
-	FloorPrice  *float64       `json:"floor_price" gorm:"type:decimal(30);comment:整个collection中item的最低的listing价格"`
-	SalePrice   *float64       `json:"sale_price" gorm:"type:decimal(30);comment:整个collection中bid的最高的价格"`
+	FloorPrice  *Wei           `json:"floor_price" gorm:"type:decimal(65,0);comment:整个collection中item的最低的listing价格(wei)"`
+	SalePrice   *Wei           `json:"sale_price" gorm:"type:decimal(65,0);comment:整个collection中bid的最高的价格(wei)"`
 	Description *string        `json:"description" gorm:"type:varchar(2048);comment:项目描述"`
 	Website     *string        `json:"website" gorm:"type:varchar(512);comment:项目官网地址"`
-	VolumeTotal *float64       `json:"volume_total" gorm:"type:decimal(30);comment:总交易量"`
+	VolumeTotal *Wei           `json:"volume_total" gorm:"type:decimal(65,0);comment:总交易量(wei)"`
 	ImageURI    *string        `json:"image_uri" gorm:"type:varchar(512);comment:项目封面图的链接"`
+	Standard    TokenStandard  `json:"standard" gorm:"type:varchar(16);default:'';not null;comment:代币标准(erc721,erc1155),空表示尚未检测"`
+	StartBlock  uint64         `json:"start_block" gorm:"type:bigint unsigned;default:0;not null;comment:回溯转移事件的起始区块(通常为合约部署区块),0表示使用同步起始区块"`
+	SyncBlock   *uint64        `json:"sync_block" gorm:"type:bigint unsigned;comment:下一个待同步转移事件的区块,为空表示尚未开始回溯"`
 	CreateTime  *int64         `json:"create_time" gorm:"type:bigint;comment:创建时间"`
 	UpdateTime  *int64         `json:"update_time" gorm:"type:bigint;comment:更新时间"`
 	CreatedAt   time.Time      `json:"created_at"`
@@ -82,9 +147,12 @@ type Item struct {
 	CollectionAddress *string        `json:"collection_address" gorm:"type:varchar(42);comment:合约地址"`
 	Creator           string         `json:"creator" gorm:"type:varchar(42);not null;comment:创建者"`
 	Supply            int64          `json:"supply" gorm:"type:bigint;not null;comment:item供应量"`
-	ListPrice         *float64       `json:"list_price" gorm:"type:decimal(30);comment:上架价格"`
+	ListPrice         *Wei           `json:"list_price" gorm:"type:decimal(65,0);comment:上架价格(wei)"`
 	ListTime          *int64         `json:"list_time" gorm:"type:bigint;comment:上架时间"`
-	SalePrice         *float64       `json:"sale_price" gorm:"type:decimal(30);comment:上一次成交价格"`
+	SalePrice         *Wei           `json:"sale_price" gorm:"type:decimal(65,0);comment:上一次成交价格(wei)"`
+	BlockNumber       int64          `json:"block_number" gorm:"type:bigint;default:0;not null;comment:由链上事件创建时所在区块,0表示非事件创建"`
+	MetadataTime      *int64         `json:"metadata_time" gorm:"type:bigint;index;comment:最近一次从tokenURI索引元数据的时间,为空表示尚未索引"`
+	MetadataError     *string        `json:"metadata_error" gorm:"type:varchar(1024);comment:最近一次索引元数据失败的原因"`
 	CreateTime        *int64         `json:"create_time" gorm:"type:bigint;comment:创建时间"`
 	UpdateTime        *int64         `json:"update_time" gorm:"type:bigint;comment:更新时间"`
 	CreatedAt         time.Time      `json:"created_at"`
@@ -92,44 +160,95 @@ type Item struct {
 	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// ItemAttribute 物品特征，来自NFT元数据的attributes，用于特征出价匹配
+type ItemAttribute struct {
+	ID                uint64    `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	CollectionAddress string    `json:"collection_address" gorm:"type:varchar(42);not null;index:index_item;index:index_trait;comment:合约地址"`
+	TokenID           string    `json:"token_id" gorm:"type:varchar(128);not null;index:index_item;comment:token_id"`
+	TraitType         string    `json:"trait_type" gorm:"type:varchar(128);not null;index:index_trait;comment:特征名"`
+	Value             string    `json:"value" gorm:"type:varchar(256);not null;index:index_trait;comment:特征值"`
+	CreateTime        *int64    `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	UpdateTime        *int64    `json:"update_time" gorm:"type:bigint;comment:更新时间"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ItemBalance ERC-1155物品的持有数量，按拥有者记录；ERC-721物品的拥有者记录在Item.Owner
+type ItemBalance struct {
+	ID                uint64    `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	CollectionAddress string    `json:"collection_address" gorm:"type:varchar(42);not null;uniqueIndex:index_item_owner;comment:合约地址"`
+	TokenID           string    `json:"token_id" gorm:"type:varchar(128);not null;uniqueIndex:index_item_owner;comment:token_id"`
+	Owner             string    `json:"owner" gorm:"type:varchar(42);not null;uniqueIndex:index_item_owner;index;comment:拥有者"`
+	Balance           int64     `json:"balance" gorm:"type:bigint;default:0;not null;comment:持有数量"`
+	CreateTime        *int64    `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	UpdateTime        *int64    `json:"update_time" gorm:"type:bigint;comment:更新时间"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
 // Order 订单模型
 type Order struct {
-	ID                uint64         `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
-	MarketplaceID     int8           `json:"marketplace_id" gorm:"type:tinyint;default:0;not null;comment:0.local"`
-	OrderID           string         `json:"order_id" gorm:"type:varchar(66);not null;uniqueIndex:index_hash;comment:订单hash"`
-	OrderStatus       OrderStatus    `json:"order_status" gorm:"type:tinyint;default:0;not null;comment:标记订单状态"`
-	OrderType         OrderType      `json:"order_type" gorm:"type:tinyint;not null;comment:1: listing 2:offer 3:collection bid 4:item bid"`
-	EventTime         *int64         `json:"event_time" gorm:"type:bigint;comment:订单时间"`
-	CollectionAddress string         `json:"collection_address" gorm:"type:varchar(42);not null;comment:集合地址"`
-	TokenID           string         `json:"token_id" gorm:"type:varchar(128);not null;comment:代币ID"`
-	ExpireTime        *int64         `json:"expire_time" gorm:"type:bigint;comment:过期时间"`
-	Price             float64        `json:"price" gorm:"type:decimal(30);default:0;not null;comment:价格"`
-	Maker             string         `json:"maker" gorm:"type:varchar(42);not null;comment:创建者"`
-	Taker             *string        `json:"taker" gorm:"type:varchar(42);comment:接受者"`
-	QuantityRemaining int64          `json:"quantity_remaining" gorm:"type:bigint;default:1;not null;comment:erc721: 1, erc1155: n"`
-	Size              int64          `json:"size" gorm:"type:bigint;default:1;not null;comment:数量"`
-	Salt              *int64         `json:"salt" gorm:"type:bigint;default:0;comment:随机数"`
-	CurrencyAddress   string         `json:"currency_address" gorm:"type:varchar(42);default:'0x0';not null;comment:货币地址"`
-	CreateTime        *int64         `json:"create_time" gorm:"type:bigint;comment:创建时间"`
-	UpdateTime        *int64         `json:"update_time" gorm:"type:bigint;comment:更新时间"`
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
-	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                uint64              `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	MarketplaceID     int8                `json:"marketplace_id" gorm:"type:tinyint;default:0;not null;comment:0.local"`
+	OrderID           string              `json:"order_id" gorm:"type:varchar(66);not null;uniqueIndex:index_hash;comment:订单hash"`
+	OrderStatus       OrderStatus         `json:"order_status" gorm:"type:tinyint;default:0;not null;comment:订单状态(0:活跃,1:已成交,2:已取消,3:已过期,4:待上链)"`
+	OrderType         OrderType           `json:"order_type" gorm:"type:tinyint;not null;comment:1: listing 2:offer 3:collection bid 4:item bid"`
+	EventTime         *int64              `json:"event_time" gorm:"type:bigint;comment:订单时间"`
+	CollectionAddress string              `json:"collection_address" gorm:"type:varchar(42);not null;comment:集合地址"`
+	TokenID           string              `json:"token_id" gorm:"type:varchar(128);not null;comment:代币ID"`
+	ExpireTime        *int64              `json:"expire_time" gorm:"type:bigint;comment:过期时间"`
+	Price             Wei                 `json:"price" gorm:"type:decimal(65,0);default:0;not null;comment:价格(wei)"`
+	Maker             string              `json:"maker" gorm:"type:varchar(42);not null;comment:创建者"`
+	Taker             *string             `json:"taker" gorm:"type:varchar(42);comment:接受者"`
+	QuantityRemaining int64               `json:"quantity_remaining" gorm:"type:bigint;default:1;not null;comment:erc721: 1, erc1155: n"`
+	Size              int64               `json:"size" gorm:"type:bigint;default:1;not null;comment:数量"`
+	Salt              *int64              `json:"salt" gorm:"type:bigint;default:0;comment:随机数"`
+	CurrencyAddress   string              `json:"currency_address" gorm:"type:varchar(42);default:'0x0';not null;comment:货币地址"`
+	BlockNumber       int64               `json:"block_number" gorm:"type:bigint;default:0;not null;index;comment:订单创建事件所在区块,0表示未上链"`
+	BlockHash         *string             `json:"block_hash" gorm:"type:varchar(66);comment:订单创建事件所在区块hash"`
+	TxHash            *string             `json:"tx_hash" gorm:"type:varchar(66);comment:订单创建交易hash"`
+	StatusBlockNumber int64               `json:"status_block_number" gorm:"type:bigint;default:0;not null;index;comment:最近一次状态变更事件所在区块"`
+	ChainStatus       OrderChainStatus    `json:"chain_status" gorm:"type:tinyint;default:0;not null;comment:链上同步状态(0:无,1:待确认,2:已确认,3:失败)"`
+	ChainError        *string             `json:"chain_error" gorm:"type:varchar(512);comment:最近一次链上操作失败原因"`
+	Invalid           bool                `json:"invalid" gorm:"default:false;not null;index;comment:订单是否因链上状态变化无法成交"`
+	InvalidReason     *OrderInvalidReason `json:"invalid_reason" gorm:"type:varchar(32);comment:失效原因(not_owner:挂单方已不持有NFT,not_approved:未授权市场合约,insufficient_balance:出价方余额不足)"`
+	Signature         *string             `json:"signature" gorm:"type:varchar(132);comment:maker对订单EIP-712类型化数据的签名,链下签名订单才有值"`
+	ReplacesID        *uint64             `json:"replaces_id" gorm:"index;comment:修改订单时被替换的原订单ID"`
+	ReplacedByID      *uint64             `json:"replaced_by_id" gorm:"index;comment:修改订单后替换本订单的新订单ID"`
+	Criteria          []OrderCriteria     `json:"criteria,omitempty" gorm:"foreignKey:OrderID"`
+	CreateTime        *int64              `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	UpdateTime        *int64              `json:"update_time" gorm:"type:bigint;comment:更新时间"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt      `json:"-" gorm:"index"`
+}
+
+// OrderCriteria 集合出价的特征条件，同一订单的多个条件需同时满足，没有条件的集合出价匹配集合内任意token
+type OrderCriteria struct {
+	ID         uint64    `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	OrderID    uint64    `json:"order_id" gorm:"not null;index;comment:订单主键"`
+	TraitType  string    `json:"trait_type" gorm:"type:varchar(128);not null;comment:特征名"`
+	Value      string    `json:"value" gorm:"type:varchar(256);not null;comment:特征值"`
+	CreateTime *int64    `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 // Activity 活动模型
 type Activity struct {
 	ID                uint64         `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
-	ActivityType      ActivityType   `json:"activity_type" gorm:"type:tinyint;not null;comment:(1:Buy,2:Mint,3:List,4:Cancel Listing,5:Cancel Offer,6.Make Offer,7.Sell,8.Transfer,9.Collection-bid,10.Item-bid)"`
+	ActivityType      ActivityType   `json:"activity_type" gorm:"type:tinyint;not null;comment:(1:Buy,2:Mint,3:List,4:Cancel Listing,5:Cancel Offer,6.Make Offer,7.Sell,8.Transfer,9.Collection-bid,10.Item-bid,11.Expire)"`
 	Maker             *string        `json:"maker" gorm:"type:varchar(42);comment:对于buy,sell,listing,transfer类型指的是nft流转的起始方，即卖方address。对于其他类型可以理解为发起方，如make offer谁发起的from就是谁的地址"`
 	Taker             *string        `json:"taker" gorm:"type:varchar(42);comment:目标方,和maker相对"`
 	MarketplaceID     int8           `json:"marketplace_id" gorm:"type:tinyint;default:0;not null;comment:市场ID"`
 	CollectionAddress *string        `json:"collection_address" gorm:"type:varchar(42);comment:集合地址"`
-	TokenID           *string        `json:"token_id" gorm:"type:varchar(128);comment:代币ID"`
+	TokenID           *string        `json:"token_id" gorm:"type:varchar(128);uniqueIndex:index_tx_log_token,priority:3;comment:代币ID,ERC-1155批量转移的同一日志按token区分"`
 	CurrencyAddress   string         `json:"currency_address" gorm:"type:varchar(42);default:'1';not null;comment:货币类型(1表示eth)"`
-	Price             float64        `json:"price" gorm:"type:decimal(30);default:0;not null;comment:nft 价格"`
+	Price             Wei            `json:"price" gorm:"type:decimal(65,0);default:0;not null;comment:nft 价格(wei),部分成交时为成交数量的总价"`
+	Amount            int64          `json:"amount" gorm:"type:bigint;default:1;not null;comment:转移或成交数量,erc721为1"`
 	BlockNumber       int64          `json:"block_number" gorm:"type:bigint;default:0;not null;comment:区块号"`
-	TxHash            *string        `json:"tx_hash" gorm:"type:varchar(66);comment:交易事务hash"`
+	TxHash            *string        `json:"tx_hash" gorm:"type:varchar(66);uniqueIndex:index_tx_log_token,priority:1;comment:交易事务hash"`
+	LogIndex          *uint          `json:"log_index" gorm:"uniqueIndex:index_tx_log_token,priority:2;comment:事件日志在区块中的序号,由链上事件生成的活动才有值"`
+	BlockHash         *string        `json:"block_hash" gorm:"type:varchar(66);comment:区块hash,由链上事件生成的活动才有值"`
 	EventTime         *int64         `json:"event_time" gorm:"type:bigint;comment:链上事件发生的时间"`
 	CreateTime        *int64         `json:"create_time" gorm:"type:bigint;comment:创建时间"`
 	UpdateTime        *int64         `json:"update_time" gorm:"type:bigint;comment:更新时间"`
@@ -152,6 +271,147 @@ type User struct {
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// AuthNonce 登录随机数模型（Sign-In with Ethereum）
+type AuthNonce struct {
+	ID         uint64    `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	Nonce      string    `json:"nonce" gorm:"type:varchar(64);not null;uniqueIndex:index_unique_nonce;comment:随机数"`
+	Address    string    `json:"address" gorm:"type:varchar(42);not null;index;comment:请求登录的钱包地址"`
+	ExpireTime int64     `json:"expire_time" gorm:"type:bigint;not null;comment:过期时间"`
+	Used       bool      `json:"used" gorm:"default:false;not null;comment:是否已使用"`
+	CreateTime *int64    `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SyncState 事件同步进度模型（每个合约记录最后完整处理的区块）
+type SyncState struct {
+	ID              uint64    `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	ContractAddress string    `json:"contract_address" gorm:"type:varchar(42);not null;uniqueIndex:index_unique_contract;comment:合约地址"`
+	LastSyncedBlock uint64    `json:"last_synced_block" gorm:"type:bigint unsigned;default:0;not null;comment:最后完整处理的区块号"`
+	UpdateTime      *int64    `json:"update_time" gorm:"type:bigint;comment:更新时间"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ChainOutbox 链上操作发件箱，与订单变更在同一数据库事务中写入，由后台任务提交并确认
+type ChainOutbox struct {
+	ID              uint64       `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	OrderID         uint64       `json:"order_id" gorm:"not null;index;comment:订单主键"`
+	Action          OutboxAction `json:"action" gorm:"type:varchar(32);not null;comment:链上操作类型"`
+	Value           Wei          `json:"value" gorm:"type:decimal(65,0);default:0;not null;comment:随交易发送的金额(wei)"`
+	Status          OutboxStatus `json:"status" gorm:"type:tinyint;default:0;not null;index:index_status_next;comment:状态(0:待提交,1:已提交,2:已确认,3:失败)"`
+	Attempts        int          `json:"attempts" gorm:"default:0;not null;comment:已尝试次数"`
+	NextAttemptTime int64        `json:"next_attempt_time" gorm:"type:bigint;default:0;not null;index:index_status_next;comment:下次尝试时间"`
+	TxHash          *string      `json:"tx_hash" gorm:"type:varchar(66);comment:已提交的交易hash"`
+	LastError       *string      `json:"last_error" gorm:"type:varchar(512);comment:最近一次失败原因"`
+	CreateTime      *int64       `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	UpdateTime      *int64       `json:"update_time" gorm:"type:bigint;comment:更新时间"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// ExpiryRun 订单过期任务执行记录
+type ExpiryRun struct {
+	ID           uint64    `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	Trigger      string    `json:"trigger" gorm:"type:varchar(16);not null;comment:触发方式(scheduler:定时,manual:手动)"`
+	TriggeredBy  *string   `json:"triggered_by" gorm:"type:varchar(42);comment:手动触发的管理员地址"`
+	ExpiredCount int64     `json:"expired_count" gorm:"type:bigint;default:0;not null;comment:本次标记过期的订单数"`
+	OnChainCount int64     `json:"on_chain_count" gorm:"type:bigint;default:0;not null;comment:本次提交链上markOrderExpired的订单数"`
+	PurgedCount  int64     `json:"purged_count" gorm:"type:bigint;default:0;not null;comment:本次清理的超时未上链订单数"`
+	Error        *string   `json:"error" gorm:"type:varchar(512);comment:执行失败原因"`
+	StartTime    int64     `json:"start_time" gorm:"type:bigint;not null;index;comment:开始时间"`
+	EndTime      *int64    `json:"end_time" gorm:"type:bigint;comment:结束时间"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ExpiryRunListResponse 订单过期任务执行记录列表响应
+type ExpiryRunListResponse struct {
+	Runs       []ExpiryRun `json:"runs"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	TotalPages int         `json:"total_pages"`
+}
+
+// Transaction 后端发送的链上交易记录
+type Transaction struct {
+	ID          uint64            `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	ChainID     uint64            `json:"chain_id" gorm:"type:bigint unsigned;not null;comment:链ID"`
+	TxHash      string            `json:"tx_hash" gorm:"type:varchar(66);not null;uniqueIndex:index_tx_hash;comment:交易hash"`
+	Nonce       uint64            `json:"nonce" gorm:"type:bigint unsigned;not null;comment:交易nonce"`
+	FromAddress string            `json:"from_address" gorm:"type:varchar(42);not null;index:index_from_nonce;comment:发送方地址"`
+	ToAddress   string            `json:"to_address" gorm:"type:varchar(42);not null;comment:接收方地址"`
+	Method      string            `json:"method" gorm:"type:varchar(64);not null;index;comment:合约方法名"`
+	OrderID     *string           `json:"order_id" gorm:"type:varchar(66);index;comment:关联的链上订单ID"`
+	Value       Wei               `json:"value" gorm:"type:decimal(65,0);default:0;not null;comment:发送金额(wei)"`
+	GasLimit    uint64            `json:"gas_limit" gorm:"type:bigint unsigned;comment:gas上限"`
+	GasPrice    Wei               `json:"gas_price" gorm:"type:decimal(65,0);default:0;not null;comment:gas价格(wei)"`
+	GasUsed     *uint64           `json:"gas_used" gorm:"type:bigint unsigned;comment:实际消耗gas"`
+	Status      TransactionStatus `json:"status" gorm:"type:tinyint;default:0;not null;index;comment:状态(0:待打包,1:成功,2:失败,3:已替换,4:已作废)"`
+	BlockNumber *uint64           `json:"block_number" gorm:"type:bigint unsigned;comment:所在区块号"`
+	BlockHash   *string           `json:"block_hash" gorm:"type:varchar(66);comment:所在区块hash"`
+	Replaces    *string           `json:"replaces" gorm:"type:varchar(66);comment:被本交易替换的交易hash"`
+	ReplacedBy  *string           `json:"replaced_by" gorm:"type:varchar(66);comment:替换本交易的交易hash"`
+	ConfirmTime *int64            `json:"confirm_time" gorm:"type:bigint;comment:确认时间"`
+	CheckTime   *int64            `json:"check_time" gorm:"type:bigint;index;comment:最近一次查询收据时间"`
+	CreateTime  *int64            `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	UpdateTime  *int64            `json:"update_time" gorm:"type:bigint;comment:更新时间"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// IndexedBlock 已索引区块hash（用于检测链重组）
+type IndexedBlock struct {
+	ID              uint64    `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	ContractAddress string    `json:"contract_address" gorm:"type:varchar(42);not null;uniqueIndex:index_contract_block;comment:合约地址"`
+	BlockNumber     uint64    `json:"block_number" gorm:"type:bigint unsigned;not null;uniqueIndex:index_contract_block;comment:区块号"`
+	BlockHash       string    `json:"block_hash" gorm:"type:varchar(66);not null;comment:区块hash"`
+	CreateTime      *int64    `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ProcessedLog 已处理的链上日志，保证每条日志只生效一次
+type ProcessedLog struct {
+	ID          uint64    `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	ChainID     uint64    `json:"chain_id" gorm:"type:bigint unsigned;not null;uniqueIndex:index_unique_log;comment:EVM链ID"`
+	TxHash      string    `json:"tx_hash" gorm:"type:varchar(66);not null;uniqueIndex:index_unique_log;comment:交易hash"`
+	LogIndex    uint      `json:"log_index" gorm:"not null;uniqueIndex:index_unique_log;comment:日志在区块中的序号"`
+	BlockNumber uint64    `json:"block_number" gorm:"type:bigint unsigned;not null;index;comment:区块号"`
+	BlockHash   string    `json:"block_hash" gorm:"type:varchar(66);not null;comment:区块hash"`
+	EventName   string    `json:"event_name" gorm:"type:varchar(64);not null;comment:事件名称"`
+	CreateTime  *int64    `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FailedLogStatus 处理失败日志的状态枚举
+type FailedLogStatus int8
+
+const (
+	FailedLogStatusRetrying FailedLogStatus = 0 // 随同步批次重试中
+	FailedLogStatusDead     FailedLogStatus = 1 // 重试耗尽，已跳过，等待人工处理
+	FailedLogStatusResolved FailedLogStatus = 2 // 人工重试成功
+)
+
+// FailedLog 处理失败的链上日志（死信），单条日志重试耗尽后记录并跳过，不阻塞同步进度
+type FailedLog struct {
+	ID              uint64          `json:"id" gorm:"primaryKey;autoIncrement;comment:主键"`
+	ChainID         uint64          `json:"chain_id" gorm:"type:bigint unsigned;not null;uniqueIndex:index_unique_log;comment:EVM链ID"`
+	TxHash          string          `json:"tx_hash" gorm:"type:varchar(66);not null;uniqueIndex:index_unique_log;comment:交易hash"`
+	LogIndex        uint            `json:"log_index" gorm:"not null;uniqueIndex:index_unique_log;comment:日志在区块中的序号"`
+	BlockNumber     uint64          `json:"block_number" gorm:"type:bigint unsigned;not null;index;comment:区块号"`
+	ContractAddress string          `json:"contract_address" gorm:"type:varchar(42);not null;comment:产生日志的合约地址"`
+	Topic           string          `json:"topic" gorm:"type:varchar(66);not null;comment:事件签名"`
+	RawLog          string          `json:"raw_log" gorm:"type:text;not null;comment:日志JSON，用于人工重试"`
+	Status          FailedLogStatus `json:"status" gorm:"type:tinyint;default:0;not null;index;comment:状态(0:重试中,1:已跳过,2:已解决)"`
+	Attempts        int             `json:"attempts" gorm:"default:0;not null;comment:已尝试次数"`
+	LastError       string          `json:"last_error" gorm:"type:varchar(1024);not null;comment:最近一次失败原因"`
+	CreateTime      *int64          `json:"create_time" gorm:"type:bigint;comment:创建时间"`
+	UpdateTime      *int64          `json:"update_time" gorm:"type:bigint;comment:更新时间"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
 // 请求和响应结构体
 
 // CreateCollectionRequest 创建集合请求
@@ -163,6 +423,7 @@ type CreateCollectionRequest struct {
 	Description *string `json:"description"`
 	Website     *string `json:"website"`
 	ImageURI    *string `json:"image_uri"`
+	StartBlock  uint64  `json:"start_block"` // 回溯转移事件的起始区块，通常为合约部署区块
 }
 
 // CreateItemRequest 创建物品请求
@@ -175,16 +436,63 @@ type CreateItemRequest struct {
 	Supply            int64   `json:"supply" binding:"required"`
 }
 
+// Trait 特征名和特征值，用于物品特征和特征出价条件
+type Trait struct {
+	TraitType string `json:"trait_type" binding:"required"`
+	Value     string `json:"value" binding:"required"`
+}
+
 // CreateOrderRequest 创建订单请求
 type CreateOrderRequest struct {
 	CollectionAddress string    `json:"collection_address" binding:"required"`
 	TokenID           string    `json:"token_id" binding:"required"`
 	OrderType         OrderType `json:"order_type" binding:"required"`
-	Price             float64   `json:"price" binding:"required"`
+	Price             Wei       `json:"price"` // 十进制wei字符串
 	ExpireTime        *int64    `json:"expire_time"`
 	QuantityRemaining int64     `json:"quantity_remaining"`
 	Size              int64     `json:"size"`
 	CurrencyAddress   string    `json:"currency_address"`
+	Criteria          []Trait   `json:"criteria" binding:"dive"` // 集合出价的特征条件，需同时满足
+}
+
+// EditOrderRequest 修改订单请求
+type EditOrderRequest struct {
+	Price      Wei   `json:"price"` // 十进制wei字符串
+	ExpireTime int64 `json:"expire_time" binding:"required"`
+}
+
+// AcceptOfferRequest 接受出价请求
+type AcceptOfferRequest struct {
+	TokenID  string `json:"token_id"` // 接受集合出价时卖方选择的token，出价和物品出价可省略
+	Quantity int64  `json:"quantity"` // ERC-1155出售数量，省略时成交出价的全部剩余数量
+}
+
+// SignedOrderRequest 链下签名订单请求，签名为maker对订单EIP-712类型化数据的签名
+type SignedOrderRequest struct {
+	CollectionAddress string    `json:"collection_address" binding:"required"`
+	TokenID           string    `json:"token_id" binding:"required"`
+	OrderType         OrderType `json:"order_type" binding:"required"`
+	Price             Wei       `json:"price"` // 十进制wei字符串
+	ExpireTime        int64     `json:"expire_time" binding:"required"`
+	Salt              int64     `json:"salt" binding:"required"` // maker选择的随机数，区分内容相同的订单
+	Signature         string    `json:"signature"`               // 获取待签名数据时为空
+}
+
+// MarketOrderRequest 市价买入/卖出请求
+type MarketOrderRequest struct {
+	CollectionAddress string `json:"collection_address" binding:"required"`
+	TokenID           string `json:"token_id" binding:"required"`
+	LimitPrice        Wei    `json:"limit_price"` // 买入时为可接受的最高价格，卖出时为可接受的最低价格，为空表示不限制
+}
+
+// MarketQuote 市价单报价，成交价格以合约选出的最优对手订单为准
+type MarketQuote struct {
+	Side         string `json:"side"`           // buy:买入 sell:卖出
+	ChainOrderID string `json:"chain_order_id"` // 合约选出的最优对手订单ID
+	Price        Wei    `json:"price"`          // 最优对手订单的链上价格(wei)
+	Order        *Order `json:"order"`          // 最优对手订单在数据库中的记录，尚未同步时为空
+	BestDBOrder  *Order `json:"best_db_order"`  // 数据库中的最优对手订单
+	InSync       bool   `json:"in_sync"`        // 数据库与链上的最优订单是否一致
 }
 
 // CreateActivityRequest 创建活动请求
@@ -194,12 +502,33 @@ type CreateActivityRequest struct {
 	Taker             *string      `json:"taker"`
 	CollectionAddress *string      `json:"collection_address"`
 	TokenID           *string      `json:"token_id"`
-	Price             float64      `json:"price" binding:"required"`
+	Price             Wei          `json:"price"` // 十进制wei字符串
 	BlockNumber       int64        `json:"block_number" binding:"required"`
 	TxHash            *string      `json:"tx_hash"`
 	EventTime         *int64       `json:"event_time"`
 }
 
+// LoginRequest SIWE登录请求
+type LoginRequest struct {
+	Message   string `json:"message" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+// NonceResponse 登录随机数响应
+type NonceResponse struct {
+	Nonce     string `json:"nonce"`
+	Address   string `json:"address"`
+	Domain    string `json:"domain"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// LoginResponse 登录响应
+type LoginResponse struct {
+	Token     string `json:"token"`
+	Address   string `json:"address"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
 // CollectionResponse 集合响应
 type CollectionResponse struct {
 	Collection *Collection `json:"collection"`
@@ -256,6 +585,24 @@ type ActivityListResponse struct {
 	TotalPages int        `json:"total_pages"`
 }
 
+// TransactionListResponse 链上交易列表响应
+type TransactionListResponse struct {
+	Transactions []Transaction `json:"transactions"`
+	Total        int64         `json:"total"`
+	Page         int           `json:"page"`
+	PageSize     int           `json:"page_size"`
+	TotalPages   int           `json:"total_pages"`
+}
+
+// FailedLogListResponse 处理失败日志列表响应
+type FailedLogListResponse struct {
+	FailedLogs []FailedLog `json:"failed_logs"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	TotalPages int         `json:"total_pages"`
+}
+
 // ErrorResponse 错误响应
 type ErrorResponse struct {
 	Error   string `json:"error"`
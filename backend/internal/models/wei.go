@@ -0,0 +1,222 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// weiPerETH 1 ETH = 10^18 wei
+var weiPerETH = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// Wei 以wei为单位的精确金额
+// 数据库中存为decimal(65,0)，JSON中序列化为十进制字符串，避免float64精度丢失和溢出
+type Wei struct {
+	value *big.Int
+}
+
+// NewWei 由big.Int创建Wei（会复制入参）
+func NewWei(v *big.Int) Wei {
+	if v == nil {
+		return Wei{}
+	}
+	return Wei{value: new(big.Int).Set(v)}
+}
+
+// NewWeiPtr 由big.Int创建Wei指针，nil入参返回nil
+func NewWeiPtr(v *big.Int) *Wei {
+	if v == nil {
+		return nil
+	}
+	w := NewWei(v)
+	return &w
+}
+
+// ParseWei 解析十进制wei字符串
+func ParseWei(s string) (Wei, error) {
+	v, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok {
+		return Wei{}, fmt.Errorf("无效的wei金额: %q", s)
+	}
+	if v.Sign() < 0 {
+		return Wei{}, fmt.Errorf("wei金额不能为负数: %q", s)
+	}
+	return Wei{value: v}, nil
+}
+
+// ParseETH 将十进制ETH字符串（如"1.25"）精确转换为Wei
+func ParseETH(s string) (Wei, error) {
+	s = strings.TrimSpace(s)
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" {
+		intPart = "0"
+	}
+	if len(fracPart) > 18 {
+		return Wei{}, fmt.Errorf("ETH金额小数位不能超过18位: %q", s)
+	}
+	fracPart += strings.Repeat("0", 18-len(fracPart))
+
+	v, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok || v.Sign() < 0 {
+		return Wei{}, fmt.Errorf("无效的ETH金额: %q", s)
+	}
+	return Wei{value: v}, nil
+}
+
+// BigInt 返回金额的big.Int副本
+func (w Wei) BigInt() *big.Int {
+	if w.value == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(w.value)
+}
+
+// Sign 返回金额符号
+func (w Wei) Sign() int {
+	if w.value == nil {
+		return 0
+	}
+	return w.value.Sign()
+}
+
+// Cmp 比较两个金额
+func (w Wei) Cmp(other Wei) int {
+	return w.BigInt().Cmp(other.BigInt())
+}
+
+// Add 返回两个金额之和
+func (w Wei) Add(other Wei) Wei {
+	return Wei{value: new(big.Int).Add(w.BigInt(), other.BigInt())}
+}
+
+// String 返回十进制wei字符串
+func (w Wei) String() string {
+	return w.BigInt().String()
+}
+
+// ETH 返回用于展示的ETH金额字符串，去掉末尾多余的0
+func (w Wei) ETH() string {
+	v := w.BigInt()
+	sign := ""
+	if v.Sign() < 0 {
+		sign = "-"
+		v.Neg(v)
+	}
+
+	intPart, fracPart := new(big.Int).QuoRem(v, weiPerETH, new(big.Int))
+	if fracPart.Sign() == 0 {
+		return sign + intPart.String()
+	}
+
+	frac := strings.TrimRight(fmt.Sprintf("%018s", fracPart.String()), "0")
+	return sign + intPart.String() + "." + frac
+}
+
+// Value 实现driver.Valuer
+func (w Wei) Value() (driver.Value, error) {
+	return w.String(), nil
+}
+
+// Scan 实现sql.Scanner
+func (w *Wei) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		w.value = nil
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		w.value = big.NewInt(v)
+		return nil
+	default:
+		return fmt.Errorf("无法将%T扫描为Wei", src)
+	}
+
+	// decimal列可能带有小数部分（如"100.0"），只保留整数部分
+	if intPart, fracPart, found := strings.Cut(s, "."); found {
+		if strings.Trim(fracPart, "0") != "" {
+			return fmt.Errorf("wei金额不能包含小数: %q", s)
+		}
+		s = intPart
+	}
+
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("无效的wei金额: %q", s)
+	}
+	w.value = v
+	return nil
+}
+
+// MarshalJSON 序列化为十进制字符串
+func (w Wei) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.String())
+}
+
+// UnmarshalJSON 支持十进制字符串或整数
+func (w *Wei) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		w.value = nil
+		return nil
+	}
+	parsed, err := ParseWei(s)
+	if err != nil {
+		return err
+	}
+	*w = parsed
+	return nil
+}
+
+// formatETHPtr 格式化可空金额
+func formatETHPtr(w *Wei) *string {
+	if w == nil {
+		return nil
+	}
+	s := w.ETH()
+	return &s
+}
+
+// MarshalJSON 订单序列化时附带ETH展示价格
+func (o Order) MarshalJSON() ([]byte, error) {
+	type orderAlias Order
+	return json.Marshal(struct {
+		orderAlias
+		PriceETH string `json:"price_eth"`
+	}{orderAlias(o), o.Price.ETH()})
+}
+
+// MarshalJSON 物品序列化时附带ETH展示价格
+func (i Item) MarshalJSON() ([]byte, error) {
+	type itemAlias Item
+	return json.Marshal(struct {
+		itemAlias
+		ListPriceETH *string `json:"list_price_eth"`
+		SalePriceETH *string `json:"sale_price_eth"`
+	}{itemAlias(i), formatETHPtr(i.ListPrice), formatETHPtr(i.SalePrice)})
+}
+
+// MarshalJSON 集合序列化时附带ETH展示价格
+func (c Collection) MarshalJSON() ([]byte, error) {
+	type collectionAlias Collection
+	return json.Marshal(struct {
+		collectionAlias
+		FloorPriceETH  *string `json:"floor_price_eth"`
+		SalePriceETH   *string `json:"sale_price_eth"`
+		VolumeTotalETH *string `json:"volume_total_eth"`
+	}{collectionAlias(c), formatETHPtr(c.FloorPrice), formatETHPtr(c.SalePrice), formatETHPtr(c.VolumeTotal)})
+}
+
+// MarshalJSON 活动序列化时附带ETH展示价格
+func (a Activity) MarshalJSON() ([]byte, error) {
+	type activityAlias Activity
+	return json.Marshal(struct {
+		activityAlias
+		PriceETH string `json:"price_eth"`
+	}{activityAlias(a), a.Price.ETH()})
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ErrPermissionDenied 调用者既不是资源拥有者也不是管理员
+var ErrPermissionDenied = errors.New("无权操作该资源，只有拥有者或管理员可以修改")
+
+// Operator 发起写操作的登录用户
+type Operator struct {
+	Address string
+	IsAdmin bool
+}
+
+// CanModify 判断操作者是否为资源拥有者或管理员
+func (o Operator) CanModify(owner string) bool {
+	if o.IsAdmin {
+		return true
+	}
+	return o.Address != "" && common.IsHexAddress(owner) && common.HexToAddress(owner) == common.HexToAddress(o.Address)
+}
+
+const (
+	// nonceTTL 登录随机数有效期
+	nonceTTL = 10 * time.Minute
+	// tokenTTL 登录令牌有效期
+	tokenTTL = 24 * time.Hour
+	// tokenIssuer 令牌签发者
+	tokenIssuer = "nft-market"
+)
+
+// AuthClaims JWT声明
+type AuthClaims struct {
+	Address string `json:"address"`
+	jwt.RegisteredClaims
+}
+
+// AuthConfig 认证服务配置
+type AuthConfig struct {
+	JWTSecret      string
+	Domain         string   // SIWE消息要求的domain
+	URI            string   // SIWE消息URI要求的来源，只比较scheme和host
+	ChainID        string   // SIWE消息要求的Chain ID
+	AdminAddresses []string // 管理员钱包地址
+}
+
+// AuthService 认证服务（Sign-In with Ethereum + JWT）
+type AuthService struct {
+	nonces    nonceStore
+	jwtSecret []byte
+	domain    string
+	uri       string
+	chainID   string
+	admins    map[common.Address]struct{}
+}
+
+// NewAuthService 创建认证服务
+func NewAuthService(db *gorm.DB, cfg AuthConfig) *AuthService {
+	admins := make(map[common.Address]struct{}, len(cfg.AdminAddresses))
+	for _, address := range cfg.AdminAddresses {
+		if common.IsHexAddress(address) {
+			admins[common.HexToAddress(address)] = struct{}{}
+		}
+	}
+
+	return &AuthService{
+		nonces:    &dbNonceStore{db: db},
+		jwtSecret: []byte(cfg.JWTSecret),
+		domain:    cfg.Domain,
+		uri:       cfg.URI,
+		chainID:   cfg.ChainID,
+		admins:    admins,
+	}
+}
+
+// IsAdmin 判断地址是否为管理员
+func (s *AuthService) IsAdmin(address string) bool {
+	if !common.IsHexAddress(address) {
+		return false
+	}
+	_, ok := s.admins[common.HexToAddress(address)]
+	return ok
+}
+
+// Domain 获取SIWE消息要求的domain
+func (s *AuthService) Domain() string {
+	return s.domain
+}
+
+// GenerateNonce 为指定地址生成登录随机数
+func (s *AuthService) GenerateNonce(address string) (*models.AuthNonce, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("无效的钱包地址: %s", address)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %v", err)
+	}
+
+	now := time.Now()
+	createTime := now.Unix()
+	nonce := &models.AuthNonce{
+		Nonce:      hex.EncodeToString(buf),
+		Address:    common.HexToAddress(address).Hex(),
+		ExpireTime: now.Add(nonceTTL).Unix(),
+		CreateTime: &createTime,
+	}
+
+	if err := s.nonces.Save(nonce); err != nil {
+		return nil, fmt.Errorf("保存随机数失败: %v", err)
+	}
+
+	return nonce, nil
+}
+
+// Login 校验SIWE消息和签名，成功后签发JWT
+func (s *AuthService) Login(message, signature string) (*models.LoginResponse, error) {
+	msg, err := ParseSIWEMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Domain != s.domain {
+		return nil, fmt.Errorf("SIWE消息domain不匹配: %s", msg.Domain)
+	}
+	if msg.ChainID != s.chainID {
+		return nil, fmt.Errorf("SIWE消息Chain ID不匹配: %s", msg.ChainID)
+	}
+	if err := s.validateURI(msg.URI); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := msg.ValidateTime(now); err != nil {
+		return nil, err
+	}
+
+	// 校验签名，恢复出的地址必须与消息中的地址一致
+	signer, err := RecoverPersonalSignAddress([]byte(message), signature)
+	if err != nil {
+		return nil, err
+	}
+	address := common.HexToAddress(msg.Address)
+	if signer != address {
+		return nil, fmt.Errorf("签名地址与消息地址不一致")
+	}
+
+	// 消耗随机数，防止重放
+	consumed, err := s.nonces.Consume(msg.Nonce, address.Hex(), now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("校验随机数失败: %v", err)
+	}
+	if !consumed {
+		return nil, fmt.Errorf("随机数无效、已使用或已过期")
+	}
+
+	expiresAt := now.Add(tokenTTL)
+	if msg.ExpirationTime != nil && msg.ExpirationTime.Before(expiresAt) {
+		expiresAt = *msg.ExpirationTime
+	}
+
+	token, err := s.issueToken(address.Hex(), now, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("用户登录成功", logrus.Fields{
+		"address":    address.Hex(),
+		"expires_at": expiresAt.Unix(),
+	})
+
+	return &models.LoginResponse{
+		Token:     token,
+		Address:   address.Hex(),
+		ExpiresAt: expiresAt.Unix(),
+	}, nil
+}
+
+// validateURI 校验SIWE消息URI与配置的来源一致，防止其他站点请求的签名被用于登录
+func (s *AuthService) validateURI(uri string) error {
+	expected, err := url.Parse(s.uri)
+	if err != nil {
+		return fmt.Errorf("SIWE_URI配置无效: %v", err)
+	}
+	actual, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("SIWE消息URI格式错误: %v", err)
+	}
+	if !strings.EqualFold(actual.Scheme, expected.Scheme) || !strings.EqualFold(actual.Host, expected.Host) {
+		return fmt.Errorf("SIWE消息URI不匹配: %s", uri)
+	}
+	return nil
+}
+
+// ParseToken 校验JWT并返回其中的钱包地址
+func (s *AuthService) ParseToken(tokenString string) (string, error) {
+	claims := &AuthClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(tokenIssuer))
+	if err != nil {
+		return "", fmt.Errorf("令牌无效: %v", err)
+	}
+
+	if !common.IsHexAddress(claims.Address) {
+		return "", fmt.Errorf("令牌中的地址无效")
+	}
+
+	return common.HexToAddress(claims.Address).Hex(), nil
+}
+
+// issueToken 签发JWT
+func (s *AuthService) issueToken(address string, issuedAt, expiresAt time.Time) (string, error) {
+	claims := AuthClaims{
+		Address: address,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   address,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return "", fmt.Errorf("签发令牌失败: %v", err)
+	}
+	return token, nil
+}
+
+// RecoverPersonalSignAddress 从personal_sign (EIP-191) 签名中恢复签名者地址
+func RecoverPersonalSignAddress(message []byte, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(strings.TrimSpace(signature))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("签名格式错误: %v", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("签名长度错误: %d", len(sig))
+	}
+
+	// 钱包返回的v值为27/28，Ecrecover要求0/1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubKeyBytes, err := crypto.Ecrecover(accounts.TextHash(message), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("恢复签名公钥失败: %v", err)
+	}
+
+	pubKey, err := crypto.UnmarshalPubkey(pubKeyBytes)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("解析签名公钥失败: %v", err)
+	}
+
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// nonceStore 登录随机数存储
+type nonceStore interface {
+	// Save 保存新生成的随机数
+	Save(nonce *models.AuthNonce) error
+	// Consume 把未使用且未过期的随机数标记为已使用，随机数不可用时返回false
+	Consume(nonce, address string, now int64) (bool, error)
+}
+
+// dbNonceStore 数据库中的登录随机数存储
+type dbNonceStore struct {
+	db *gorm.DB
+}
+
+func (s *dbNonceStore) Save(nonce *models.AuthNonce) error {
+	return s.db.Create(nonce).Error
+}
+
+func (s *dbNonceStore) Consume(nonce, address string, now int64) (bool, error) {
+	result := s.db.Model(&models.AuthNonce{}).
+		Where("nonce = ? AND address = ? AND used = ? AND expire_time > ?", nonce, address, false, now).
+		Update("used", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
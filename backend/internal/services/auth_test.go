@@ -0,0 +1,209 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.Init(&logger.Config{Level: "error", Format: "text", Output: "console"}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// memoryNonceStore 内存中的登录随机数存储
+type memoryNonceStore struct {
+	nonces map[string]*models.AuthNonce
+}
+
+func newMemoryNonceStore() *memoryNonceStore {
+	return &memoryNonceStore{nonces: make(map[string]*models.AuthNonce)}
+}
+
+func (s *memoryNonceStore) Save(nonce *models.AuthNonce) error {
+	s.nonces[nonce.Nonce] = nonce
+	return nil
+}
+
+func (s *memoryNonceStore) Consume(nonce, address string, now int64) (bool, error) {
+	n, ok := s.nonces[nonce]
+	if !ok || n.Address != address || n.Used || n.ExpireTime <= now {
+		return false, nil
+	}
+	n.Used = true
+	return true, nil
+}
+
+func newTestAuthService(nonces nonceStore) *AuthService {
+	return &AuthService{
+		nonces:    nonces,
+		jwtSecret: []byte("test-secret"),
+		domain:    "example.com",
+		uri:       "https://example.com",
+		chainID:   "1",
+	}
+}
+
+func siweMessage(address common.Address, uri, chainID, nonce string, issuedAt time.Time) string {
+	return fmt.Sprintf(`example.com wants you to sign in with your Ethereum account:
+%s
+
+Sign in to NFT Market
+
+URI: %s
+Version: 1
+Chain ID: %s
+Nonce: %s
+Issued At: %s`, address.Hex(), uri, chainID, nonce, issuedAt.UTC().Format(time.RFC3339))
+}
+
+// personalSign 按钱包personal_sign的格式签名，v值为27/28
+func personalSign(t *testing.T, key *ecdsa.PrivateKey, message string) string {
+	t.Helper()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig)
+}
+
+func TestParseSIWEMessage(t *testing.T) {
+	address := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	issuedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	message := siweMessage(address, "https://example.com/login", "1", "abc123", issuedAt) +
+		"\nExpiration Time: 2024-01-03T03:04:05Z\nResources:\n- https://example.com/terms"
+
+	msg, err := ParseSIWEMessage(message)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if msg.Domain != "example.com" || msg.Address != address.Hex() || msg.Statement != "Sign in to NFT Market" {
+		t.Fatalf("header = %q %q %q", msg.Domain, msg.Address, msg.Statement)
+	}
+	if msg.URI != "https://example.com/login" || msg.ChainID != "1" || msg.Nonce != "abc123" || !msg.IssuedAt.Equal(issuedAt) {
+		t.Fatalf("fields = %+v", msg)
+	}
+	if msg.ExpirationTime == nil || !msg.ExpirationTime.Equal(issuedAt.Add(24*time.Hour)) {
+		t.Fatalf("expiration = %v", msg.ExpirationTime)
+	}
+	if len(msg.Resources) != 1 || msg.Resources[0] != "https://example.com/terms" {
+		t.Fatalf("resources = %v", msg.Resources)
+	}
+
+	invalid := map[string]string{
+		"bad header":    strings.Replace(message, "wants you to sign in", "wants to sign in", 1),
+		"bad address":   strings.Replace(message, address.Hex(), "0x1234", 1),
+		"missing nonce": strings.Replace(message, "Nonce: abc123\n", "", 1),
+		"bad version":   strings.Replace(message, "Version: 1", "Version: 2", 1),
+		"unknown field": message + "\nFoo: bar",
+	}
+	for name, m := range invalid {
+		if _, err := ParseSIWEMessage(m); err == nil {
+			t.Errorf("%s: expected parse error", name)
+		}
+	}
+}
+
+func TestRecoverPersonalSignAddress(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	want := crypto.PubkeyToAddress(key.PublicKey)
+	message := "hello"
+
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	// 钱包返回27/28，部分签名库返回0/1，两种v值都应恢复出同一地址
+	for _, offset := range []byte{0, 27} {
+		s := append([]byte(nil), sig...)
+		s[crypto.RecoveryIDOffset] += offset
+		got, err := RecoverPersonalSignAddress([]byte(message), hexutil.Encode(s))
+		if err != nil {
+			t.Fatalf("v offset %d: %v", offset, err)
+		}
+		if got != want {
+			t.Fatalf("v offset %d: signer = %s, want %s", offset, got.Hex(), want.Hex())
+		}
+	}
+
+	got, err := RecoverPersonalSignAddress([]byte("other message"), personalSign(t, key, message))
+	if err == nil && got == want {
+		t.Fatal("signature over a different message recovered the signer")
+	}
+	if _, err := RecoverPersonalSignAddress([]byte(message), "0x1234"); err == nil {
+		t.Fatal("expected error for short signature")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	nonces := newMemoryNonceStore()
+	s := newTestAuthService(nonces)
+
+	newNonce := func() string {
+		n, err := s.GenerateNonce(address.Hex())
+		if err != nil {
+			t.Fatalf("generate nonce: %v", err)
+		}
+		return n.Nonce
+	}
+
+	nonce := newNonce()
+	message := siweMessage(address, "https://example.com/login", "1", nonce, time.Now())
+	signature := personalSign(t, key, message)
+
+	resp, err := s.Login(message, signature)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.Address != address.Hex() {
+		t.Fatalf("address = %s, want %s", resp.Address, address.Hex())
+	}
+	parsed, err := s.ParseToken(resp.Token)
+	if err != nil || parsed != address.Hex() {
+		t.Fatalf("parse token = %s, %v", parsed, err)
+	}
+
+	// 随机数只能使用一次
+	if _, err := s.Login(message, signature); err == nil {
+		t.Fatal("expected replayed nonce to be rejected")
+	}
+
+	other, _ := crypto.GenerateKey()
+	cases := map[string]struct {
+		message string
+		key     *ecdsa.PrivateKey
+	}{
+		"wrong chain id": {siweMessage(address, "https://example.com/login", "5", newNonce(), time.Now()), key},
+		"wrong uri":      {siweMessage(address, "https://evil.example/login", "1", newNonce(), time.Now()), key},
+		"unknown nonce":  {siweMessage(address, "https://example.com/login", "1", "deadbeef", time.Now()), key},
+		"wrong signer":   {siweMessage(address, "https://example.com/login", "1", newNonce(), time.Now()), other},
+	}
+	for name, c := range cases {
+		if _, err := s.Login(c.message, personalSign(t, c.key, c.message)); err == nil {
+			t.Errorf("%s: expected login to fail", name)
+		}
+	}
+
+	// 校验失败的登录不消耗随机数
+	for _, n := range nonces.nonces {
+		if n.Used && n.Nonce != nonce {
+			t.Errorf("nonce %s consumed by a rejected login", n.Nonce)
+		}
+	}
+}
@@ -2,17 +2,15 @@ package services
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"nft-market/internal/blockchain"
 	"strings"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
@@ -21,12 +19,12 @@ type BlockchainService struct {
 	client          *ethclient.Client
 	contractAddress common.Address
 	contractABI     abi.ABI
-	privateKey      *ecdsa.PrivateKey
-	publicKey       common.Address
+	signer          blockchain.Signer
 }
 
 // NewBlockchainService 创建新的区块链服务
-func NewBlockchainService(rpcURL, contractAddress, privateKeyHex string) (*BlockchainService, error) {
+// signer为nil时只能读取链上数据
+func NewBlockchainService(rpcURL, contractAddress string, signer blockchain.Signer) (*BlockchainService, error) {
 	// 连接到以太坊节点
 	client, err := ethclient.Dial(rpcURL)
 	if err != nil {
@@ -36,21 +34,6 @@ func NewBlockchainService(rpcURL, contractAddress, privateKeyHex string) (*Block
 	// 解析合约地址
 	contractAddr := common.HexToAddress(contractAddress)
 
-	// 解析私钥
-	var privateKey *ecdsa.PrivateKey
-	var publicKey common.Address
-	if privateKeyHex != "" {
-		// 移除0x前缀如果存在
-		if strings.HasPrefix(privateKeyHex, "0x") || strings.HasPrefix(privateKeyHex, "0X") {
-			privateKeyHex = privateKeyHex[2:]
-		}
-		privateKey, err = crypto.HexToECDSA(privateKeyHex)
-		if err != nil {
-			return nil, fmt.Errorf("解析私钥失败: %v", err)
-		}
-		publicKey = crypto.PubkeyToAddress(privateKey.PublicKey)
-	}
-
 	// 加载合约ABI
 	contractABI, err := abi.JSON(strings.NewReader(NFTMarketplaceABI))
 	if err != nil {
@@ -61,8 +44,7 @@ func NewBlockchainService(rpcURL, contractAddress, privateKeyHex string) (*Block
 		client:          client,
 		contractAddress: contractAddr,
 		contractABI:     contractABI,
-		privateKey:      privateKey,
-		publicKey:       publicKey,
+		signer:          signer,
 	}, nil
 }
 
@@ -186,14 +168,11 @@ func (bs *BlockchainService) GetNFTOrders(nftContract common.Address, tokenID *b
 
 // CreateLimitSellOrder 创建限价卖单
 func (bs *BlockchainService) CreateLimitSellOrder(nftContract common.Address, tokenID, price, expiration *big.Int) (*types.Transaction, error) {
-	if bs.privateKey == nil {
-		return nil, fmt.Errorf("私钥未设置")
+	if bs.signer == nil {
+		return nil, fmt.Errorf("签名器未设置")
 	}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(bs.privateKey, nil)
-	if err != nil {
-		return nil, err
-	}
+	from := bs.signer.Address()
 
 	input, err := bs.contractABI.Pack("createLimitSellOrder", nftContract, tokenID, price, expiration)
 	if err != nil {
@@ -206,7 +185,7 @@ func (bs *BlockchainService) CreateLimitSellOrder(nftContract common.Address, to
 	}
 
 	gasLimit, err := bs.client.EstimateGas(context.Background(), ethereum.CallMsg{
-		From: auth.From,
+		From: from,
 		To:   &bs.contractAddress,
 		Data: input,
 	})
@@ -214,7 +193,7 @@ func (bs *BlockchainService) CreateLimitSellOrder(nftContract common.Address, to
 		return nil, err
 	}
 
-	nonce, err := bs.client.PendingNonceAt(context.Background(), auth.From)
+	nonce, err := bs.client.PendingNonceAt(context.Background(), from)
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +205,7 @@ func (bs *BlockchainService) CreateLimitSellOrder(nftContract common.Address, to
 		return nil, err
 	}
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), bs.privateKey)
+	signedTx, err := bs.signer.SignTx(tx, chainID)
 	if err != nil {
 		return nil, err
 	}
@@ -241,14 +220,11 @@ func (bs *BlockchainService) CreateLimitSellOrder(nftContract common.Address, to
 
 // CreateLimitBuyOrder 创建限价买单
 func (bs *BlockchainService) CreateLimitBuyOrder(nftContract common.Address, tokenID, expiration *big.Int, value *big.Int) (*types.Transaction, error) {
-	if bs.privateKey == nil {
-		return nil, fmt.Errorf("私钥未设置")
+	if bs.signer == nil {
+		return nil, fmt.Errorf("签名器未设置")
 	}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(bs.privateKey, nil)
-	if err != nil {
-		return nil, err
-	}
+	from := bs.signer.Address()
 
 	input, err := bs.contractABI.Pack("createLimitBuyOrder", nftContract, tokenID, expiration)
 	if err != nil {
@@ -261,7 +237,7 @@ func (bs *BlockchainService) CreateLimitBuyOrder(nftContract common.Address, tok
 	}
 
 	gasLimit, err := bs.client.EstimateGas(context.Background(), ethereum.CallMsg{
-		From:  auth.From,
+		From:  from,
 		To:    &bs.contractAddress,
 		Value: value,
 		Data:  input,
@@ -270,7 +246,7 @@ func (bs *BlockchainService) CreateLimitBuyOrder(nftContract common.Address, tok
 		return nil, err
 	}
 
-	nonce, err := bs.client.PendingNonceAt(context.Background(), auth.From)
+	nonce, err := bs.client.PendingNonceAt(context.Background(), from)
 	if err != nil {
 		return nil, err
 	}
@@ -282,7 +258,7 @@ func (bs *BlockchainService) CreateLimitBuyOrder(nftContract common.Address, tok
 		return nil, err
 	}
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), bs.privateKey)
+	signedTx, err := bs.signer.SignTx(tx, chainID)
 	if err != nil {
 		return nil, err
 	}
@@ -297,14 +273,11 @@ func (bs *BlockchainService) CreateLimitBuyOrder(nftContract common.Address, tok
 
 // CancelOrder 取消订单
 func (bs *BlockchainService) CancelOrder(orderID *big.Int) (*types.Transaction, error) {
-	if bs.privateKey == nil {
-		return nil, fmt.Errorf("私钥未设置")
+	if bs.signer == nil {
+		return nil, fmt.Errorf("签名器未设置")
 	}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(bs.privateKey, nil)
-	if err != nil {
-		return nil, err
-	}
+	from := bs.signer.Address()
 
 	input, err := bs.contractABI.Pack("cancelOrder", orderID)
 	if err != nil {
@@ -317,7 +290,7 @@ func (bs *BlockchainService) CancelOrder(orderID *big.Int) (*types.Transaction,
 	}
 
 	gasLimit, err := bs.client.EstimateGas(context.Background(), ethereum.CallMsg{
-		From: auth.From,
+		From: from,
 		To:   &bs.contractAddress,
 		Data: input,
 	})
@@ -325,7 +298,7 @@ func (bs *BlockchainService) CancelOrder(orderID *big.Int) (*types.Transaction,
 		return nil, err
 	}
 
-	nonce, err := bs.client.PendingNonceAt(context.Background(), auth.From)
+	nonce, err := bs.client.PendingNonceAt(context.Background(), from)
 	if err != nil {
 		return nil, err
 	}
@@ -337,7 +310,7 @@ func (bs *BlockchainService) CancelOrder(orderID *big.Int) (*types.Transaction,
 		return nil, err
 	}
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), bs.privateKey)
+	signedTx, err := bs.signer.SignTx(tx, chainID)
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"nft-market/internal/blockchain"
@@ -9,40 +10,51 @@ import (
 	"nft-market/internal/models"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// ErrBlockchainUnavailable 未连接区块链节点或未配置合约，服务以只读模式运行
+var ErrBlockchainUnavailable = errors.New("区块链服务不可用")
+
 // EnhancedBlockchainService 增强的区块链服务
 type EnhancedBlockchainService struct {
 	*BlockchainService // 嵌入原有服务
 	contract           *blockchain.NFTMarketplaceContract
 	eventListener      *blockchain.EventListener
+	txTracker          *blockchain.TransactionTracker
 	db                 *gorm.DB
 }
 
 // NewEnhancedBlockchainService 创建增强的区块链服务
-func NewEnhancedBlockchainService(rpcURL, contractAddress, privateKey string, db *gorm.DB) (*EnhancedBlockchainService, error) {
+func NewEnhancedBlockchainService(rpcURL, contractAddress string, signer blockchain.Signer, db *gorm.DB, listenerConfig blockchain.ListenerConfig, feeConfig blockchain.FeeConfig) (*EnhancedBlockchainService, error) {
 	// 创建基础区块链服务
-	baseService, err := NewBlockchainService(rpcURL, contractAddress, privateKey)
+	baseService, err := NewBlockchainService(rpcURL, contractAddress, signer)
 	if err != nil {
 		return nil, fmt.Errorf("创建基础区块链服务失败: %v", err)
 	}
 
 	// 创建增强的合约实例
-	contract, err := blockchain.NewNFTMarketplaceContract(rpcURL, contractAddress, privateKey)
+	contract, err := blockchain.NewNFTMarketplaceContract(rpcURL, contractAddress, signer, feeConfig)
 	if err != nil {
 		return nil, fmt.Errorf("创建增强合约实例失败: %v", err)
 	}
 
+	// 记录合约发送的交易并轮询收据
+	txTracker := blockchain.NewTransactionTracker(db, contract)
+	contract.SetTransactionTracker(txTracker)
+	txTracker.Start()
+
 	// 创建事件监听器
-	eventListener := blockchain.NewEventListener(baseService.client, contractAddress, contract, db)
+	eventListener := blockchain.NewEventListener(baseService.client, contractAddress, contract, db, listenerConfig)
 
 	service := &EnhancedBlockchainService{
 		BlockchainService: baseService,
 		contract:          contract,
 		eventListener:     eventListener,
+		txTracker:         txTracker,
 		db:                db,
 	}
 
@@ -57,19 +69,54 @@ func NewEnhancedBlockchainService(rpcURL, contractAddress, privateKey string, db
 	return service, nil
 }
 
+// BlockchainCapabilities 区块链相关功能的可用性
+type BlockchainCapabilities struct {
+	ChainRead  bool `json:"chain_read"`  // 已连接节点和合约，可读取链上数据、构建用户签名的交易
+	ChainWrite bool `json:"chain_write"` // 已配置签名器，热钱包可发送交易
+	Indexer    bool `json:"indexer"`     // 事件监听器正在运行
+}
+
+// Capabilities 获取当前可用的区块链功能，服务未初始化时全部不可用
+func (s *EnhancedBlockchainService) Capabilities() BlockchainCapabilities {
+	if s == nil {
+		return BlockchainCapabilities{}
+	}
+	return BlockchainCapabilities{
+		ChainRead:  true,
+		ChainWrite: s.contract.CanSign(),
+		Indexer:    s.eventListener.IsRunning(),
+	}
+}
+
 // GetBlockchainStatus 获取区块链服务状态
 func (s *EnhancedBlockchainService) GetBlockchainStatus() map[string]interface{} {
+	capabilities := s.Capabilities()
+	if s == nil {
+		return map[string]interface{}{
+			"status":       "unavailable",
+			"capabilities": capabilities,
+			"timestamp":    time.Now().Unix(),
+		}
+	}
+
 	header, err := s.contract.Client().HeaderByNumber(context.Background(), nil)
 	if err != nil {
 		return map[string]interface{}{
-			"status": "error",
-			"error":  err.Error(),
+			"status":       "error",
+			"error":        err.Error(),
+			"capabilities": capabilities,
 		}
 	}
+	status := "running"
+	if !capabilities.ChainWrite {
+		status = "read_only"
+	}
 	return map[string]interface{}{
-		"status":        "running",
+		"status":        status,
 		"latest_block":  header.Number.String(),
 		"contract_addr": s.contract.ContractAddress().Hex(),
+		"capabilities":  capabilities,
+		"indexer":       s.eventListener.Status(header.Number.Uint64()),
 		"timestamp":     time.Now().Unix(),
 	}
 }
@@ -95,7 +142,7 @@ func (ebs *EnhancedBlockchainService) CreateOrderOnChain(order *models.Order) (*
 		"collection_address": order.CollectionAddress,
 		"token_id":           order.TokenID,
 		"order_type":         order.OrderType,
-		"price":              order.Price,
+		"price_wei":          order.Price.String(),
 	})
 
 	var tx *types.Transaction
@@ -112,7 +159,7 @@ func (ebs *EnhancedBlockchainService) CreateOrderOnChain(order *models.Order) (*
 		tx, err = ebs.contract.CreateLimitSellOrder(
 			order.CollectionAddress,
 			order.TokenID,
-			order.Price,
+			order.Price.BigInt(),
 			expiration,
 		)
 
@@ -126,7 +173,7 @@ func (ebs *EnhancedBlockchainService) CreateOrderOnChain(order *models.Order) (*
 		tx, err = ebs.contract.CreateLimitBuyOrder(
 			order.CollectionAddress,
 			order.TokenID,
-			order.Price,
+			order.Price.BigInt(),
 			expiration,
 		)
 
@@ -171,14 +218,24 @@ func (ebs *EnhancedBlockchainService) CancelOrderOnChain(orderID uint64) (*types
 	return tx, nil
 }
 
+// EditOrderOnChain 在区块链上修改订单，修改买单时value为新的出价金额
+func (ebs *EnhancedBlockchainService) EditOrderOnChain(orderID uint64, newPrice models.Wei, newExpiration int64, value models.Wei) (*types.Transaction, error) {
+	return ebs.contract.EditOrder(orderID, newPrice.BigInt(), newExpiration, value.BigInt())
+}
+
+// MarkOrderExpiredOnChain 在区块链上标记过期订单
+func (ebs *EnhancedBlockchainService) MarkOrderExpiredOnChain(orderID uint64) (*types.Transaction, error) {
+	return ebs.contract.MarkOrderExpired(orderID)
+}
+
 // ExecuteOrderOnChain 在区块链上执行订单
-func (ebs *EnhancedBlockchainService) ExecuteOrderOnChain(orderID uint64, price float64) (*types.Transaction, error) {
+func (ebs *EnhancedBlockchainService) ExecuteOrderOnChain(orderID uint64, price models.Wei) (*types.Transaction, error) {
 	logger.Info("开始在链上执行订单", logrus.Fields{
-		"order_id": orderID,
-		"price":    price,
+		"order_id":  orderID,
+		"price_wei": price.String(),
 	})
 
-	tx, err := ebs.contract.ExecuteOrder(orderID, price)
+	tx, err := ebs.contract.ExecuteOrder(orderID, price.BigInt())
 	if err != nil {
 		logger.Error("链上执行订单失败", err, logrus.Fields{
 			"order_id": orderID,
@@ -194,6 +251,61 @@ func (ebs *EnhancedBlockchainService) ExecuteOrderOnChain(orderID uint64, price
 	return tx, nil
 }
 
+// BuildCreateOrderTx 构建由用户钱包签名的创建订单交易
+func (ebs *EnhancedBlockchainService) BuildCreateOrderTx(order *models.Order, from common.Address) (*blockchain.UnsignedTransaction, error) {
+	if order.ExpireTime == nil {
+		return nil, fmt.Errorf("订单缺少过期时间")
+	}
+
+	switch order.OrderType {
+	case models.OrderTypeListing:
+		return ebs.contract.BuildCreateLimitSellOrderTx(from, order.CollectionAddress, order.TokenID, order.Price.BigInt(), *order.ExpireTime)
+	case models.OrderTypeOffer:
+		return ebs.contract.BuildCreateLimitBuyOrderTx(from, order.CollectionAddress, order.TokenID, order.Price.BigInt(), *order.ExpireTime)
+	default:
+		return nil, fmt.Errorf("不支持的订单类型: %v", order.OrderType)
+	}
+}
+
+// BuildCancelOrderTx 构建由用户钱包签名的取消订单交易
+func (ebs *EnhancedBlockchainService) BuildCancelOrderTx(order *models.Order, from common.Address) (*blockchain.UnsignedTransaction, error) {
+	chainOrderID, err := blockchain.ParseChainOrderID(order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	return ebs.contract.BuildCancelOrderTx(from, chainOrderID)
+}
+
+// BuildExecuteOrderTx 构建由用户钱包签名的执行订单交易
+// 买入上架订单需支付订单价格，接受出价（卖出）不需要发送ETH
+func (ebs *EnhancedBlockchainService) BuildExecuteOrderTx(order *models.Order, from common.Address) (*blockchain.UnsignedTransaction, error) {
+	chainOrderID, err := blockchain.ParseChainOrderID(order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	value := big.NewInt(0)
+	if order.OrderType == models.OrderTypeListing {
+		value = order.Price.BigInt()
+	}
+	return ebs.contract.BuildExecuteOrderTx(from, chainOrderID, value)
+}
+
+// BuildEditOrderTx 构建由用户钱包签名的修改订单交易
+// 合约会取消原订单并创建新订单，修改出价时需重新发送新的出价金额（原出价由合约退回）
+func (ebs *EnhancedBlockchainService) BuildEditOrderTx(order *models.Order, from common.Address, newPrice models.Wei, newExpiration int64) (*blockchain.UnsignedTransaction, error) {
+	chainOrderID, err := blockchain.ParseChainOrderID(order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	value := big.NewInt(0)
+	if order.OrderType == models.OrderTypeOffer {
+		value = newPrice.BigInt()
+	}
+	return ebs.contract.BuildEditOrderTx(from, chainOrderID, newPrice.BigInt(), newExpiration, value)
+}
+
 // GetOrderFromChainEnhanced 从链上获取订单信息（增强版）
 func (ebs *EnhancedBlockchainService) GetOrderFromChainEnhanced(orderID uint64) (map[string]interface{}, error) {
 	logger.Debug("从链上获取订单信息", logrus.Fields{
@@ -237,6 +349,11 @@ func (ebs *EnhancedBlockchainService) WaitForTransactionConfirmation(tx *types.T
 	return ebs.contract.WaitForTransaction(tx, timeout)
 }
 
+// GetTransactionReceipt 获取交易收据，交易未上链时返回ethereum.NotFound
+func (ebs *EnhancedBlockchainService) GetTransactionReceipt(txHash common.Hash) (*types.Receipt, error) {
+	return ebs.contract.Client().TransactionReceipt(context.Background(), txHash)
+}
+
 // GetOrderCounter 获取订单计数器
 func (ebs *EnhancedBlockchainService) GetOrderCounter() (*big.Int, error) {
 	return ebs.contract.GetOrderCounter()
@@ -261,7 +378,7 @@ func (ebs *EnhancedBlockchainService) SyncOrderFromChain(orderID uint64) (*model
 	now := time.Now().Unix()
 	maker := chainOrder["maker"].(string)
 	tokenID := chainOrder["tokenId"].(*big.Int).String()
-	price := float64(chainOrder["price"].(*big.Int).Int64()) / 1e18 // 转换为ETH单位
+	price := models.NewWei(chainOrder["price"].(*big.Int))
 	collectionAddress := chainOrder["nftContract"].(string)
 
 	order := &models.Order{
@@ -354,11 +471,90 @@ func (ebs *EnhancedBlockchainService) SyncAllOrdersFromChain() error {
 	return nil
 }
 
+// ListTransactions 分页查询后端发送的链上交易
+func (ebs *EnhancedBlockchainService) ListTransactions(page, pageSize int, status *models.TransactionStatus, method, orderID string) (*models.TransactionListResponse, error) {
+	var transactions []models.Transaction
+	var total int64
+
+	query := ebs.db.Model(&models.Transaction{})
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if method != "" {
+		query = query.Where("method = ?", method)
+	}
+	if orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("查询交易总数失败: %v", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("id DESC").Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("查询交易列表失败: %v", err)
+	}
+
+	return &models.TransactionListResponse{
+		Transactions: transactions,
+		Total:        total,
+		Page:         page,
+		PageSize:     pageSize,
+		TotalPages:   int((total + int64(pageSize) - 1) / int64(pageSize)),
+	}, nil
+}
+
+// GetTransactionByHash 根据交易hash获取交易记录
+func (ebs *EnhancedBlockchainService) GetTransactionByHash(txHash string) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := ebs.db.Where("tx_hash = ?", common.HexToHash(txHash).Hex()).First(&transaction).Error; err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// ListFailedLogs 分页查询处理失败的链上日志
+func (ebs *EnhancedBlockchainService) ListFailedLogs(page, pageSize int, status *models.FailedLogStatus) (*models.FailedLogListResponse, error) {
+	var failedLogs []models.FailedLog
+	var total int64
+
+	query := ebs.db.Model(&models.FailedLog{})
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("查询失败日志总数失败: %v", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("id DESC").Find(&failedLogs).Error; err != nil {
+		return nil, fmt.Errorf("查询失败日志列表失败: %v", err)
+	}
+
+	return &models.FailedLogListResponse{
+		FailedLogs: failedLogs,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize)),
+	}, nil
+}
+
+// RetryFailedLog 人工重试处理失败的链上日志
+func (ebs *EnhancedBlockchainService) RetryFailedLog(id uint64) (*models.FailedLog, error) {
+	return ebs.eventListener.RetryFailedLog(id)
+}
+
 // Close 关闭增强区块链服务
 func (ebs *EnhancedBlockchainService) Close() {
 	if ebs.eventListener != nil {
 		ebs.eventListener.Stop()
 	}
+	if ebs.txTracker != nil {
+		ebs.txTracker.Stop()
+	}
 	if ebs.contract != nil {
 		ebs.contract.Close()
 	}
@@ -29,6 +29,7 @@ func (s *CollectionService) CreateCollection(req *models.CreateCollectionRequest
 		Description: req.Description,
 		Website:     req.Website,
 		ImageURI:    req.ImageURI,
+		StartBlock:  req.StartBlock,
 		CreateTime:  &now,
 		UpdateTime:  &now,
 	}
@@ -93,21 +94,50 @@ func (s *CollectionService) ListCollections(page, pageSize int, keyword string)
 	}, nil
 }
 
-// UpdateCollection 更新集合
-func (s *CollectionService) UpdateCollection(id uint64, updates map[string]interface{}) error {
+// collectionProtectedFields 只有管理员可以直接修改的集合字段
+var collectionProtectedFields = []string{"creator", "address", "standard"}
+
+// authorizeCollection 查询集合并校验操作者为集合创建者或管理员
+func (s *CollectionService) authorizeCollection(id uint64, op Operator) error {
+	var collection models.Collection
+	if err := s.db.Select("id", "creator").First(&collection, id).Error; err != nil {
+		return err
+	}
+	if !op.CanModify(collection.Creator) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// UpdateCollection 更新集合，只允许集合创建者或管理员
+func (s *CollectionService) UpdateCollection(id uint64, updates map[string]interface{}, op Operator) error {
+	if err := s.authorizeCollection(id, op); err != nil {
+		return err
+	}
+	if !op.IsAdmin {
+		for _, field := range collectionProtectedFields {
+			if _, ok := updates[field]; ok {
+				return ErrPermissionDenied
+			}
+		}
+	}
+
 	now := time.Now().Unix()
 	updates["update_time"] = now
 
 	return s.db.Model(&models.Collection{}).Where("id = ?", id).Updates(updates).Error
 }
 
-// DeleteCollection 删除集合
-func (s *CollectionService) DeleteCollection(id uint64) error {
+// DeleteCollection 删除集合，只允许集合创建者或管理员
+func (s *CollectionService) DeleteCollection(id uint64, op Operator) error {
+	if err := s.authorizeCollection(id, op); err != nil {
+		return err
+	}
 	return s.db.Delete(&models.Collection{}, id).Error
 }
 
 // UpdateCollectionStats 更新集合统计信息
-func (s *CollectionService) UpdateCollectionStats(address string, ownerAmount, itemAmount int64, floorPrice, salePrice *float64, volumeTotal *float64) error {
+func (s *CollectionService) UpdateCollectionStats(address string, ownerAmount, itemAmount int64, floorPrice, salePrice *models.Wei, volumeTotal *models.Wei) error {
 	updates := map[string]interface{}{
 		"owner_amount": ownerAmount,
 		"item_amount":  itemAmount,
@@ -115,13 +145,13 @@ func (s *CollectionService) UpdateCollectionStats(address string, ownerAmount, i
 	}
 
 	if floorPrice != nil {
-		updates["floor_price"] = floorPrice
+		updates["floor_price"] = *floorPrice
 	}
 	if salePrice != nil {
-		updates["sale_price"] = salePrice
+		updates["sale_price"] = *salePrice
 	}
 	if volumeTotal != nil {
-		updates["volume_total"] = volumeTotal
+		updates["volume_total"] = *volumeTotal
 	}
 
 	return s.db.Model(&models.Collection{}).Where("address = ?", address).Updates(updates).Error
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// orderExpireBatchSize 每批标记过期的订单数量
+	orderExpireBatchSize = 100
+	// expiryTriggerScheduler 定时触发
+	expiryTriggerScheduler = "scheduler"
+	// expiryTriggerManual 管理员手动触发
+	expiryTriggerManual = "manual"
+	// defaultExpiryInterval 未配置或配置无效时过期任务的间隔
+	defaultExpiryInterval = time.Minute
+)
+
+// ErrExpiryRunning 已有过期任务正在执行
+var ErrExpiryRunning = errors.New("订单过期任务正在执行")
+
+// ExpiryScheduler 定期标记过期订单的后台任务，可选在链上调用markOrderExpired退还出价托管的ETH，
+// 并清理用户未签名、长时间未上链的待上链订单
+type ExpiryScheduler struct {
+	db            *gorm.DB
+	orderService  *OrderService
+	interval      time.Duration
+	submitOnChain bool
+	pendingTTL    time.Duration
+	mu            sync.Mutex
+	stopChan      chan struct{}
+	isRunning     bool
+}
+
+// NewExpiryScheduler 创建订单过期后台任务
+func NewExpiryScheduler(db *gorm.DB, orderService *OrderService, interval time.Duration, submitOnChain bool, pendingTTL time.Duration) *ExpiryScheduler {
+	return &ExpiryScheduler{
+		db:            db,
+		orderService:  orderService,
+		interval:      interval,
+		submitOnChain: submitOnChain,
+		pendingTTL:    pendingTTL,
+		stopChan:      make(chan struct{}),
+	}
+}
+
+// Start 启动订单过期任务，只依赖数据库，只读模式下同样运行
+func (s *ExpiryScheduler) Start() {
+	if s.isRunning {
+		return
+	}
+	s.isRunning = true
+	s.stopChan = make(chan struct{})
+	go s.run()
+
+	logger.Info("订单过期任务已启动", logrus.Fields{
+		"interval":        s.expiryInterval().String(),
+		"submit_on_chain": s.submitOnChain,
+		"pending_ttl":     s.pendingTTL.String(),
+	})
+}
+
+// Stop 停止订单过期任务
+func (s *ExpiryScheduler) Stop() {
+	if !s.isRunning {
+		return
+	}
+	close(s.stopChan)
+	s.isRunning = false
+}
+
+// run 按间隔标记过期订单
+func (s *ExpiryScheduler) run() {
+	ticker := time.NewTicker(s.expiryInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.RunOnce(expiryTriggerScheduler, ""); err != nil && err != ErrExpiryRunning {
+				logger.Error("标记过期订单失败", err)
+			}
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// expiryInterval 过期任务间隔，配置不大于0时使用默认值
+func (s *ExpiryScheduler) expiryInterval() time.Duration {
+	if s.interval > 0 {
+		return s.interval
+	}
+	return defaultExpiryInterval
+}
+
+// RunOnce 执行一次过期任务并记录执行结果，triggeredBy为手动触发的管理员地址
+func (s *ExpiryScheduler) RunOnce(trigger, triggeredBy string) (*models.ExpiryRun, error) {
+	if !s.mu.TryLock() {
+		return nil, ErrExpiryRunning
+	}
+	defer s.mu.Unlock()
+
+	now := time.Now().Unix()
+	run := &models.ExpiryRun{
+		Trigger:   trigger,
+		StartTime: now,
+	}
+	if triggeredBy != "" {
+		address := strings.ToLower(triggeredBy)
+		run.TriggeredBy = &address
+	}
+
+	expired, submitted, runErr := s.orderService.MarkExpiredOrders(s.submitOnChain)
+	if s.pendingTTL > 0 {
+		purged, err := s.orderService.PurgeStalePendingOrders(s.pendingTTL)
+		run.PurgedCount = purged
+		if runErr == nil {
+			runErr = err
+		}
+	}
+	end := time.Now().Unix()
+	run.ExpiredCount = expired
+	run.OnChainCount = submitted
+	run.EndTime = &end
+	if runErr != nil {
+		errMsg := truncateError(runErr.Error())
+		run.Error = &errMsg
+	}
+
+	// 定时任务没有过期和清理的订单时不记录，避免执行记录被空任务占满
+	if trigger == expiryTriggerScheduler && runErr == nil && expired == 0 && run.PurgedCount == 0 {
+		return run, nil
+	}
+	if err := s.db.Create(run).Error; err != nil {
+		logger.Error("保存订单过期任务记录失败", err, logrus.Fields{
+			"trigger": trigger,
+		})
+	}
+	return run, runErr
+}
+
+// TriggerManual 管理员手动执行一次过期任务
+func (s *ExpiryScheduler) TriggerManual(adminAddress string) (*models.ExpiryRun, error) {
+	return s.RunOnce(expiryTriggerManual, adminAddress)
+}
+
+// ListRuns 分页获取过期任务执行记录
+func (s *ExpiryScheduler) ListRuns(page, pageSize int) (*models.ExpiryRunListResponse, error) {
+	var runs []models.ExpiryRun
+	var total int64
+
+	query := s.db.Model(&models.ExpiryRun{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("id DESC").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.ExpiryRunListResponse{
+		Runs:       runs,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize)),
+	}, nil
+}
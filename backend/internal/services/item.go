@@ -9,12 +9,16 @@ import (
 
 // ItemService 物品服务
 type ItemService struct {
-	db *gorm.DB
+	db                *gorm.DB
+	blockchainService *EnhancedBlockchainService
 }
 
 // NewItemService 创建物品服务
-func NewItemService(db *gorm.DB) *ItemService {
-	return &ItemService{db: db}
+func NewItemService(db *gorm.DB, blockchainService *EnhancedBlockchainService) *ItemService {
+	return &ItemService{
+		db:                db,
+		blockchainService: blockchainService,
+	}
 }
 
 // CreateItem 创建物品
@@ -101,15 +105,45 @@ func (s *ItemService) ListItems(page, pageSize int, collectionAddress, owner, ke
 	}, nil
 }
 
-// UpdateItem 更新物品
-func (s *ItemService) UpdateItem(id uint64, updates map[string]interface{}) error {
+// itemProtectedFields 只有管理员可以直接修改的物品字段，拥有者由链上转移事件同步
+var itemProtectedFields = []string{"owner", "collection_address", "token_id"}
+
+// authorizeItem 查询物品并校验操作者为物品拥有者或管理员
+func (s *ItemService) authorizeItem(op Operator, query string, args ...interface{}) (*models.Item, error) {
+	var item models.Item
+	if err := s.db.Where(query, args...).First(&item).Error; err != nil {
+		return nil, err
+	}
+	owner := ""
+	if item.Owner != nil {
+		owner = *item.Owner
+	}
+	if !op.CanModify(owner) {
+		return nil, ErrPermissionDenied
+	}
+	return &item, nil
+}
+
+// UpdateItem 更新物品，只允许物品拥有者或管理员
+func (s *ItemService) UpdateItem(id uint64, updates map[string]interface{}, op Operator) error {
+	if _, err := s.authorizeItem(op, "id = ?", id); err != nil {
+		return err
+	}
+	if !op.IsAdmin {
+		for _, field := range itemProtectedFields {
+			if _, ok := updates[field]; ok {
+				return ErrPermissionDenied
+			}
+		}
+	}
+
 	now := time.Now().Unix()
 	updates["update_time"] = now
 
 	return s.db.Model(&models.Item{}).Where("id = ?", id).Updates(updates).Error
 }
 
-// UpdateItemOwner 更新物品拥有者
+// UpdateItemOwner 更新物品拥有者，路由只对管理员开放
 func (s *ItemService) UpdateItemOwner(collectionAddress, tokenID, newOwner string) error {
 	now := time.Now().Unix()
 	updates := map[string]interface{}{
@@ -120,26 +154,33 @@ func (s *ItemService) UpdateItemOwner(collectionAddress, tokenID, newOwner strin
 	return s.db.Model(&models.Item{}).Where("collection_address = ? AND token_id = ?", collectionAddress, tokenID).Updates(updates).Error
 }
 
-// UpdateItemPrice 更新物品价格
-func (s *ItemService) UpdateItemPrice(collectionAddress, tokenID string, listPrice, salePrice *float64) error {
+// UpdateItemPrice 更新物品价格，只允许物品拥有者或管理员
+func (s *ItemService) UpdateItemPrice(collectionAddress, tokenID string, listPrice, salePrice *models.Wei, op Operator) error {
+	if _, err := s.authorizeItem(op, "collection_address = ? AND token_id = ?", collectionAddress, tokenID); err != nil {
+		return err
+	}
+
 	now := time.Now().Unix()
 	updates := map[string]interface{}{
 		"update_time": now,
 	}
 
 	if listPrice != nil {
-		updates["list_price"] = listPrice
+		updates["list_price"] = *listPrice
 		updates["list_time"] = now
 	}
 	if salePrice != nil {
-		updates["sale_price"] = salePrice
+		updates["sale_price"] = *salePrice
 	}
 
 	return s.db.Model(&models.Item{}).Where("collection_address = ? AND token_id = ?", collectionAddress, tokenID).Updates(updates).Error
 }
 
-// DeleteItem 删除物品
-func (s *ItemService) DeleteItem(id uint64) error {
+// DeleteItem 删除物品，只允许物品拥有者或管理员
+func (s *ItemService) DeleteItem(id uint64, op Operator) error {
+	if _, err := s.authorizeItem(op, "id = ?", id); err != nil {
+		return err
+	}
 	return s.db.Delete(&models.Item{}, id).Error
 }
 
@@ -160,3 +201,15 @@ func (s *ItemService) GetItemsByOwner(owner string) ([]models.Item, error) {
 	}
 	return items, nil
 }
+
+// GetItemBalances 获取ERC-1155物品各拥有者的持有数量，按持有数量从多到少排列
+func (s *ItemService) GetItemBalances(collectionAddress, tokenID string) ([]models.ItemBalance, error) {
+	var balances []models.ItemBalance
+	err := s.db.Where("collection_address = ? AND token_id = ? AND balance > 0", collectionAddress, tokenID).
+		Order("balance DESC").
+		Find(&balances).Error
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// metadataIndexBatchSize 每轮索引元数据的最大物品数
+	metadataIndexBatchSize = 20
+	// metadataIndexTimeout 单个物品获取元数据的超时时间
+	metadataIndexTimeout = 30 * time.Second
+	// metadataRetryInterval 索引失败的物品重新索引的间隔
+	metadataRetryInterval = time.Hour
+	// maxMetadataErrorLength 索引失败原因最大保存长度
+	maxMetadataErrorLength = 1024
+	// defaultMetadataIndexInterval 未配置或配置无效时索引元数据的间隔
+	defaultMetadataIndexInterval = time.Minute
+)
+
+// GetItemAttributes 获取物品的全部特征
+func (s *ItemService) GetItemAttributes(collectionAddress, tokenID string) ([]models.ItemAttribute, error) {
+	var attributes []models.ItemAttribute
+	err := s.db.Where("collection_address = ? AND token_id = ?", collectionAddress, tokenID).
+		Order("trait_type ASC, value ASC").
+		Find(&attributes).Error
+	if err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+// IndexItemMetadata 从tokenURI获取物品元数据，用其中的attributes替换物品现有的全部特征
+// 特征只来自链上tokenURI指向的元数据，用户不能直接写入，避免篡改特征后成交特征出价
+func (s *ItemService) IndexItemMetadata(collectionAddress, tokenID string) ([]models.ItemAttribute, error) {
+	if !s.blockchainService.Capabilities().ChainRead {
+		return nil, ErrBlockchainUnavailable
+	}
+
+	var item models.Item
+	if err := s.db.Where("collection_address = ? AND token_id = ?", collectionAddress, tokenID).First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	traits, fetchErr := s.fetchItemTraits(collectionAddress, tokenID)
+	now := time.Now().Unix()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"metadata_time":  now,
+			"metadata_error": nil,
+		}
+		if fetchErr != nil {
+			message := []rune(fetchErr.Error())
+			if len(message) > maxMetadataErrorLength {
+				message = message[:maxMetadataErrorLength]
+			}
+			updates["metadata_error"] = string(message)
+		}
+		if err := tx.Model(&models.Item{}).Where("id = ?", item.ID).UpdateColumns(updates).Error; err != nil {
+			return fmt.Errorf("更新物品元数据索引状态失败: %v", err)
+		}
+		// 获取失败时保留之前索引的特征
+		if fetchErr != nil {
+			return nil
+		}
+		return replaceItemAttributes(tx, collectionAddress, tokenID, traits, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return s.GetItemAttributes(collectionAddress, tokenID)
+}
+
+// IndexPendingMetadata 索引一批尚未索引或上次索引失败超过重试间隔的物品元数据，返回成功索引的数量
+func (s *ItemService) IndexPendingMetadata() (int, error) {
+	var items []models.Item
+	retryBefore := time.Now().Add(-metadataRetryInterval).Unix()
+	err := s.db.Select("id", "collection_address", "token_id").
+		Where("collection_address IS NOT NULL AND collection_address <> ''").
+		Where("metadata_time IS NULL OR (metadata_error IS NOT NULL AND metadata_time < ?)", retryBefore).
+		Order("metadata_time ASC").
+		Order("id ASC").
+		Limit(metadataIndexBatchSize).
+		Find(&items).Error
+	if err != nil {
+		return 0, fmt.Errorf("查询待索引物品失败: %v", err)
+	}
+
+	indexed := 0
+	for _, item := range items {
+		if _, err := s.IndexItemMetadata(*item.CollectionAddress, item.TokenID); err != nil {
+			logger.Warn("索引物品元数据失败", logrus.Fields{
+				"collection": *item.CollectionAddress,
+				"token_id":   item.TokenID,
+				"error":      err.Error(),
+			})
+			continue
+		}
+		indexed++
+	}
+	return indexed, nil
+}
+
+// fetchItemTraits 查询tokenURI并解析元数据中的特征
+func (s *ItemService) fetchItemTraits(collectionAddress, tokenID string) ([]models.Trait, error) {
+	if !common.IsHexAddress(collectionAddress) {
+		return nil, fmt.Errorf("无效的集合地址: %s", collectionAddress)
+	}
+	tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的Token ID: %s", tokenID)
+	}
+
+	contract := s.blockchainService.contract
+	standard := blockchain.ResolveTokenStandard(s.db, contract, collectionAddress)
+	uri, err := contract.TokenURI(common.HexToAddress(collectionAddress), tokenIDBig, standard)
+	if err != nil {
+		return nil, fmt.Errorf("查询元数据地址失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), metadataIndexTimeout)
+	defer cancel()
+	metadata, err := blockchain.FetchTokenMetadata(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Attributes, nil
+}
+
+// replaceItemAttributes 删除物品现有特征并写入新特征，重复的特征只保留一条
+func replaceItemAttributes(tx *gorm.DB, collectionAddress, tokenID string, traits []models.Trait, now int64) error {
+	err := tx.Where("collection_address = ? AND token_id = ?", collectionAddress, tokenID).
+		Delete(&models.ItemAttribute{}).Error
+	if err != nil {
+		return fmt.Errorf("删除物品特征失败: %v", err)
+	}
+
+	traits = uniqueTraits(traits)
+	if len(traits) == 0 {
+		return nil
+	}
+	attributes := make([]models.ItemAttribute, 0, len(traits))
+	for _, trait := range traits {
+		attributes = append(attributes, models.ItemAttribute{
+			CollectionAddress: collectionAddress,
+			TokenID:           tokenID,
+			TraitType:         trait.TraitType,
+			Value:             trait.Value,
+			CreateTime:        &now,
+			UpdateTime:        &now,
+		})
+	}
+	if err := tx.Create(&attributes).Error; err != nil {
+		return fmt.Errorf("保存物品特征失败: %v", err)
+	}
+	return nil
+}
+
+// uniqueTraits 去除重复的特征，保持原有顺序
+func uniqueTraits(traits []models.Trait) []models.Trait {
+	seen := make(map[models.Trait]bool, len(traits))
+	result := make([]models.Trait, 0, len(traits))
+	for _, trait := range traits {
+		if seen[trait] {
+			continue
+		}
+		seen[trait] = true
+		result = append(result, trait)
+	}
+	return result
+}
+
+// MetadataIndexer 定期从tokenURI索引物品特征的后台任务
+type MetadataIndexer struct {
+	itemService *ItemService
+	interval    time.Duration
+	stopChan    chan struct{}
+	isRunning   bool
+}
+
+// NewMetadataIndexer 创建物品元数据索引任务
+func NewMetadataIndexer(itemService *ItemService, interval time.Duration) *MetadataIndexer {
+	return &MetadataIndexer{
+		itemService: itemService,
+		interval:    interval,
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start 启动物品元数据索引任务
+func (m *MetadataIndexer) Start() error {
+	if !m.itemService.blockchainService.Capabilities().ChainRead {
+		return ErrBlockchainUnavailable
+	}
+	if m.isRunning {
+		return fmt.Errorf("元数据索引任务已在运行")
+	}
+	m.isRunning = true
+	m.stopChan = make(chan struct{})
+	go m.run()
+	return nil
+}
+
+// Stop 停止物品元数据索引任务
+func (m *MetadataIndexer) Stop() {
+	if !m.isRunning {
+		return
+	}
+	close(m.stopChan)
+	m.isRunning = false
+}
+
+// run 按间隔索引物品元数据
+func (m *MetadataIndexer) run() {
+	ticker := time.NewTicker(m.indexInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := m.itemService.IndexPendingMetadata(); err != nil {
+				logger.Error("索引物品元数据失败", err)
+			}
+		case <-m.stopChan:
+			return
+		}
+	}
+}
+
+// indexInterval 索引间隔，配置不大于0时使用默认值
+func (m *MetadataIndexer) indexInterval() time.Duration {
+	if m.interval > 0 {
+		return m.interval
+	}
+	return defaultMetadataIndexInterval
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"fmt"
+	"nft-market/internal/logger"
+	"nft-market/internal/matching"
+	"nft-market/internal/models"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// defaultRebuildInterval 未配置或配置无效时重建订单簿的间隔
+const defaultRebuildInterval = time.Minute
+
+// MatchingService 撮合服务：用数据库中的有效订单维护进程内订单簿，新订单进入时检测价格交叉并生成撮合建议
+// 订单簿按间隔从数据库重建，链上事件、过期和失效等在其他位置发生的状态变化由重建同步
+type MatchingService struct {
+	db        *gorm.DB
+	interval  time.Duration
+	mu        sync.Mutex
+	engine    *matching.Engine
+	proposals []matching.Match
+	stopChan  chan struct{}
+	isRunning bool
+}
+
+// NewMatchingService 创建撮合服务
+func NewMatchingService(db *gorm.DB, interval time.Duration) *MatchingService {
+	return &MatchingService{
+		db:       db,
+		interval: interval,
+		engine:   matching.NewEngine(),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start 重建订单簿并启动定期重建任务
+func (s *MatchingService) Start() error {
+	if s.isRunning {
+		return fmt.Errorf("撮合服务已在运行")
+	}
+	if err := s.Rebuild(); err != nil {
+		return err
+	}
+	s.isRunning = true
+	s.stopChan = make(chan struct{})
+	go s.run()
+	return nil
+}
+
+// Stop 停止定期重建任务
+func (s *MatchingService) Stop() {
+	if !s.isRunning {
+		return
+	}
+	close(s.stopChan)
+	s.isRunning = false
+}
+
+// run 按间隔重建订单簿
+func (s *MatchingService) run() {
+	ticker := time.NewTicker(s.rebuildInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Rebuild(); err != nil {
+				logger.Error("重建订单簿失败", err)
+			}
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// rebuildInterval 重建订单簿间隔，配置不大于0时使用默认值
+func (s *MatchingService) rebuildInterval() time.Duration {
+	if s.interval > 0 {
+		return s.interval
+	}
+	return defaultRebuildInterval
+}
+
+// Rebuild 从数据库中的有效订单重建订单簿，撮合建议替换为重建时检测到的交叉订单
+// 带特征条件的集合出价只在卖方接受时按物品特征匹配，不进入订单簿
+func (s *MatchingService) Rebuild() error {
+	var orders []models.Order
+	err := s.db.Where("order_status = ? AND invalid = ? AND order_type IN ? AND (expire_time IS NULL OR expire_time > ?)",
+		models.OrderStatusActive, false,
+		[]models.OrderType{models.OrderTypeListing, models.OrderTypeOffer, models.OrderTypeCollectionBid, models.OrderTypeItemBid},
+		time.Now().Unix()).
+		Where("id NOT IN (?)", s.db.Model(&models.OrderCriteria{}).Select("order_id")).
+		Find(&orders).Error
+	if err != nil {
+		return fmt.Errorf("查询有效订单失败: %v", err)
+	}
+
+	engine := matching.NewEngine()
+	matches := engine.Load(orders)
+
+	s.mu.Lock()
+	s.engine = engine
+	s.proposals = matches
+	s.mu.Unlock()
+
+	logger.Debug("订单簿已重建", logrus.Fields{
+		"orders":    engine.Len(),
+		"proposals": len(matches),
+	})
+	return nil
+}
+
+// Submit 把新的有效订单放入订单簿，价格交叉时记录撮合建议；撮合服务未启用时忽略
+func (s *MatchingService) Submit(order *models.Order) {
+	if s == nil || order.OrderStatus != models.OrderStatusActive || order.Invalid || len(order.Criteria) > 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	match, err := s.engine.Add(order)
+	if err != nil {
+		logger.Debug("订单未进入订单簿", logrus.Fields{
+			"order_id": order.ID,
+			"reason":   err.Error(),
+		})
+		return
+	}
+	if match != nil {
+		s.proposals = append(s.proposals, *match)
+		logger.Info("检测到价格交叉的订单", logrus.Fields{
+			"ask_id":    match.Ask.ID,
+			"bid_id":    match.Bid.ID,
+			"price_wei": match.Price.String(),
+		})
+	}
+}
+
+// Remove 从订单簿中移除已取消、成交或被替换的订单；撮合服务未启用时忽略
+func (s *MatchingService) Remove(id uint64) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.engine.Remove(id)
+}
+
+// Proposals 返回待执行的撮合建议，过滤掉双方订单已不再有效的建议
+func (s *MatchingService) Proposals() ([]matching.Match, error) {
+	s.mu.Lock()
+	proposals := make([]matching.Match, len(s.proposals))
+	copy(proposals, s.proposals)
+	s.mu.Unlock()
+
+	if len(proposals) == 0 {
+		return proposals, nil
+	}
+
+	ids := make([]uint64, 0, len(proposals)*2)
+	for _, proposal := range proposals {
+		ids = append(ids, proposal.Ask.ID, proposal.Bid.ID)
+	}
+	var activeIDs []uint64
+	err := s.db.Model(&models.Order{}).
+		Where("id IN ? AND order_status = ? AND invalid = ?", ids, models.OrderStatusActive, false).
+		Pluck("id", &activeIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询订单状态失败: %v", err)
+	}
+	active := make(map[uint64]bool, len(activeIDs))
+	for _, id := range activeIDs {
+		active[id] = true
+	}
+
+	result := make([]matching.Match, 0, len(proposals))
+	for _, proposal := range proposals {
+		if active[proposal.Ask.ID] && active[proposal.Bid.ID] {
+			result = append(result, proposal)
+		}
+	}
+	return result, nil
+}
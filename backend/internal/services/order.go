@@ -3,8 +3,10 @@ package services
 import (
 	"fmt"
 	"math/big"
+	"nft-market/internal/blockchain"
 	"nft-market/internal/logger"
 	"nft-market/internal/models"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -15,6 +17,8 @@ import (
 type OrderService struct {
 	db                *gorm.DB
 	blockchainService *EnhancedBlockchainService
+	matchingService   *MatchingService
+	signedOrders      bool
 }
 
 // NewOrderService 创建新的订单服务
@@ -25,12 +29,39 @@ func NewOrderService(db *gorm.DB, blockchainService *EnhancedBlockchainService)
 	}
 }
 
+// SetMatchingService 设置撮合服务，新订单进入订单簿检测价格交叉，取消和成交的订单移出订单簿
+func (os *OrderService) SetMatchingService(matchingService *MatchingService) {
+	os.matchingService = matchingService
+}
+
+// chainWritable 热钱包是否可以发送交易，只读模式下订单只写入数据库
+func (os *OrderService) chainWritable() bool {
+	return os.blockchainService.Capabilities().ChainWrite
+}
+
+// isChainOrderType 市场合约只支持挂单和出价，物品出价和集合出价只在数据库中创建和成交
+func isChainOrderType(orderType models.OrderType) bool {
+	return orderType == models.OrderTypeListing || orderType == models.OrderTypeOffer
+}
+
 // CreateOrder 创建订单
 func (os *OrderService) CreateOrder(req *models.CreateOrderRequest, maker string) (*models.Order, error) {
 	// 验证输入
 	if err := os.validateCreateOrderRequest(req); err != nil {
 		return nil, err
 	}
+	if err := validateOrderCriteria(req); err != nil {
+		return nil, err
+	}
+	standard := os.tokenStandard(req.CollectionAddress)
+	if err := normalizeOrderQuantity(req, standard); err != nil {
+		return nil, err
+	}
+	if err := os.validateCreateOrderOnChain(req, maker); err != nil {
+		return nil, err
+	}
+	// ERC-1155订单带数量，物品出价和集合出价没有对应的合约订单类型，只在数据库中创建和成交
+	onChain := os.chainWritable() && standard != models.TokenStandardERC1155 && isChainOrderType(req.OrderType)
 
 	// 生成订单ID（这里简化处理，实际项目中应该从区块链获取）
 	orderID := fmt.Sprintf("0x%x", time.Now().UnixNano())
@@ -50,9 +81,13 @@ func (os *OrderService) CreateOrder(req *models.CreateOrderRequest, maker string
 		CurrencyAddress:   req.CurrencyAddress,
 		EventTime:         &now,
 		ExpireTime:        req.ExpireTime,
+		Criteria:          newOrderCriteria(req.Criteria, now),
 		CreateTime:        &now,
 		UpdateTime:        &now,
 	}
+	if onChain {
+		order.ChainStatus = models.OrderChainStatusPending
+	}
 
 	// 开始数据库事务
 	tx := os.db.Begin()
@@ -72,57 +107,32 @@ func (os *OrderService) CreateOrder(req *models.CreateOrderRequest, maker string
 	}
 
 	// 创建或更新Item记录
-	if err := os.createOrUpdateItem(tx, req, maker, now); err != nil {
+	if err := os.createOrUpdateItem(tx, req, maker, standard, now); err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("创建Item记录失败: %v", err)
 	}
 
+	// 链上创建操作写入发件箱，由后台任务提交并确认
+	if onChain {
+		if err := enqueueChainAction(tx, order.ID, models.OutboxActionCreateOrder, models.Wei{}, now); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("提交事务失败: %v", err)
 	}
 
-	// 在区块链上创建订单
-	if os.blockchainService != nil {
-		logger.Info("开始在区块链上创建订单", logrus.Fields{
-			"order_id": order.OrderID,
-		})
-
-		// 异步创建链上订单，避免阻塞用户操作
-		go func() {
-			tx, err := os.blockchainService.CreateOrderOnChain(order)
-			if err != nil {
-				logger.Error("链上创建订单失败", err, logrus.Fields{
-					"order_id": order.OrderID,
-				})
-				return
-			}
-
-			// 等待交易确认
-			receipt, err := os.blockchainService.WaitForTransactionConfirmation(tx, 5*time.Minute)
-			if err != nil {
-				logger.Error("等待交易确认失败", err, logrus.Fields{
-					"order_id": order.OrderID,
-					"tx_hash":  tx.Hash().Hex(),
-				})
-				return
-			}
-
-			logger.Info("链上订单创建并确认成功", logrus.Fields{
-				"order_id":     order.OrderID,
-				"tx_hash":      tx.Hash().Hex(),
-				"block_number": receipt.BlockNumber.String(),
-			})
-		}()
-	}
-
+	os.matchingService.Submit(order)
 	return order, nil
 }
 
 // GetOrderByID 根据ID获取订单
 func (os *OrderService) GetOrderByID(id uint) (*models.Order, error) {
 	var order models.Order
-	if err := os.db.First(&order, id).Error; err != nil {
+	if err := os.db.Preload("Criteria").First(&order, id).Error; err != nil {
 		return nil, err
 	}
 	return &order, nil
@@ -137,6 +147,15 @@ func (os *OrderService) GetOrderByOrderID(orderID string) (*models.Order, error)
 	return &order, nil
 }
 
+// GetOrderChainActions 获取订单的链上操作记录
+func (os *OrderService) GetOrderChainActions(id uint64) ([]models.ChainOutbox, error) {
+	var entries []models.ChainOutbox
+	if err := os.db.Where("order_id = ?", id).Order("id ASC").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("查询链上操作记录失败: %v", err)
+	}
+	return entries, nil
+}
+
 // GetUserOrders 获取用户订单
 func (os *OrderService) GetUserOrders(userAddress string, page, pageSize int, status string) (*models.OrderListResponse, error) {
 	var orders []models.Order
@@ -205,8 +224,8 @@ func (os *OrderService) GetActiveOrders(page, pageSize int, orderType string) (*
 	var total int64
 
 	now := time.Now().Unix()
-	query := os.db.Model(&models.Order{}).Where("order_status = ? AND (expire_time IS NULL OR expire_time > ?)",
-		models.OrderStatusActive, now)
+	query := os.db.Model(&models.Order{}).Where("order_status = ? AND invalid = ? AND (expire_time IS NULL OR expire_time > ?)",
+		models.OrderStatusActive, false, now)
 
 	if orderType != "" {
 		query = query.Where("order_type = ?", orderType)
@@ -243,7 +262,7 @@ func (os *OrderService) CancelOrder(orderID uint64, userAddress string) error {
 	}
 
 	// 验证权限
-	if order.Maker != userAddress {
+	if !strings.EqualFold(order.Maker, userAddress) {
 		return fmt.Errorf("无权限取消此订单")
 	}
 
@@ -252,58 +271,52 @@ func (os *OrderService) CancelOrder(orderID uint64, userAddress string) error {
 		return fmt.Errorf("订单状态不允许取消")
 	}
 
-	// 更新状态
+	// 更新状态，链上取消操作在同一事务中写入发件箱；只有由发件箱在链上创建的挂单和出价需要在链上取消，
+	// 链下签名订单、物品出价、集合出价和ERC-1155订单没有对应的链上订单，只在数据库中取消
+	onChain := false
+	if order.Signature == nil && isChainOrderType(order.OrderType) {
+		custodial, err := os.isCustodialOrder(order.ID)
+		if err != nil {
+			return err
+		}
+		// 用户钱包创建的链上订单只能由用户自己在链上取消
+		if !custodial && order.BlockHash != nil {
+			return fmt.Errorf("订单由用户钱包在链上创建，请通过 /orders/:id/tx/cancel 构建取消交易")
+		}
+		onChain = custodial && os.chainWritable()
+	}
 	now := time.Now().Unix()
 	order.OrderStatus = models.OrderStatusCancelled
 	order.UpdateTime = &now
-
-	if err := os.db.Save(&order).Error; err != nil {
-		return fmt.Errorf("更新订单状态失败: %v", err)
+	if onChain {
+		order.ChainStatus = models.OrderChainStatusPending
 	}
 
-	// 在区块链上取消订单
-	if os.blockchainService != nil {
-		logger.Info("开始在区块链上取消订单", logrus.Fields{
-			"order_id": orderID,
-		})
-
-		// 异步取消链上订单
-		go func() {
-			tx, err := os.blockchainService.CancelOrderOnChain(orderID)
-			if err != nil {
-				logger.Error("链上取消订单失败", err, logrus.Fields{
-					"order_id": orderID,
-				})
-				return
-			}
-
-			// 等待交易确认
-			receipt, err := os.blockchainService.WaitForTransactionConfirmation(tx, 2*time.Minute)
-			if err != nil {
-				logger.Error("等待取消交易确认失败", err, logrus.Fields{
-					"order_id": orderID,
-					"tx_hash":  tx.Hash().Hex(),
-				})
-				return
-			}
-
-			logger.Info("链上订单取消并确认成功", logrus.Fields{
-				"order_id":     orderID,
-				"tx_hash":      tx.Hash().Hex(),
-				"block_number": receipt.BlockNumber.String(),
-			})
-		}()
+	err := os.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&order).Error; err != nil {
+			return fmt.Errorf("更新订单状态失败: %v", err)
+		}
+		if onChain {
+			return enqueueChainAction(tx, order.ID, models.OutboxActionCancelOrder, models.Wei{}, now)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	os.matchingService.Remove(order.ID)
 	return nil
 }
 
 // PurchaseOrder 购买订单
-func (os *OrderService) PurchaseOrder(orderID uint64, buyerAddress string, offeredPrice float64) error {
+// ERC-1155挂单可指定购买数量部分成交，quantity为0表示购买全部剩余数量
+func (os *OrderService) PurchaseOrder(orderID uint64, buyerAddress string, offeredPrice models.Wei, quantity int64) error {
 	logger.Info("开始购买订单", logrus.Fields{
 		"order_id":      orderID,
 		"buyer_address": buyerAddress,
-		"offered_price": offeredPrice,
+		"offered_price": offeredPrice.String(),
+		"quantity":      quantity,
 	})
 
 	// 查找订单
@@ -318,7 +331,7 @@ func (os *OrderService) PurchaseOrder(orderID uint64, buyerAddress string, offer
 	logger.Info("找到订单", logrus.Fields{
 		"order_id":     orderID,
 		"order_maker":  order.Maker,
-		"order_price":  order.Price,
+		"order_price":  order.Price.String(),
 		"order_status": order.OrderStatus,
 		"order_type":   order.OrderType,
 	})
@@ -333,14 +346,28 @@ func (os *OrderService) PurchaseOrder(orderID uint64, buyerAddress string, offer
 		return fmt.Errorf("只能购买上架订单（listing），当前订单类型: %d", order.OrderType)
 	}
 
+	standard := os.tokenStandard(order.CollectionAddress)
+	quantity, err := fillQuantity(&order, quantity, standard)
+	if err != nil {
+		return err
+	}
+
+	// 挂单方已转出NFT等链上状态变化导致订单无法成交
+	if order.Invalid {
+		return invalidOrderError(&order)
+	}
+	if err := os.validateOrderFillable(&order); err != nil {
+		return err
+	}
+
 	// 验证买家不是卖家
-	if order.Maker == buyerAddress {
+	if strings.EqualFold(order.Maker, buyerAddress) {
 		return fmt.Errorf("不能购买自己的订单")
 	}
 
 	// 验证价格（如果提供了价格，必须匹配或更高）
-	if offeredPrice > 0 && offeredPrice < order.Price {
-		return fmt.Errorf("出价过低，订单价格: %.6f ETH，您的出价: %.6f ETH", order.Price, offeredPrice)
+	if offeredPrice.Sign() > 0 && offeredPrice.Cmp(order.Price) < 0 {
+		return fmt.Errorf("出价过低，订单价格: %s ETH，您的出价: %s ETH", order.Price.ETH(), offeredPrice.ETH())
 	}
 
 	// 检查订单是否过期
@@ -348,12 +375,32 @@ func (os *OrderService) PurchaseOrder(orderID uint64, buyerAddress string, offer
 		return fmt.Errorf("订单已过期")
 	}
 
+	// 只有由发件箱在链上创建并已确认的挂单由热钱包在链上执行；用户钱包创建的链上挂单只能由买方钱包成交，
+	// 签名订单、ERC-1155订单和未上链的订单只在数据库中成交
+	onChain := false
+	if order.BlockHash != nil || order.ChainStatus != models.OrderChainStatusNone {
+		custodial, err := os.isCustodialOrder(order.ID)
+		if err != nil {
+			return err
+		}
+		if !custodial {
+			return fmt.Errorf("订单由用户钱包在链上创建，请通过 /orders/:id/tx/execute 构建购买交易")
+		}
+		if order.BlockHash == nil {
+			return fmt.Errorf("订单尚未在链上确认，请稍后再购买")
+		}
+		if !os.chainWritable() {
+			return blockchain.ErrSignerNotConfigured
+		}
+		onChain = true
+	}
+
 	logger.Info("开始处理订单购买", logrus.Fields{
 		"order_id":      orderID,
 		"buyer":         buyerAddress,
 		"seller":        order.Maker,
-		"price":         order.Price,
-		"offered_price": offeredPrice,
+		"price":         order.Price.String(),
+		"offered_price": offeredPrice.String(),
 	})
 
 	// 开始数据库事务
@@ -367,80 +414,64 @@ func (os *OrderService) PurchaseOrder(orderID uint64, buyerAddress string, offer
 		}
 	}()
 
-	// 更新订单状态
+	// 更新订单状态，部分成交时扣减剩余数量
 	now := time.Now().Unix()
 	updateData := map[string]interface{}{
-		"order_status": models.OrderStatusFilled,
-		"taker":        buyerAddress,
-		"update_time":  now,
+		"update_time": now,
+	}
+	if onChain {
+		updateData["chain_status"] = models.OrderChainStatusPending
 	}
 
-	if err := tx.Model(&order).Updates(updateData).Error; err != nil {
+	filled, err := fillOrder(tx, &order, quantity, buyerAddress, updateData)
+	if err != nil {
 		tx.Rollback()
-		return fmt.Errorf("更新订单状态失败: %v", err)
+		return err
 	}
 
-	// 更新Item的拥有者
-	if err := os.updateItemOwner(tx, order.CollectionAddress, order.TokenID, buyerAddress, now); err != nil {
+	// 更新Item的拥有者，ERC-1155更新双方持有数量
+	if standard == models.TokenStandardERC1155 {
+		if err := transferERC1155(tx, order.CollectionAddress, order.TokenID, order.Maker, buyerAddress, quantity); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("更新持有数量失败: %v", err)
+		}
+	} else if err := os.updateItemOwner(tx, order.CollectionAddress, order.TokenID, buyerAddress, now); err != nil {
 		tx.Rollback()
 		return fmt.Errorf("更新物品拥有者失败: %v", err)
 	}
 
 	// 创建交易活动记录
-	if err := os.createPurchaseActivity(tx, &order, buyerAddress, now); err != nil {
+	if err := os.createPurchaseActivity(tx, &order, buyerAddress, quantity, now); err != nil {
 		tx.Rollback()
 		return fmt.Errorf("创建交易活动记录失败: %v", err)
 	}
 
+	// 链上执行操作写入发件箱，由后台任务提交并确认
+	if onChain {
+		finalPrice := order.Price
+		if offeredPrice.Sign() > 0 {
+			finalPrice = offeredPrice
+		}
+		if err := enqueueChainAction(tx, order.ID, models.OutboxActionExecuteOrder, finalPrice, now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		return fmt.Errorf("提交事务失败: %v", err)
 	}
 
-	// 在区块链上执行订单（异步）
-	if os.blockchainService != nil {
-		logger.Info("开始在区块链上执行订单", logrus.Fields{
-			"order_id": orderID,
-		})
-
-		// 异步执行链上订单
-		go func() {
-			finalPrice := order.Price
-			if offeredPrice > 0 {
-				finalPrice = offeredPrice
-			}
-
-			tx, err := os.blockchainService.ExecuteOrderOnChain(orderID, finalPrice)
-			if err != nil {
-				logger.Error("链上执行订单失败", err, logrus.Fields{
-					"order_id": orderID,
-				})
-				return
-			}
-
-			// 等待交易确认
-			receipt, err := os.blockchainService.WaitForTransactionConfirmation(tx, 5*time.Minute)
-			if err != nil {
-				logger.Error("等待执行交易确认失败", err, logrus.Fields{
-					"order_id": orderID,
-					"tx_hash":  tx.Hash().Hex(),
-				})
-				return
-			}
-
-			logger.Info("链上订单执行并确认成功", logrus.Fields{
-				"order_id":     orderID,
-				"tx_hash":      tx.Hash().Hex(),
-				"block_number": receipt.BlockNumber.String(),
-			})
-		}()
+	if filled {
+		os.matchingService.Remove(order.ID)
 	}
 
 	logger.Info("订单购买成功", logrus.Fields{
 		"order_id": orderID,
 		"buyer":    buyerAddress,
 		"seller":   order.Maker,
-		"price":    order.Price,
+		"price":    order.Price.String(),
 	})
 
 	return nil
@@ -479,15 +510,16 @@ func (os *OrderService) updateItemOwner(tx *gorm.DB, collectionAddress, tokenID,
 	return nil
 }
 
-// createPurchaseActivity 创建购买活动记录
-func (os *OrderService) createPurchaseActivity(tx *gorm.DB, order *models.Order, buyer string, now int64) error {
+// createPurchaseActivity 创建购买活动记录，价格为成交数量的总价
+func (os *OrderService) createPurchaseActivity(tx *gorm.DB, order *models.Order, buyer string, quantity, now int64) error {
 	activity := &models.Activity{
 		ActivityType:      models.ActivityTypeBuy,
 		Maker:             &order.Maker,
 		Taker:             &buyer,
 		CollectionAddress: &order.CollectionAddress,
 		TokenID:           &order.TokenID,
-		Price:             order.Price,
+		Price:             totalPrice(order.Price, quantity),
+		Amount:            quantity,
 		BlockNumber:       0, // 链上确认后会更新
 		EventTime:         &now,
 		CreateTime:        &now,
@@ -503,7 +535,7 @@ func (os *OrderService) createPurchaseActivity(tx *gorm.DB, order *models.Order,
 		"order_id": order.ID,
 		"buyer":    buyer,
 		"seller":   order.Maker,
-		"price":    order.Price,
+		"price":    order.Price.String(),
 	})
 
 	return nil
@@ -511,6 +543,10 @@ func (os *OrderService) createPurchaseActivity(tx *gorm.DB, order *models.Order,
 
 // SyncOrderFromChain 从链上同步订单信息
 func (os *OrderService) SyncOrderFromChain(orderID uint64) (*models.Order, error) {
+	if os.blockchainService == nil {
+		return nil, ErrBlockchainUnavailable
+	}
+
 	// 从链上获取订单信息
 	chainOrder, err := os.blockchainService.GetOrderFromChain(big.NewInt(int64(orderID)))
 	if err != nil {
@@ -528,7 +564,7 @@ func (os *OrderService) SyncOrderFromChain(orderID uint64) (*models.Order, error
 	now := time.Now().Unix()
 	maker := chainOrder["maker"].(string)
 	tokenID := chainOrder["tokenId"].(*big.Int).String()
-	price := float64(chainOrder["price"].(*big.Int).Int64()) / 1e18 // 转换为ETH单位
+	price := models.NewWei(chainOrder["price"].(*big.Int))
 	collectionAddress := chainOrder["nftContract"].(string)
 
 	order := &models.Order{
@@ -560,23 +596,126 @@ func (os *OrderService) SyncOrderFromChain(orderID uint64) (*models.Order, error
 	return order, nil
 }
 
-// MarkExpiredOrders 标记过期订单
-func (os *OrderService) MarkExpiredOrders() error {
+// MarkExpiredOrders 标记过期订单并记录过期活动，返回标记过期的订单数和提交链上过期的订单数
+// submitOnChain为true且热钱包可用时，已上链的出价在同一事务中写入markOrderExpired，由合约退还托管的ETH
+func (os *OrderService) MarkExpiredOrders(submitOnChain bool) (int64, int64, error) {
 	now := time.Now().Unix()
-	result := os.db.Model(&models.Order{}).
-		Where("order_status = ? AND expire_time IS NOT NULL AND expire_time <= ?", models.OrderStatusActive, now).
-		Update("order_status", models.OrderStatusExpired)
+	onChain := submitOnChain && os.chainWritable()
+
+	var lastID uint64
+	var expired, submitted int64
+	for {
+		var orders []models.Order
+		err := os.db.Where("id > ? AND order_status = ? AND expire_time IS NOT NULL AND expire_time <= ?",
+			lastID, models.OrderStatusActive, now).
+			Order("id ASC").
+			Limit(orderExpireBatchSize).
+			Find(&orders).Error
+		if err != nil {
+			return expired, submitted, fmt.Errorf("查询过期订单失败: %v", err)
+		}
+		if len(orders) == 0 {
+			break
+		}
 
-	if result.Error != nil {
-		return result.Error
+		for i := range orders {
+			order := &orders[i]
+			lastID = order.ID
+			// 只有合约托管了ETH的已上链出价需要调用markOrderExpired退款
+			submit := onChain && order.OrderType == models.OrderTypeOffer && order.BlockHash != nil
+
+			updated := false
+			err := os.db.Transaction(func(tx *gorm.DB) error {
+				updates := map[string]interface{}{
+					"order_status": models.OrderStatusExpired,
+					"update_time":  now,
+				}
+				if submit {
+					updates["chain_status"] = models.OrderChainStatusPending
+				}
+				result := tx.Model(&models.Order{}).
+					Where("id = ? AND order_status = ?", order.ID, models.OrderStatusActive).
+					Updates(updates)
+				if result.Error != nil {
+					return fmt.Errorf("更新订单状态失败: %v", result.Error)
+				}
+				// 订单已被其他操作更新（成交、取消）
+				if result.RowsAffected == 0 {
+					return nil
+				}
+				updated = true
+
+				activity := &models.Activity{
+					ActivityType:      models.ActivityTypeExpire,
+					Maker:             &order.Maker,
+					CollectionAddress: &order.CollectionAddress,
+					TokenID:           &order.TokenID,
+					Price:             order.Price,
+					EventTime:         &now,
+					CreateTime:        &now,
+					UpdateTime:        &now,
+					CurrencyAddress:   "1", // ETH
+				}
+				if err := tx.Create(activity).Error; err != nil {
+					return fmt.Errorf("创建过期活动记录失败: %v", err)
+				}
+
+				if order.OrderType == models.OrderTypeListing {
+					if err := blockchain.RefreshItemListPrice(tx, order.CollectionAddress, order.TokenID); err != nil {
+						return err
+					}
+				}
+				if submit {
+					return enqueueChainAction(tx, order.ID, models.OutboxActionMarkExpired, models.Wei{}, now)
+				}
+				return nil
+			})
+			if err != nil {
+				logger.Error("标记过期订单失败", err, logrus.Fields{
+					"order_id": order.ID,
+				})
+				continue
+			}
+			if updated {
+				expired++
+				if submit {
+					submitted++
+				}
+			}
+		}
 	}
 
-	fmt.Printf("标记了 %d 个过期订单\n", result.RowsAffected)
-	return nil
+	if expired > 0 {
+		logger.Info("已标记过期订单", logrus.Fields{
+			"expired":   expired,
+			"submitted": submitted,
+		})
+	}
+	return expired, submitted, nil
 }
 
-// createOrUpdateItem 创建或更新Item记录
-func (os *OrderService) createOrUpdateItem(tx *gorm.DB, req *models.CreateOrderRequest, maker string, now int64) error {
+// PurgeStalePendingOrders 删除创建超过ttl仍未上链的待上链订单
+// 这些订单只在用户请求未签名交易时生成，用户未签名或交易未上链时不会被事件激活；
+// 之后才上链的交易仍会由事件监听器按链上事件创建订单
+func (os *OrderService) PurgeStalePendingOrders(ttl time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-ttl).Unix()
+	result := os.db.Unscoped().
+		Where("order_status = ? AND create_time < ?", models.OrderStatusPending, cutoff).
+		Delete(&models.Order{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("清理超时待上链订单失败: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		logger.Info("已清理超时未上链的待上链订单", logrus.Fields{
+			"purged": result.RowsAffected,
+			"ttl":    ttl.String(),
+		})
+	}
+	return result.RowsAffected, nil
+}
+
+// createOrUpdateItem 创建或更新Item记录，ERC-1155物品的拥有者按持有数量记录，不更新Item拥有者
+func (os *OrderService) createOrUpdateItem(tx *gorm.DB, req *models.CreateOrderRequest, maker string, standard models.TokenStandard, now int64) error {
 	var item models.Item
 
 	// 检查Item是否已存在
@@ -595,6 +734,10 @@ func (os *OrderService) createOrUpdateItem(tx *gorm.DB, req *models.CreateOrderR
 			CreateTime:        &now,
 			UpdateTime:        &now,
 		}
+		if standard == models.TokenStandardERC1155 {
+			item.Owner = nil
+			item.Supply = req.Size
+		}
 
 		// 如果是上架订单，设置上架价格和时间
 		if req.OrderType == models.OrderTypeListing {
@@ -624,7 +767,9 @@ func (os *OrderService) createOrUpdateItem(tx *gorm.DB, req *models.CreateOrderR
 		if req.OrderType == models.OrderTypeListing {
 			updateData["list_price"] = req.Price
 			updateData["list_time"] = now
-			updateData["owner"] = maker // 更新拥有者
+			if standard != models.TokenStandardERC1155 {
+				updateData["owner"] = maker // 更新拥有者
+			}
 		}
 
 		if err := tx.Model(&item).Updates(updateData).Error; err != nil {
@@ -654,7 +799,7 @@ func (os *OrderService) validateCreateOrderRequest(req *models.CreateOrderReques
 	}
 
 	if req.OrderType == models.OrderTypeListing || req.OrderType == models.OrderTypeOffer {
-		if req.Price <= 0 {
+		if req.Price.Sign() <= 0 {
 			return fmt.Errorf("订单必须指定有效价格")
 		}
 	}
@@ -665,3 +810,12 @@ func (os *OrderService) validateCreateOrderRequest(req *models.CreateOrderReques
 
 	return nil
 }
+
+// invalidOrderError 返回失效订单的错误信息
+func invalidOrderError(order *models.Order) error {
+	reason := "未知原因"
+	if order.InvalidReason != nil {
+		reason = invalidReasonText(*order.InvalidReason)
+	}
+	return fmt.Errorf("订单已失效: %s", reason)
+}
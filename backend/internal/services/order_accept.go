@@ -0,0 +1,251 @@
+package services
+
+import (
+	"fmt"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// AcceptOffer NFT拥有者接受出价：校验拥有权后把NFT转给出价方并记录出售活动
+// 集合出价可由卖方选择集合内满足特征条件的任意token成交，出价和物品出价只能以订单指定的token成交
+// 已在链上创建的出价必须由卖方钱包调用executeOrder成交，此时不修改数据库，返回由卖方签名的未签名交易，
+// 事件监听器收到OrderFilled事件后再更新订单和物品
+func (os *OrderService) AcceptOffer(orderID uint64, sellerAddress string, req *models.AcceptOfferRequest) (*models.Order, *blockchain.UnsignedTransaction, error) {
+	logger.Info("开始接受出价", logrus.Fields{
+		"order_id":       orderID,
+		"seller_address": sellerAddress,
+		"token_id":       req.TokenID,
+	})
+
+	var order models.Order
+	if err := os.db.First(&order, orderID).Error; err != nil {
+		return nil, nil, fmt.Errorf("订单不存在: %v", err)
+	}
+
+	if order.OrderStatus != models.OrderStatusActive {
+		return nil, nil, fmt.Errorf("订单状态不允许成交，当前状态: %d", order.OrderStatus)
+	}
+
+	tokenID, err := acceptedTokenID(&order, req.TokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	standard := os.tokenStandard(order.CollectionAddress)
+	quantity, err := fillQuantity(&order, req.Quantity, standard)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 出价方余额不足等链上状态变化导致订单无法成交
+	if order.Invalid {
+		return nil, nil, invalidOrderError(&order)
+	}
+	if err := os.validateOrderFillable(&order); err != nil {
+		return nil, nil, err
+	}
+
+	if strings.EqualFold(order.Maker, sellerAddress) {
+		return nil, nil, fmt.Errorf("不能接受自己的出价")
+	}
+
+	if order.ExpireTime != nil && time.Now().Unix() > *order.ExpireTime {
+		return nil, nil, fmt.Errorf("订单已过期")
+	}
+
+	if order.OrderType == models.OrderTypeCollectionBid {
+		if err := os.validateBidCriteria(&order, tokenID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if standard == models.TokenStandardERC1155 {
+		err = os.validateERC1155Balance(order.CollectionAddress, tokenID, sellerAddress, quantity)
+	} else {
+		err = os.validateSellerOwnership(order.CollectionAddress, tokenID, sellerAddress, order.Price)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 只有ERC-721的出价会在链上创建，签名订单、物品出价、集合出价和ERC-1155订单只在数据库中成交；
+	// 链上出价由卖方钱包成交，热钱包是出价的maker或与订单无关，不能代为执行
+	if order.BlockHash != nil || order.ChainStatus != models.OrderChainStatusNone {
+		if order.BlockHash == nil {
+			return nil, nil, fmt.Errorf("订单尚未在链上确认，请稍后再成交")
+		}
+		if !os.blockchainService.Capabilities().ChainRead {
+			return nil, nil, ErrBlockchainUnavailable
+		}
+		unsignedTx, err := os.blockchainService.BuildExecuteOrderTx(&order, common.HexToAddress(sellerAddress))
+		if err != nil {
+			return nil, nil, err
+		}
+		return &order, unsignedTx, nil
+	}
+
+	tx := os.db.Begin()
+	if tx.Error != nil {
+		return nil, nil, fmt.Errorf("开始事务失败: %v", tx.Error)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	now := time.Now().Unix()
+	updateData := map[string]interface{}{
+		"update_time": now,
+	}
+	// 全部成交的集合出价记录卖方选择成交的token
+	if order.OrderType == models.OrderTypeCollectionBid && quantity == order.QuantityRemaining {
+		updateData["token_id"] = tokenID
+	}
+	filled, err := fillOrder(tx, &order, quantity, sellerAddress, updateData)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	var staleListings []uint64
+	if standard == models.TokenStandardERC1155 {
+		if err := transferERC1155(tx, order.CollectionAddress, tokenID, sellerAddress, order.Maker, quantity); err != nil {
+			tx.Rollback()
+			return nil, nil, fmt.Errorf("更新持有数量失败: %v", err)
+		}
+	} else {
+		// 卖方在该token上的挂单随NFT转出而失效
+		staleListings, err = os.invalidateSellerListings(tx, order.CollectionAddress, tokenID, sellerAddress, now)
+		if err != nil {
+			tx.Rollback()
+			return nil, nil, fmt.Errorf("更新卖方挂单失败: %v", err)
+		}
+
+		if err := os.updateItemOwner(tx, order.CollectionAddress, tokenID, order.Maker, now); err != nil {
+			tx.Rollback()
+			return nil, nil, fmt.Errorf("更新物品拥有者失败: %v", err)
+		}
+	}
+
+	if err := os.createSellActivity(tx, &order, tokenID, sellerAddress, quantity, now); err != nil {
+		tx.Rollback()
+		return nil, nil, fmt.Errorf("创建交易活动记录失败: %v", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, nil, fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	if filled {
+		os.matchingService.Remove(order.ID)
+	}
+	for _, id := range staleListings {
+		os.matchingService.Remove(id)
+	}
+
+	logger.Info("出价接受成功", logrus.Fields{
+		"order_id": orderID,
+		"seller":   sellerAddress,
+		"buyer":    order.Maker,
+		"token_id": tokenID,
+		"quantity": quantity,
+		"price":    order.Price.String(),
+	})
+
+	if err := os.db.First(&order, orderID).Error; err != nil {
+		return nil, nil, fmt.Errorf("查询订单失败: %v", err)
+	}
+	return &order, nil, nil
+}
+
+// acceptedTokenID 确定接受出价时成交的token
+func acceptedTokenID(order *models.Order, requested string) (string, error) {
+	switch order.OrderType {
+	case models.OrderTypeCollectionBid:
+		if requested == "" {
+			return "", fmt.Errorf("接受集合出价需要指定token_id")
+		}
+		return requested, nil
+	case models.OrderTypeOffer, models.OrderTypeItemBid:
+		if requested != "" && requested != order.TokenID {
+			return "", fmt.Errorf("该出价只能以token %s 成交", order.TokenID)
+		}
+		return order.TokenID, nil
+	default:
+		return "", fmt.Errorf("只能接受出价类订单，当前订单类型: %d", order.OrderType)
+	}
+}
+
+// validateSellerOwnership 校验卖方拥有该NFT，链上可读时同时校验链上拥有者和市场授权
+func (os *OrderService) validateSellerOwnership(collectionAddress, tokenID, sellerAddress string, price models.Wei) error {
+	var item models.Item
+	err := os.db.Where("collection_address = ? AND token_id = ?", collectionAddress, tokenID).First(&item).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("NFT不存在: %s #%s", collectionAddress, tokenID)
+		}
+		return fmt.Errorf("查询NFT失败: %v", err)
+	}
+	if item.Owner == nil || !strings.EqualFold(*item.Owner, sellerAddress) {
+		return fmt.Errorf("只有NFT拥有者可以接受出价")
+	}
+
+	if !os.blockchainService.Capabilities().ChainRead {
+		return nil
+	}
+	reason, err := os.checkOrderFillable(models.OrderTypeListing, collectionAddress, tokenID, sellerAddress, price, 1)
+	if err != nil {
+		return fmt.Errorf("校验NFT链上状态失败: %v", err)
+	}
+	if reason != nil {
+		return fmt.Errorf("卖方无法转出该NFT: %s", invalidReasonText(*reason))
+	}
+	return nil
+}
+
+// invalidateSellerListings 把卖方在该token上的有效挂单标记为失效，返回受影响的订单主键
+func (os *OrderService) invalidateSellerListings(tx *gorm.DB, collectionAddress, tokenID, sellerAddress string, now int64) ([]uint64, error) {
+	var ids []uint64
+	err := tx.Model(&models.Order{}).
+		Where("collection_address = ? AND token_id = ? AND order_type = ? AND order_status = ? AND invalid = ? AND LOWER(maker) = ?",
+			collectionAddress, tokenID, models.OrderTypeListing, models.OrderStatusActive, false, strings.ToLower(sellerAddress)).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	reason := models.OrderInvalidReasonNotOwner
+	err = tx.Model(&models.Order{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"invalid":        true,
+		"invalid_reason": reason,
+		"update_time":    now,
+	}).Error
+	return ids, err
+}
+
+// createSellActivity 创建出售活动记录，maker为出售NFT的卖方，taker为出价方，价格为成交数量的总价
+func (os *OrderService) createSellActivity(tx *gorm.DB, order *models.Order, tokenID, seller string, quantity, now int64) error {
+	activity := &models.Activity{
+		ActivityType:      models.ActivityTypeSell,
+		Maker:             &seller,
+		Taker:             &order.Maker,
+		CollectionAddress: &order.CollectionAddress,
+		TokenID:           &tokenID,
+		Price:             totalPrice(order.Price, quantity),
+		Amount:            quantity,
+		BlockNumber:       0, // 链上确认后会更新
+		EventTime:         &now,
+		CreateTime:        &now,
+		UpdateTime:        &now,
+		CurrencyAddress:   "1", // ETH
+	}
+	return tx.Create(activity).Error
+}
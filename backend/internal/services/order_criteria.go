@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"nft-market/internal/models"
+	"time"
+)
+
+// validateOrderCriteria 特征条件只能用于集合出价
+func validateOrderCriteria(req *models.CreateOrderRequest) error {
+	if len(req.Criteria) == 0 {
+		return nil
+	}
+	if req.OrderType != models.OrderTypeCollectionBid {
+		return fmt.Errorf("只有集合出价可以指定特征条件")
+	}
+	return nil
+}
+
+// newOrderCriteria 由请求中的特征生成订单条件，随订单一起保存
+func newOrderCriteria(traits []models.Trait, now int64) []models.OrderCriteria {
+	traits = uniqueTraits(traits)
+	if len(traits) == 0 {
+		return nil
+	}
+	criteria := make([]models.OrderCriteria, 0, len(traits))
+	for _, trait := range traits {
+		criteria = append(criteria, models.OrderCriteria{
+			TraitType:  trait.TraitType,
+			Value:      trait.Value,
+			CreateTime: &now,
+		})
+	}
+	return criteria
+}
+
+// criteriaSatisfied 物品特征是否满足订单的全部特征条件
+func criteriaSatisfied(criteria []models.OrderCriteria, attributes []models.ItemAttribute) bool {
+	traits := make(map[models.Trait]bool, len(attributes))
+	for _, attribute := range attributes {
+		traits[models.Trait{TraitType: attribute.TraitType, Value: attribute.Value}] = true
+	}
+	for _, criterion := range criteria {
+		if !traits[models.Trait{TraitType: criterion.TraitType, Value: criterion.Value}] {
+			return false
+		}
+	}
+	return true
+}
+
+// validateBidCriteria 接受集合出价时校验所选token满足出价的特征条件
+func (os *OrderService) validateBidCriteria(order *models.Order, tokenID string) error {
+	var criteria []models.OrderCriteria
+	if err := os.db.Where("order_id = ?", order.ID).Find(&criteria).Error; err != nil {
+		return fmt.Errorf("查询出价特征条件失败: %v", err)
+	}
+	if len(criteria) == 0 {
+		return nil
+	}
+
+	var attributes []models.ItemAttribute
+	err := os.db.Where("collection_address = ? AND token_id = ?", order.CollectionAddress, tokenID).Find(&attributes).Error
+	if err != nil {
+		return fmt.Errorf("查询物品特征失败: %v", err)
+	}
+	if !criteriaSatisfied(criteria, attributes) {
+		return fmt.Errorf("token %s 不满足出价的特征条件", tokenID)
+	}
+	return nil
+}
+
+// GetQualifyingBids 获取指定NFT可以接受的有效出价，包括该token的出价和物品出价，以及特征条件满足的集合出价，按价格从高到低排列
+func (os *OrderService) GetQualifyingBids(collectionAddress, tokenID string) ([]models.Order, error) {
+	var orders []models.Order
+	err := os.db.Preload("Criteria").
+		Where("collection_address = ? AND order_status = ? AND invalid = ? AND (expire_time IS NULL OR expire_time > ?)",
+			collectionAddress, models.OrderStatusActive, false, time.Now().Unix()).
+		Where("(order_type IN ? AND token_id = ?) OR order_type = ?",
+			[]models.OrderType{models.OrderTypeOffer, models.OrderTypeItemBid}, tokenID, models.OrderTypeCollectionBid).
+		Order("price DESC, create_time ASC").
+		Find(&orders).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询出价失败: %v", err)
+	}
+
+	var attributes []models.ItemAttribute
+	err = os.db.Where("collection_address = ? AND token_id = ?", collectionAddress, tokenID).Find(&attributes).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询物品特征失败: %v", err)
+	}
+
+	result := make([]models.Order, 0, len(orders))
+	for _, order := range orders {
+		if order.OrderType == models.OrderTypeCollectionBid && !criteriaSatisfied(order.Criteria, attributes) {
+			continue
+		}
+		result = append(result, order)
+	}
+	return result, nil
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// orderHistoryMaxDepth 订单修改历史的最大查询深度，防止异常数据形成环
+const orderHistoryMaxDepth = 100
+
+// ErrWalletOrder 订单由用户钱包在链上创建，热钱包无法代为修改
+var ErrWalletOrder = errors.New("订单由用户钱包在链上创建，请通过 /orders/:id/tx/edit 构建修改交易")
+
+// EditOrder 修改挂单或出价的价格和过期时间
+// 与合约editOrder一致：原订单标记为已取消并关联到以新价格创建的新订单，热钱包可用时由发件箱提交链上修改
+func (os *OrderService) EditOrder(id uint64, userAddress string, req *models.EditOrderRequest) (*models.Order, error) {
+	if err := validateEditOrderRequest(req); err != nil {
+		return nil, err
+	}
+
+	var order models.Order
+	if err := os.db.First(&order, id).Error; err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(order.Maker, userAddress) {
+		return nil, fmt.Errorf("无权限修改此订单")
+	}
+	if order.OrderStatus != models.OrderStatusActive {
+		return nil, fmt.Errorf("订单状态不允许修改")
+	}
+	if order.OrderType != models.OrderTypeListing && order.OrderType != models.OrderTypeOffer {
+		return nil, fmt.Errorf("只支持修改挂单和出价")
+	}
+	if order.Signature != nil {
+		return nil, ErrSignedOrder
+	}
+
+	onChain := os.chainWritable()
+	if order.BlockHash != nil || order.ChainStatus != models.OrderChainStatusNone {
+		custodial, err := os.isCustodialOrder(order.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !custodial {
+			return nil, ErrWalletOrder
+		}
+		if !onChain {
+			return nil, blockchain.ErrSignerNotConfigured
+		}
+		if order.BlockHash == nil {
+			return nil, fmt.Errorf("订单尚未在链上确认，请稍后再修改")
+		}
+	}
+
+	// 出价金额变化后重新校验出价方余额
+	if err := os.validateCreateOrderOnChain(&models.CreateOrderRequest{
+		CollectionAddress: order.CollectionAddress,
+		TokenID:           order.TokenID,
+		OrderType:         order.OrderType,
+		Price:             req.Price,
+		QuantityRemaining: order.QuantityRemaining,
+	}, order.Maker); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	replacement := newReplacementOrder(&order, req, fmt.Sprintf("0x%x", time.Now().UnixNano()), models.OrderStatusActive, now)
+	if onChain && order.BlockHash != nil {
+		replacement.ChainStatus = models.OrderChainStatusPending
+	}
+
+	err := os.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(replacement).Error; err != nil {
+			return fmt.Errorf("保存新订单失败: %v", err)
+		}
+
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND order_status = ?", order.ID, models.OrderStatusActive).
+			Updates(map[string]interface{}{
+				"order_status":   models.OrderStatusCancelled,
+				"replaced_by_id": replacement.ID,
+				"update_time":    now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("更新原订单状态失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("订单状态已变化，请刷新后重试")
+		}
+
+		if order.OrderType == models.OrderTypeListing {
+			if err := blockchain.RefreshItemListPrice(tx, order.CollectionAddress, order.TokenID); err != nil {
+				return err
+			}
+		}
+
+		// 链上修改记录在新订单上，发件箱提交后据交易hash关联链上新订单
+		if replacement.ChainStatus == models.OrderChainStatusPending {
+			value := models.Wei{}
+			if order.OrderType == models.OrderTypeOffer {
+				value = replacement.Price
+			}
+			return enqueueChainAction(tx, replacement.ID, models.OutboxActionEditOrder, value, now)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	os.matchingService.Remove(order.ID)
+	os.matchingService.Submit(replacement)
+
+	logger.Info("订单修改成功", logrus.Fields{
+		"order_id":       order.ID,
+		"replacement_id": replacement.ID,
+		"price_wei":      replacement.Price.String(),
+		"expire_time":    req.ExpireTime,
+	})
+
+	return replacement, nil
+}
+
+// GetOrderHistory 获取订单的修改历史，按修改顺序返回从最初订单到最新订单的完整链条
+// 向后只沿replaced_by_id查找，未上链的修改草稿（待上链订单）不会出现在其他订单的历史中
+func (os *OrderService) GetOrderHistory(id uint64) ([]models.Order, error) {
+	var order models.Order
+	if err := os.db.First(&order, id).Error; err != nil {
+		return nil, err
+	}
+
+	// 先回溯到最初的订单
+	history := []models.Order{order}
+	for previousID, i := order.ReplacesID, 0; previousID != nil && i < orderHistoryMaxDepth; i++ {
+		var previous models.Order
+		if err := os.db.First(&previous, *previousID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return nil, fmt.Errorf("查询被替换的订单失败: %v", err)
+		}
+		history = append([]models.Order{previous}, history...)
+		previousID = previous.ReplacesID
+	}
+
+	// 再沿已生效的替换关系向后查找
+	for nextID, i := order.ReplacedByID, 0; nextID != nil && i < orderHistoryMaxDepth; i++ {
+		var next models.Order
+		if err := os.db.First(&next, *nextID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return nil, fmt.Errorf("查询替换订单失败: %v", err)
+		}
+		history = append(history, next)
+		nextID = next.ReplacedByID
+	}
+
+	return history, nil
+}
+
+// isCustodialOrder 订单是否由热钱包在链上创建，只有这类订单可由后端代为修改
+func (os *OrderService) isCustodialOrder(id uint64) (bool, error) {
+	var count int64
+	err := os.db.Model(&models.ChainOutbox{}).
+		Where("order_id = ? AND action IN ?", id, []models.OutboxAction{models.OutboxActionCreateOrder, models.OutboxActionEditOrder}).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("查询链上操作记录失败: %v", err)
+	}
+	return count > 0, nil
+}
+
+// newReplacementOrder 以新价格和过期时间复制原订单，生成替换原订单的新订单
+func newReplacementOrder(order *models.Order, req *models.EditOrderRequest, orderID string, status models.OrderStatus, now int64) *models.Order {
+	expireTime := req.ExpireTime
+	return &models.Order{
+		OrderID:           orderID,
+		OrderType:         order.OrderType,
+		OrderStatus:       status,
+		CollectionAddress: order.CollectionAddress,
+		TokenID:           order.TokenID,
+		Price:             req.Price,
+		Maker:             order.Maker,
+		QuantityRemaining: order.QuantityRemaining,
+		Size:              order.Size,
+		CurrencyAddress:   order.CurrencyAddress,
+		ReplacesID:        &order.ID,
+		EventTime:         &now,
+		ExpireTime:        &expireTime,
+		CreateTime:        &now,
+		UpdateTime:        &now,
+	}
+}
+
+// validateEditOrderRequest 验证修改订单请求
+func validateEditOrderRequest(req *models.EditOrderRequest) error {
+	if req.Price.Sign() <= 0 {
+		return fmt.Errorf("订单必须指定有效价格")
+	}
+	if req.ExpireTime <= time.Now().Unix() {
+		return fmt.Errorf("过期时间必须在未来")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrERC1155OffChain 市场合约的订单不带数量，ERC-1155订单只在数据库中创建和成交
+var ErrERC1155OffChain = errors.New("ERC-1155订单不支持链上创建，请使用 POST /orders 创建链下订单")
+
+// tokenStandard 集合的代币标准，未记录时通过ERC-165检测，链上不可读时按ERC-721处理
+func (os *OrderService) tokenStandard(collectionAddress string) models.TokenStandard {
+	var contract *blockchain.NFTMarketplaceContract
+	if os.blockchainService.Capabilities().ChainRead {
+		contract = os.blockchainService.contract
+	}
+	return blockchain.ResolveTokenStandard(os.db, contract, collectionAddress)
+}
+
+// normalizeOrderQuantity 校验订单数量并补全默认值：未指定时为1，ERC-721订单只能为1
+func normalizeOrderQuantity(req *models.CreateOrderRequest, standard models.TokenStandard) error {
+	if req.QuantityRemaining < 0 || req.Size < 0 {
+		return fmt.Errorf("订单数量不能为负数")
+	}
+	if req.QuantityRemaining == 0 {
+		req.QuantityRemaining = 1
+	}
+	if req.Size == 0 {
+		req.Size = req.QuantityRemaining
+	}
+	if req.QuantityRemaining > req.Size {
+		return fmt.Errorf("剩余数量不能超过订单数量")
+	}
+	if standard != models.TokenStandardERC1155 && req.Size > 1 {
+		return fmt.Errorf("ERC-721订单数量只能为1")
+	}
+	return nil
+}
+
+// fillQuantity 确定本次成交数量：未指定时成交全部剩余数量，部分成交只支持ERC-1155
+func fillQuantity(order *models.Order, requested int64, standard models.TokenStandard) (int64, error) {
+	remaining := order.QuantityRemaining
+	if remaining < 1 {
+		remaining = 1
+	}
+	if requested < 0 {
+		return 0, fmt.Errorf("成交数量不能为负数")
+	}
+	if requested == 0 {
+		return remaining, nil
+	}
+	if requested > remaining {
+		return 0, fmt.Errorf("成交数量超过订单剩余数量: %d", remaining)
+	}
+	if requested < remaining && standard != models.TokenStandardERC1155 {
+		return 0, fmt.Errorf("只有ERC-1155订单支持部分成交")
+	}
+	return requested, nil
+}
+
+// fillOrder 按成交数量扣减订单剩余数量，剩余为0时订单变为已成交；返回订单是否已全部成交
+// 以读取时的剩余数量为条件更新，并发成交时后到者失败
+func fillOrder(tx *gorm.DB, order *models.Order, quantity int64, taker string, updates map[string]interface{}) (bool, error) {
+	remaining := order.QuantityRemaining - quantity
+	if remaining < 0 {
+		remaining = 0
+	}
+	updates["quantity_remaining"] = remaining
+	updates["taker"] = taker
+	if remaining == 0 {
+		updates["order_status"] = models.OrderStatusFilled
+	}
+
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND order_status = ? AND quantity_remaining = ?", order.ID, models.OrderStatusActive, order.QuantityRemaining).
+		Updates(updates)
+	if result.Error != nil {
+		return false, fmt.Errorf("更新订单状态失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, fmt.Errorf("订单状态已变化，请刷新后重试")
+	}
+	return remaining == 0, nil
+}
+
+// totalPrice 成交总价，订单价格为单价
+func totalPrice(price models.Wei, quantity int64) models.Wei {
+	return models.NewWei(new(big.Int).Mul(price.BigInt(), big.NewInt(quantity)))
+}
+
+// transferERC1155 在数据库中把ERC-1155 token从卖方转给买方，并按双方新的持有数量校验挂单
+func transferERC1155(tx *gorm.DB, collectionAddress, tokenID, from, to string, quantity int64) error {
+	if err := blockchain.AdjustItemBalance(tx, collectionAddress, tokenID, from, -quantity); err != nil {
+		return err
+	}
+	if err := blockchain.AdjustItemBalance(tx, collectionAddress, tokenID, to, quantity); err != nil {
+		return err
+	}
+	if err := blockchain.UpdateListingBalanceValidity(tx, collectionAddress, tokenID, from); err != nil {
+		return err
+	}
+	return blockchain.UpdateListingBalanceValidity(tx, collectionAddress, tokenID, to)
+}
+
+// validateERC1155Balance 校验卖方持有足够数量的ERC-1155 token：链上可读时查询链上持有数量和授权，否则使用已索引的持有数量
+func (os *OrderService) validateERC1155Balance(collectionAddress, tokenID, owner string, quantity int64) error {
+	if os.blockchainService.Capabilities().ChainRead {
+		reason, err := os.checkOrderFillable(models.OrderTypeListing, collectionAddress, tokenID, owner, models.Wei{}, quantity)
+		if err != nil {
+			return fmt.Errorf("校验NFT链上状态失败: %v", err)
+		}
+		if reason != nil {
+			return fmt.Errorf("卖方无法转出该NFT: %s", invalidReasonText(*reason))
+		}
+		return nil
+	}
+
+	balance, err := blockchain.GetItemBalance(os.db, collectionAddress, tokenID, owner)
+	if err != nil {
+		return err
+	}
+	if balance < quantity {
+		return fmt.Errorf("持有数量不足，持有: %d，需要: %d", balance, quantity)
+	}
+	return nil
+}
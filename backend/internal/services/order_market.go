@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// marketSideBuy 市价买入，与价格最低的卖单成交
+	marketSideBuy = "buy"
+	// marketSideSell 市价卖出，与出价最高的买单成交
+	marketSideSell = "sell"
+)
+
+// ErrNoMatchingOrder 没有可成交的对手订单
+var ErrNoMatchingOrder = errors.New("没有可成交的对手订单")
+
+// PrepareMarketBuy 报价并返回由买方签名的市价买入交易，交易发送的ETH为报价金额
+// 报价后最优卖单被成交或取消时，合约会选出价格更高的卖单并因支付不足而revert
+func (os *OrderService) PrepareMarketBuy(req *models.MarketOrderRequest, caller string) (*models.MarketQuote, *blockchain.UnsignedTransaction, error) {
+	quote, err := os.QuoteMarketOrder(marketSideBuy, req.CollectionAddress, req.TokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.LimitPrice.Sign() > 0 && quote.Price.Cmp(req.LimitPrice) > 0 {
+		return quote, nil, fmt.Errorf("最优卖单价格%s高于可接受的最高价格%s", quote.Price.String(), req.LimitPrice.String())
+	}
+	if quote.Order != nil && common.HexToAddress(quote.Order.Maker) == common.HexToAddress(caller) {
+		return quote, nil, fmt.Errorf("最优卖单是自己的挂单")
+	}
+
+	unsignedTx, err := os.blockchainService.contract.BuildCreateMarketBuyOrderTx(common.HexToAddress(caller), req.CollectionAddress, req.TokenID, quote.Price.BigInt())
+	if err != nil {
+		return quote, nil, err
+	}
+	return quote, unsignedTx, nil
+}
+
+// PrepareMarketSell 报价并返回由NFT持有者签名的市价卖出交易
+// 合约要求卖方已通过setApprovalForAll授权市场合约，未授权时gas估算失败
+func (os *OrderService) PrepareMarketSell(req *models.MarketOrderRequest, caller string) (*models.MarketQuote, *blockchain.UnsignedTransaction, error) {
+	quote, err := os.QuoteMarketOrder(marketSideSell, req.CollectionAddress, req.TokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.LimitPrice.Sign() > 0 && quote.Price.Cmp(req.LimitPrice) < 0 {
+		return quote, nil, fmt.Errorf("最优买单价格%s低于可接受的最低价格%s", quote.Price.String(), req.LimitPrice.String())
+	}
+
+	unsignedTx, err := os.blockchainService.contract.BuildCreateMarketSellOrderTx(common.HexToAddress(caller), req.CollectionAddress, req.TokenID)
+	if err != nil {
+		return quote, nil, err
+	}
+	return quote, unsignedTx, nil
+}
+
+// QuoteMarketOrder 查询市价单报价：以合约findBestSellOrder/findBestBuyOrder选出的订单为准，
+// 同时返回数据库中的最优对手订单，两者不一致说明事件尚未同步
+func (os *OrderService) QuoteMarketOrder(side, collectionAddress, tokenID string) (*models.MarketQuote, error) {
+	if !os.blockchainService.Capabilities().ChainRead {
+		return nil, ErrBlockchainUnavailable
+	}
+	if !common.IsHexAddress(collectionAddress) {
+		return nil, fmt.Errorf("无效的集合地址: %s", collectionAddress)
+	}
+	collectionAddress = common.HexToAddress(collectionAddress).Hex()
+
+	contract := os.blockchainService.contract
+	var counterType models.OrderType
+	var priceOrder string
+	var findBest func(nftContract, tokenID string) (*big.Int, error)
+	switch side {
+	case marketSideBuy:
+		counterType, priceOrder, findBest = models.OrderTypeListing, "price ASC", contract.FindBestSellOrder
+	case marketSideSell:
+		counterType, priceOrder, findBest = models.OrderTypeOffer, "price DESC", contract.FindBestBuyOrder
+	default:
+		return nil, fmt.Errorf("不支持的市价单方向: %s", side)
+	}
+
+	quote := &models.MarketQuote{Side: side}
+
+	var best models.Order
+	err := os.db.Where("collection_address = ? AND token_id = ? AND order_type = ? AND order_status = ? AND invalid = ? AND block_hash IS NOT NULL AND (expire_time IS NULL OR expire_time > ?)",
+		collectionAddress, tokenID, counterType, models.OrderStatusActive, false, time.Now().Unix()).
+		Order(priceOrder).Order("id ASC").
+		First(&best).Error
+	switch {
+	case err == nil:
+		quote.BestDBOrder = &best
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("查询最优订单失败: %v", err)
+	}
+
+	chainOrderID, err := findBest(collectionAddress, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if chainOrderID.Sign() == 0 {
+		return quote, ErrNoMatchingOrder
+	}
+
+	chainOrder, err := contract.GetOrder(chainOrderID.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	quote.ChainOrderID = fmt.Sprintf("0x%x", chainOrderID)
+	quote.Price = models.NewWei(chainOrder.Price)
+
+	var order models.Order
+	err = os.db.Where("order_id = ?", quote.ChainOrderID).First(&order).Error
+	switch {
+	case err == nil:
+		quote.Order = &order
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("查询链上订单对应记录失败: %v", err)
+	}
+	quote.InSync = quote.BestDBOrder != nil && quote.BestDBOrder.OrderID == quote.ChainOrderID
+
+	if !quote.InSync {
+		logger.Warn("数据库与链上最优订单不一致", logrus.Fields{
+			"side":           side,
+			"collection":     collectionAddress,
+			"token_id":       tokenID,
+			"chain_order_id": quote.ChainOrderID,
+		})
+	}
+	return quote, nil
+}
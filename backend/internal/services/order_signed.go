@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ErrSignedOrder 链下签名订单没有对应的链上订单，不支持修改
+var ErrSignedOrder = errors.New("链下签名订单不支持修改，请取消后重新签名")
+
+// ErrSignedOrdersDisabled 未开启链下签名订单
+var ErrSignedOrdersDisabled = errors.New("链下签名订单未开启")
+
+// SetSignedOrdersEnabled 开启链下签名订单
+// 市场合约不验证签名，签名订单只在数据库中撮合成交，NFT和ETH不会在链上转移；取消订单也只在数据库中生效，无法撤销已发出的签名
+func (os *OrderService) SetSignedOrdersEnabled(enabled bool) {
+	os.signedOrders = enabled
+}
+
+// SignedOrderTypedData 返回待maker钱包通过eth_signTypedData_v4签名的订单类型化数据及其摘要
+func (os *OrderService) SignedOrderTypedData(req *models.SignedOrderRequest, maker string) (apitypes.TypedData, common.Hash, error) {
+	if !os.signedOrders {
+		return apitypes.TypedData{}, common.Hash{}, ErrSignedOrdersDisabled
+	}
+	if err := validateSignedOrderRequest(req); err != nil {
+		return apitypes.TypedData{}, common.Hash{}, err
+	}
+	return os.orderTypedData(req.CollectionAddress, req.TokenID, req.OrderType, req.Price, req.ExpireTime, req.Salt, maker)
+}
+
+// CreateSignedOrder 校验maker的EIP-712签名并保存链下签名订单，挂单和出价都不需要支付gas，只在数据库中撮合成交
+// 订单以EIP-712摘要作为订单ID，同一签名重复提交时返回错误
+func (os *OrderService) CreateSignedOrder(req *models.SignedOrderRequest, maker string) (*models.Order, error) {
+	if !os.signedOrders {
+		return nil, ErrSignedOrdersDisabled
+	}
+	if err := validateSignedOrderRequest(req); err != nil {
+		return nil, err
+	}
+	if req.Signature == "" {
+		return nil, fmt.Errorf("订单签名不能为空")
+	}
+
+	typedData, hash, err := os.orderTypedData(req.CollectionAddress, req.TokenID, req.OrderType, req.Price, req.ExpireTime, req.Salt, maker)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := blockchain.RecoverOrderSigner(typedData, req.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if signer != common.HexToAddress(maker) {
+		return nil, fmt.Errorf("签名者%s与订单创建者不一致", signer.Hex())
+	}
+
+	createReq := &models.CreateOrderRequest{
+		CollectionAddress: common.HexToAddress(req.CollectionAddress).Hex(),
+		TokenID:           req.TokenID,
+		OrderType:         req.OrderType,
+		Price:             req.Price,
+		ExpireTime:        &req.ExpireTime,
+	}
+	if err := os.validateCreateOrderOnChain(createReq, maker); err != nil {
+		return nil, err
+	}
+
+	orderID := hash.Hex()
+	var count int64
+	if err := os.db.Model(&models.Order{}).Where("order_id = ?", orderID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("查询订单失败: %v", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("该签名订单已存在")
+	}
+
+	now := time.Now().Unix()
+	signature := req.Signature
+	salt := req.Salt
+	makerHex := common.HexToAddress(maker).Hex()
+	order := &models.Order{
+		OrderID:           orderID,
+		OrderType:         req.OrderType,
+		OrderStatus:       models.OrderStatusActive,
+		CollectionAddress: createReq.CollectionAddress,
+		TokenID:           req.TokenID,
+		Price:             req.Price,
+		Maker:             makerHex,
+		QuantityRemaining: 1,
+		Size:              1,
+		Salt:              &salt,
+		Signature:         &signature,
+		CurrencyAddress:   "0x0000000000000000000000000000000000000000",
+		EventTime:         &now,
+		ExpireTime:        &req.ExpireTime,
+		CreateTime:        &now,
+		UpdateTime:        &now,
+	}
+
+	err = os.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(order).Error; err != nil {
+			return fmt.Errorf("保存订单到数据库失败: %v", err)
+		}
+		if err := os.createOrUpdateItem(tx, createReq, makerHex, os.tokenStandard(createReq.CollectionAddress), now); err != nil {
+			return fmt.Errorf("创建Item记录失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	os.matchingService.Submit(order)
+
+	logger.Info("链下签名订单创建成功", logrus.Fields{
+		"id":         order.ID,
+		"order_hash": orderID,
+		"maker":      makerHex,
+		"order_type": order.OrderType,
+		"price_wei":  order.Price.String(),
+	})
+	return order, nil
+}
+
+// GetSignedOrder 获取有效的链下签名订单及其类型化数据，供对手方校验maker的签名
+// 合约不验证签名，签名只证明maker的下单意图，不能用于链上结算
+func (os *OrderService) GetSignedOrder(id uint64) (*models.Order, apitypes.TypedData, error) {
+	if !os.signedOrders {
+		return nil, apitypes.TypedData{}, ErrSignedOrdersDisabled
+	}
+	var order models.Order
+	if err := os.db.First(&order, id).Error; err != nil {
+		return nil, apitypes.TypedData{}, err
+	}
+	if order.Signature == nil || order.Salt == nil || order.ExpireTime == nil {
+		return nil, apitypes.TypedData{}, fmt.Errorf("订单不是链下签名订单")
+	}
+	if order.OrderStatus != models.OrderStatusActive {
+		return nil, apitypes.TypedData{}, fmt.Errorf("订单状态不允许成交")
+	}
+	if order.Invalid {
+		return nil, apitypes.TypedData{}, invalidOrderError(&order)
+	}
+
+	typedData, _, err := os.orderTypedData(order.CollectionAddress, order.TokenID, order.OrderType, order.Price, *order.ExpireTime, *order.Salt, order.Maker)
+	if err != nil {
+		return nil, apitypes.TypedData{}, err
+	}
+	return &order, typedData, nil
+}
+
+// orderTypedData 构建订单的EIP-712类型化数据，域绑定当前链ID和市场合约地址
+func (os *OrderService) orderTypedData(collectionAddress, tokenID string, orderType models.OrderType, price models.Wei, expireTime, salt int64, maker string) (apitypes.TypedData, common.Hash, error) {
+	if !os.blockchainService.Capabilities().ChainRead {
+		return apitypes.TypedData{}, common.Hash{}, ErrBlockchainUnavailable
+	}
+	if !common.IsHexAddress(collectionAddress) {
+		return apitypes.TypedData{}, common.Hash{}, fmt.Errorf("无效的集合地址: %s", collectionAddress)
+	}
+	tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		return apitypes.TypedData{}, common.Hash{}, fmt.Errorf("无效的Token ID: %s", tokenID)
+	}
+
+	contract := os.blockchainService.contract
+	typedData := blockchain.OrderTypedData(contract.ChainID(), contract.ContractAddress(), &blockchain.SignedOrder{
+		Maker:       common.HexToAddress(maker),
+		NftContract: common.HexToAddress(collectionAddress),
+		TokenId:     tokenIDBig,
+		Price:       price.BigInt(),
+		Expiration:  expireTime,
+		OrderType:   uint8(orderType - 1), // 合约从0开始，模型从1开始
+		Salt:        big.NewInt(salt),
+	})
+	hash, err := blockchain.HashOrder(typedData)
+	if err != nil {
+		return apitypes.TypedData{}, common.Hash{}, err
+	}
+	return typedData, hash, nil
+}
+
+// validateSignedOrderRequest 验证链下签名订单请求
+func validateSignedOrderRequest(req *models.SignedOrderRequest) error {
+	if req.OrderType != models.OrderTypeListing && req.OrderType != models.OrderTypeOffer {
+		return fmt.Errorf("链下签名只支持挂单和出价")
+	}
+	if req.Price.Sign() <= 0 {
+		return fmt.Errorf("订单必须指定有效价格")
+	}
+	if req.ExpireTime <= time.Now().Unix() {
+		return fmt.Errorf("过期时间必须在未来")
+	}
+	if req.Salt <= 0 {
+		return fmt.Errorf("随机数必须为正整数")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// PrepareCreateOrder 创建待上链订单并返回由用户钱包签名的交易
+// 订单状态为待上链，事件监听器收到对应的OrderCreated事件后才会变为有效
+func (os *OrderService) PrepareCreateOrder(req *models.CreateOrderRequest, maker string) (*models.Order, *blockchain.UnsignedTransaction, error) {
+	if os.blockchainService == nil {
+		return nil, nil, ErrBlockchainUnavailable
+	}
+	if err := os.validateCreateOrderRequest(req); err != nil {
+		return nil, nil, err
+	}
+	if !common.IsHexAddress(req.CollectionAddress) {
+		return nil, nil, fmt.Errorf("无效的集合地址: %s", req.CollectionAddress)
+	}
+	if os.tokenStandard(req.CollectionAddress) == models.TokenStandardERC1155 {
+		return nil, nil, ErrERC1155OffChain
+	}
+	if err := os.validateCreateOrderOnChain(req, maker); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	expireTime := req.ExpireTime
+	if expireTime == nil {
+		// 与服务端下单保持一致的默认有效期
+		defaultExpire := now.Add(30 * 24 * time.Hour).Unix()
+		if req.OrderType == models.OrderTypeOffer {
+			defaultExpire = now.Add(7 * 24 * time.Hour).Unix()
+		}
+		expireTime = &defaultExpire
+	}
+
+	nowUnix := now.Unix()
+	order := &models.Order{
+		OrderID:           fmt.Sprintf("pending-%x", now.UnixNano()),
+		OrderType:         req.OrderType,
+		OrderStatus:       models.OrderStatusPending,
+		CollectionAddress: common.HexToAddress(req.CollectionAddress).Hex(),
+		TokenID:           req.TokenID,
+		Price:             req.Price,
+		Maker:             common.HexToAddress(maker).Hex(),
+		QuantityRemaining: 1,
+		Size:              1,
+		CurrencyAddress:   "0x0000000000000000000000000000000000000000",
+		EventTime:         &nowUnix,
+		ExpireTime:        expireTime,
+		CreateTime:        &nowUnix,
+		UpdateTime:        &nowUnix,
+	}
+
+	// 先构建交易，gas估算失败（如未授权、余额不足）时不落库
+	unsignedTx, err := os.blockchainService.BuildCreateOrderTx(order, common.HexToAddress(maker))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.db.Create(order).Error; err != nil {
+		return nil, nil, fmt.Errorf("保存订单到数据库失败: %v", err)
+	}
+
+	logger.Info("待上链订单创建成功", logrus.Fields{
+		"id":         order.ID,
+		"maker":      order.Maker,
+		"order_type": order.OrderType,
+		"price_wei":  order.Price.String(),
+	})
+
+	return order, unsignedTx, nil
+}
+
+// PrepareCancelOrder 返回由订单创建者签名的取消订单交易，订单状态在监听到链上事件后更新
+func (os *OrderService) PrepareCancelOrder(id uint64, caller string) (*blockchain.UnsignedTransaction, error) {
+	order, err := os.getActiveChainOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if common.HexToAddress(order.Maker) != common.HexToAddress(caller) {
+		return nil, fmt.Errorf("只有订单创建者可以取消订单")
+	}
+
+	return os.blockchainService.BuildCancelOrderTx(order, common.HexToAddress(caller))
+}
+
+// PrepareExecuteOrder 返回由成交方签名的执行订单交易
+func (os *OrderService) PrepareExecuteOrder(id uint64, caller string) (*blockchain.UnsignedTransaction, error) {
+	order, err := os.getActiveChainOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if common.HexToAddress(order.Maker) == common.HexToAddress(caller) {
+		return nil, fmt.Errorf("不能执行自己的订单")
+	}
+	if order.Invalid {
+		return nil, invalidOrderError(order)
+	}
+	if err := os.validateOrderFillable(order); err != nil {
+		return nil, err
+	}
+
+	return os.blockchainService.BuildExecuteOrderTx(order, common.HexToAddress(caller))
+}
+
+// PrepareEditOrder 创建替换原订单的待上链新订单，返回由订单创建者签名的修改订单交易
+// 事件监听器收到editOrder产生的OrderCreated事件后激活新订单并关联原订单
+func (os *OrderService) PrepareEditOrder(id uint64, caller string, req *models.EditOrderRequest) (*models.Order, *blockchain.UnsignedTransaction, error) {
+	if err := validateEditOrderRequest(req); err != nil {
+		return nil, nil, err
+	}
+
+	order, err := os.getActiveChainOrder(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if common.HexToAddress(order.Maker) != common.HexToAddress(caller) {
+		return nil, nil, fmt.Errorf("只有订单创建者可以修改订单")
+	}
+
+	unsignedTx, err := os.blockchainService.BuildEditOrderTx(order, common.HexToAddress(caller), req.Price, req.ExpireTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	replacement := newReplacementOrder(order, req, fmt.Sprintf("pending-%x", now.UnixNano()), models.OrderStatusPending, now.Unix())
+	if err := os.db.Create(replacement).Error; err != nil {
+		return nil, nil, fmt.Errorf("保存订单到数据库失败: %v", err)
+	}
+
+	return replacement, unsignedTx, nil
+}
+
+// getActiveChainOrder 获取已上链且有效的订单
+func (os *OrderService) getActiveChainOrder(id uint64) (*models.Order, error) {
+	if os.blockchainService == nil {
+		return nil, ErrBlockchainUnavailable
+	}
+
+	var order models.Order
+	if err := os.db.First(&order, id).Error; err != nil {
+		return nil, err
+	}
+
+	switch order.OrderStatus {
+	case models.OrderStatusActive:
+		if order.BlockHash == nil {
+			return nil, fmt.Errorf("订单尚未在链上确认")
+		}
+		return &order, nil
+	case models.OrderStatusPending:
+		return nil, fmt.Errorf("订单尚未在链上确认")
+	default:
+		return nil, fmt.Errorf("订单状态不允许此操作")
+	}
+}
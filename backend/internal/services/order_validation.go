@@ -0,0 +1,300 @@
+package services
+
+import (
+	"fmt"
+	"math/big"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// orderRevalidateBatchSize 每批重新校验的订单数量
+	orderRevalidateBatchSize = 100
+)
+
+// checkOrderFillable 通过eth_call校验订单在链上能否成交，可成交时返回nil
+// 挂单校验挂单方持有该NFT（ERC-1155为持有数量不低于剩余数量）并已授权市场合约，出价校验出价方ETH余额不低于单价乘以数量
+func (os *OrderService) checkOrderFillable(orderType models.OrderType, collectionAddress, tokenID, maker string, price models.Wei, quantity int64) (*models.OrderInvalidReason, error) {
+	contract := os.blockchainService.contract
+	makerAddress := common.HexToAddress(maker)
+	invalid := func(reason models.OrderInvalidReason) (*models.OrderInvalidReason, error) {
+		return &reason, nil
+	}
+	if quantity < 1 {
+		quantity = 1
+	}
+
+	if orderType == models.OrderTypeListing {
+		tokenIDBig, ok := new(big.Int).SetString(tokenID, 10)
+		if !ok {
+			return nil, fmt.Errorf("无效的Token ID: %s", tokenID)
+		}
+		nftContract := common.HexToAddress(collectionAddress)
+
+		if os.tokenStandard(collectionAddress) == models.TokenStandardERC1155 {
+			balance, err := contract.BalanceOf1155(nftContract, makerAddress, tokenIDBig)
+			if err != nil {
+				return nil, fmt.Errorf("查询NFT持有数量失败: %v", err)
+			}
+			if balance.Cmp(big.NewInt(quantity)) < 0 {
+				return invalid(models.OrderInvalidReasonNotOwner)
+			}
+			approved, err := contract.IsApprovedForAll1155(nftContract, makerAddress)
+			if err != nil {
+				return nil, fmt.Errorf("查询NFT授权失败: %v", err)
+			}
+			if !approved {
+				return invalid(models.OrderInvalidReasonNotApproved)
+			}
+			return nil, nil
+		}
+
+		owner, err := contract.OwnerOf(nftContract, tokenIDBig)
+		if err != nil {
+			return nil, fmt.Errorf("查询NFT拥有者失败: %v", err)
+		}
+		if owner != makerAddress {
+			return invalid(models.OrderInvalidReasonNotOwner)
+		}
+
+		approved, err := contract.IsApprovedForMarketplace(nftContract, makerAddress, tokenIDBig)
+		if err != nil {
+			return nil, fmt.Errorf("查询NFT授权失败: %v", err)
+		}
+		if !approved {
+			return invalid(models.OrderInvalidReasonNotApproved)
+		}
+		return nil, nil
+	}
+
+	balance, err := contract.BalanceAt(makerAddress)
+	if err != nil {
+		return nil, err
+	}
+	total := new(big.Int).Mul(price.BigInt(), big.NewInt(quantity))
+	if balance.Cmp(total) < 0 {
+		return invalid(models.OrderInvalidReasonInsufficientBalance)
+	}
+	return nil, nil
+}
+
+// validateCreateOrderOnChain 创建订单前校验链上状态，未连接区块链时跳过
+func (os *OrderService) validateCreateOrderOnChain(req *models.CreateOrderRequest, maker string) error {
+	if !os.blockchainService.Capabilities().ChainRead {
+		return nil
+	}
+
+	reason, err := os.checkOrderFillable(req.OrderType, req.CollectionAddress, req.TokenID, maker, req.Price, req.QuantityRemaining)
+	if err != nil {
+		return fmt.Errorf("校验订单链上状态失败: %v", err)
+	}
+	if reason != nil {
+		return fmt.Errorf("订单无法成交: %s", invalidReasonText(*reason))
+	}
+	return nil
+}
+
+// validateOrderFillable 成交前校验订单链上状态，无法成交时把订单标记为失效
+func (os *OrderService) validateOrderFillable(order *models.Order) error {
+	if !os.blockchainService.Capabilities().ChainRead {
+		return nil
+	}
+
+	reason, err := os.checkOrderFillable(order.OrderType, order.CollectionAddress, order.TokenID, order.Maker, order.Price, order.QuantityRemaining)
+	if err != nil {
+		return fmt.Errorf("校验订单链上状态失败: %v", err)
+	}
+	if reason == nil {
+		return nil
+	}
+
+	if _, err := os.setOrderValidity(os.db, order, reason); err != nil {
+		logger.Error("标记失效订单失败", err, logrus.Fields{
+			"order_id": order.ID,
+		})
+	}
+	order.Invalid = true
+	order.InvalidReason = reason
+	return invalidOrderError(order)
+}
+
+// setOrderValidity 按校验结果更新订单失效标记，reason为nil表示订单可成交；返回是否发生变化
+func (os *OrderService) setOrderValidity(db *gorm.DB, order *models.Order, reason *models.OrderInvalidReason) (bool, error) {
+	if reason == nil && !order.Invalid {
+		return false, nil
+	}
+	if reason != nil && order.Invalid && order.InvalidReason != nil && *order.InvalidReason == *reason {
+		return false, nil
+	}
+
+	updates := map[string]interface{}{
+		"invalid":        reason != nil,
+		"invalid_reason": reason,
+		"update_time":    time.Now().Unix(),
+	}
+	if err := db.Model(&models.Order{}).Where("id = ?", order.ID).Updates(updates).Error; err != nil {
+		return false, err
+	}
+	if order.OrderType == models.OrderTypeListing {
+		if err := blockchain.RefreshItemListPrice(db, order.CollectionAddress, order.TokenID); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// RevalidateOrders 重新校验所有有效和待上链订单的链上状态，标记无法成交的订单并恢复重新可成交的订单
+// 已上链的出价资金由合约托管，不再校验出价方余额
+func (os *OrderService) RevalidateOrders() error {
+	if !os.blockchainService.Capabilities().ChainRead {
+		return ErrBlockchainUnavailable
+	}
+
+	var lastID uint64
+	var checked, changed int
+	for {
+		var orders []models.Order
+		err := os.db.Where("id > ? AND order_status IN ?", lastID,
+			[]models.OrderStatus{models.OrderStatusActive, models.OrderStatusPending}).
+			Order("id ASC").
+			Limit(orderRevalidateBatchSize).
+			Find(&orders).Error
+		if err != nil {
+			return fmt.Errorf("查询待校验订单失败: %v", err)
+		}
+		if len(orders) == 0 {
+			break
+		}
+
+		for i := range orders {
+			order := &orders[i]
+			lastID = order.ID
+			if order.OrderType != models.OrderTypeListing && order.BlockHash != nil {
+				continue
+			}
+
+			reason, err := os.checkOrderFillable(order.OrderType, order.CollectionAddress, order.TokenID, order.Maker, order.Price, order.QuantityRemaining)
+			if err != nil {
+				logger.Warn("校验订单链上状态失败", logrus.Fields{
+					"order_id": order.ID,
+					"error":    err.Error(),
+				})
+				continue
+			}
+			checked++
+
+			updated, err := os.setOrderValidity(os.db, order, reason)
+			if err != nil {
+				logger.Error("更新订单有效性失败", err, logrus.Fields{
+					"order_id": order.ID,
+				})
+				continue
+			}
+			if updated {
+				changed++
+				fields := logrus.Fields{
+					"order_id": order.ID,
+					"invalid":  reason != nil,
+				}
+				if reason != nil {
+					fields["reason"] = string(*reason)
+				}
+				logger.Info("订单有效性已变化", fields)
+			}
+		}
+	}
+
+	logger.Info("订单链上状态校验完成", logrus.Fields{
+		"checked": checked,
+		"changed": changed,
+	})
+	return nil
+}
+
+// invalidReasonText 失效原因说明
+func invalidReasonText(reason models.OrderInvalidReason) string {
+	switch reason {
+	case models.OrderInvalidReasonNotOwner:
+		return "挂单方已不再持有该NFT"
+	case models.OrderInvalidReasonNotApproved:
+		return "挂单方未授权市场合约转移该NFT"
+	case models.OrderInvalidReasonInsufficientBalance:
+		return "出价方ETH余额不足"
+	default:
+		return string(reason)
+	}
+}
+
+// defaultRevalidateInterval 未配置或配置无效时订单校验的间隔
+const defaultRevalidateInterval = 5 * time.Minute
+
+// OrderValidator 定期重新校验订单链上状态的后台任务
+type OrderValidator struct {
+	orderService *OrderService
+	interval     time.Duration
+	stopChan     chan struct{}
+	isRunning    bool
+}
+
+// NewOrderValidator 创建订单校验后台任务
+func NewOrderValidator(orderService *OrderService, interval time.Duration) *OrderValidator {
+	return &OrderValidator{
+		orderService: orderService,
+		interval:     interval,
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// Start 启动订单校验任务
+func (v *OrderValidator) Start() error {
+	if !v.orderService.blockchainService.Capabilities().ChainRead {
+		return ErrBlockchainUnavailable
+	}
+	if v.isRunning {
+		return fmt.Errorf("订单校验任务已在运行")
+	}
+	v.isRunning = true
+	v.stopChan = make(chan struct{})
+	go v.run()
+	return nil
+}
+
+// Stop 停止订单校验任务
+func (v *OrderValidator) Stop() {
+	if !v.isRunning {
+		return
+	}
+	close(v.stopChan)
+	v.isRunning = false
+}
+
+// run 按间隔执行订单校验
+func (v *OrderValidator) run() {
+	ticker := time.NewTicker(v.revalidateInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := v.orderService.RevalidateOrders(); err != nil {
+				logger.Error("订单链上状态校验失败", err)
+			}
+		case <-v.stopChan:
+			return
+		}
+	}
+}
+
+// revalidateInterval 订单校验间隔，配置不大于0时使用默认值
+func (v *OrderValidator) revalidateInterval() time.Duration {
+	if v.interval > 0 {
+		return v.interval
+	}
+	return defaultRevalidateInterval
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// outboxPollInterval 发件箱轮询间隔
+	outboxPollInterval = 5 * time.Second
+	// outboxBatchSize 每次处理的最大条数
+	outboxBatchSize = 20
+	// outboxMaxAttempts 提交失败的最大尝试次数
+	outboxMaxAttempts = 10
+	// outboxInitialBackoff 首次重试等待时间，之后指数增长
+	outboxInitialBackoff = 10 * time.Second
+	// outboxMaxBackoff 重试等待时间上限
+	outboxMaxBackoff = 10 * time.Minute
+	// outboxClaimTTL 提交期间占用记录的时长，防止多个实例重复提交
+	outboxClaimTTL = 2 * time.Minute
+)
+
+// enqueueChainAction 在调用方的数据库事务中写入待执行的链上操作
+func enqueueChainAction(tx *gorm.DB, orderID uint64, action models.OutboxAction, value models.Wei, now int64) error {
+	entry := &models.ChainOutbox{
+		OrderID:         orderID,
+		Action:          action,
+		Value:           value,
+		Status:          models.OutboxStatusPending,
+		NextAttemptTime: now,
+		CreateTime:      &now,
+		UpdateTime:      &now,
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("写入链上操作发件箱失败: %v", err)
+	}
+	return nil
+}
+
+// chainOrderID 获取已被事件监听器确认的订单的链上ID
+func chainOrderID(order *models.Order) (*big.Int, error) {
+	if order.BlockHash == nil {
+		return nil, fmt.Errorf("订单尚未在链上确认")
+	}
+	return blockchain.ParseChainOrderID(order.OrderID)
+}
+
+// OutboxWorker 链上操作发件箱后台任务：提交交易、失败重试并等待确认
+type OutboxWorker struct {
+	db                *gorm.DB
+	blockchainService *EnhancedBlockchainService
+	stopChan          chan struct{}
+	isRunning         bool
+}
+
+// NewOutboxWorker 创建链上操作发件箱后台任务
+func NewOutboxWorker(db *gorm.DB, blockchainService *EnhancedBlockchainService) *OutboxWorker {
+	return &OutboxWorker{
+		db:                db,
+		blockchainService: blockchainService,
+		stopChan:          make(chan struct{}),
+	}
+}
+
+// Start 启动后台任务
+func (w *OutboxWorker) Start() error {
+	capabilities := w.blockchainService.Capabilities()
+	if !capabilities.ChainRead {
+		return ErrBlockchainUnavailable
+	}
+	if !capabilities.ChainWrite {
+		return blockchain.ErrSignerNotConfigured
+	}
+	if w.isRunning {
+		return fmt.Errorf("发件箱任务已在运行")
+	}
+
+	w.isRunning = true
+	w.stopChan = make(chan struct{})
+	go w.run()
+
+	logger.Info("链上操作发件箱任务已启动")
+	return nil
+}
+
+// Stop 停止后台任务
+func (w *OutboxWorker) Stop() {
+	if !w.isRunning {
+		return
+	}
+	close(w.stopChan)
+	w.isRunning = false
+}
+
+// run 定期处理发件箱
+func (w *OutboxWorker) run() {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.processOnce()
+
+		select {
+		case <-ticker.C:
+		case <-w.stopChan:
+			logger.Info("链上操作发件箱任务已停止")
+			return
+		}
+	}
+}
+
+// processOnce 提交到期的操作并检查已提交交易的确认情况
+func (w *OutboxWorker) processOnce() {
+	w.submitPending()
+	w.confirmSubmitted()
+}
+
+// submitPending 提交到期的待提交操作
+func (w *OutboxWorker) submitPending() {
+	var entries []models.ChainOutbox
+	err := w.db.Where("status = ? AND next_attempt_time <= ?", models.OutboxStatusPending, time.Now().Unix()).
+		Order("id ASC").
+		Limit(outboxBatchSize).
+		Find(&entries).Error
+	if err != nil {
+		logger.Error("查询待提交链上操作失败", err)
+		return
+	}
+
+	for i := range entries {
+		w.submit(&entries[i])
+	}
+}
+
+// submit 提交单个链上操作
+// 进程在发送交易后、记录结果前退出时，该操作会在占用过期后被再次提交，属于至少一次语义
+func (w *OutboxWorker) submit(entry *models.ChainOutbox) {
+	now := time.Now().Unix()
+	claim := w.db.Model(&models.ChainOutbox{}).
+		Where("id = ? AND status = ? AND next_attempt_time = ?", entry.ID, models.OutboxStatusPending, entry.NextAttemptTime).
+		Update("next_attempt_time", now+int64(outboxClaimTTL.Seconds()))
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	tx, err := w.send(entry)
+	if err != nil {
+		w.handleSubmitFailure(entry, err)
+		return
+	}
+
+	txHash := tx.Hash().Hex()
+	err = w.db.Transaction(func(db *gorm.DB) error {
+		if err := db.Model(&models.ChainOutbox{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+			"status":      models.OutboxStatusSubmitted,
+			"attempts":    entry.Attempts + 1,
+			"tx_hash":     txHash,
+			"last_error":  nil,
+			"update_time": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		// 记录创建交易hash，事件监听器据此把链上订单关联到该数据库订单
+		if entry.Action == models.OutboxActionCreateOrder || entry.Action == models.OutboxActionEditOrder {
+			return db.Model(&models.Order{}).Where("id = ?", entry.OrderID).Update("tx_hash", txHash).Error
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("记录链上操作提交结果失败", err, logrus.Fields{
+			"outbox_id": entry.ID,
+			"tx_hash":   txHash,
+		})
+		return
+	}
+
+	logger.Info("链上操作已提交", logrus.Fields{
+		"outbox_id": entry.ID,
+		"order_id":  entry.OrderID,
+		"action":    entry.Action,
+		"tx_hash":   txHash,
+	})
+}
+
+// send 按操作类型发送交易
+func (w *OutboxWorker) send(entry *models.ChainOutbox) (*types.Transaction, error) {
+	var order models.Order
+	if err := w.db.First(&order, entry.OrderID).Error; err != nil {
+		return nil, fmt.Errorf("订单不存在: %v", err)
+	}
+
+	switch entry.Action {
+	case models.OutboxActionCreateOrder:
+		return w.blockchainService.CreateOrderOnChain(&order)
+	case models.OutboxActionCancelOrder:
+		id, err := chainOrderID(&order)
+		if err != nil {
+			return nil, err
+		}
+		return w.blockchainService.CancelOrderOnChain(id.Uint64())
+	case models.OutboxActionExecuteOrder:
+		id, err := chainOrderID(&order)
+		if err != nil {
+			return nil, err
+		}
+		return w.blockchainService.ExecuteOrderOnChain(id.Uint64(), entry.Value)
+	case models.OutboxActionEditOrder:
+		// 修改操作记录在新订单上，链上修改的是被替换的原订单
+		if order.ReplacesID == nil {
+			return nil, fmt.Errorf("订单没有关联被替换的原订单")
+		}
+		var original models.Order
+		if err := w.db.First(&original, *order.ReplacesID).Error; err != nil {
+			return nil, fmt.Errorf("原订单不存在: %v", err)
+		}
+		id, err := chainOrderID(&original)
+		if err != nil {
+			return nil, err
+		}
+		if order.ExpireTime == nil {
+			return nil, fmt.Errorf("修改订单必须指定过期时间")
+		}
+		return w.blockchainService.EditOrderOnChain(id.Uint64(), order.Price, *order.ExpireTime, entry.Value)
+	case models.OutboxActionMarkExpired:
+		id, err := chainOrderID(&order)
+		if err != nil {
+			return nil, err
+		}
+		return w.blockchainService.MarkOrderExpiredOnChain(id.Uint64())
+	default:
+		return nil, fmt.Errorf("未知的链上操作类型: %s", entry.Action)
+	}
+}
+
+// handleSubmitFailure 提交失败时按指数退避重试，次数耗尽后标记失败
+func (w *OutboxWorker) handleSubmitFailure(entry *models.ChainOutbox, submitErr error) {
+	attempts := entry.Attempts + 1
+	logger.Warn("提交链上操作失败", logrus.Fields{
+		"outbox_id": entry.ID,
+		"order_id":  entry.OrderID,
+		"action":    entry.Action,
+		"attempts":  attempts,
+		"error":     submitErr.Error(),
+	})
+
+	if attempts >= outboxMaxAttempts {
+		w.finish(entry, models.OutboxStatusFailed, fmt.Sprintf("重试%d次后仍失败: %v", attempts, submitErr))
+		return
+	}
+
+	backoff := outboxInitialBackoff << uint(attempts-1)
+	if backoff > outboxMaxBackoff || backoff <= 0 {
+		backoff = outboxMaxBackoff
+	}
+
+	now := time.Now().Unix()
+	errMsg := truncateError(submitErr.Error())
+	err := w.db.Model(&models.ChainOutbox{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+		"attempts":          attempts,
+		"next_attempt_time": now + int64(backoff.Seconds()),
+		"last_error":        errMsg,
+		"update_time":       now,
+	}).Error
+	if err != nil {
+		logger.Error("更新链上操作重试信息失败", err, logrus.Fields{
+			"outbox_id": entry.ID,
+		})
+	}
+}
+
+// confirmSubmitted 检查已提交交易的收据
+func (w *OutboxWorker) confirmSubmitted() {
+	var entries []models.ChainOutbox
+	err := w.db.Where("status = ?", models.OutboxStatusSubmitted).
+		Order("id ASC").
+		Limit(outboxBatchSize).
+		Find(&entries).Error
+	if err != nil {
+		logger.Error("查询已提交链上操作失败", err)
+		return
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.TxHash == nil {
+			continue
+		}
+
+		// 交易被加速或取消时，以同nonce实际上链的交易为准
+		mined, err := w.minedReplacement(*entry.TxHash)
+		if err != nil {
+			logger.Error("查询替换交易失败", err, logrus.Fields{
+				"outbox_id": entry.ID,
+				"tx_hash":   *entry.TxHash,
+			})
+			continue
+		}
+		txHash := *entry.TxHash
+		if mined != nil {
+			if mined.Method == cancelMethod {
+				w.finish(entry, models.OutboxStatusFailed, "交易已被管理员取消")
+				continue
+			}
+			txHash = mined.TxHash
+		}
+
+		receipt, err := w.blockchainService.GetTransactionReceipt(common.HexToHash(txHash))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			logger.Error("获取交易收据失败", err, logrus.Fields{
+				"outbox_id": entry.ID,
+				"tx_hash":   txHash,
+			})
+			continue
+		}
+
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			w.finish(entry, models.OutboxStatusConfirmed, "")
+		} else {
+			w.finish(entry, models.OutboxStatusFailed, "交易执行失败(revert)")
+		}
+	}
+}
+
+// minedReplacement 返回与该交易同nonce、已上链的另一笔替换交易，没有时返回nil
+func (w *OutboxWorker) minedReplacement(txHash string) (*models.Transaction, error) {
+	var original models.Transaction
+	err := w.db.Where("tx_hash = ?", txHash).First(&original).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var mined models.Transaction
+	err = w.db.Where("from_address = ? AND nonce = ? AND tx_hash <> ? AND status IN ?",
+		original.FromAddress, original.Nonce, original.TxHash,
+		[]models.TransactionStatus{models.TransactionStatusSuccess, models.TransactionStatusFailed}).
+		First(&mined).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mined, nil
+}
+
+// finish 结束链上操作并同步订单的链上状态
+func (w *OutboxWorker) finish(entry *models.ChainOutbox, status models.OutboxStatus, errMsg string) {
+	now := time.Now().Unix()
+	err := w.db.Transaction(func(db *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":      status,
+			"update_time": now,
+		}
+		if errMsg != "" {
+			updates["last_error"] = truncateError(errMsg)
+		}
+		if err := db.Model(&models.ChainOutbox{}).Where("id = ?", entry.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		orderUpdates := map[string]interface{}{
+			"update_time": now,
+		}
+		if status == models.OutboxStatusFailed {
+			orderUpdates["chain_status"] = models.OrderChainStatusFailed
+			orderUpdates["chain_error"] = truncateError(fmt.Sprintf("%s: %s", entry.Action, errMsg))
+		} else {
+			// 同一订单还有未完成的操作时保持待确认
+			var remaining int64
+			if err := db.Model(&models.ChainOutbox{}).
+				Where("order_id = ? AND status IN ?", entry.OrderID, []models.OutboxStatus{models.OutboxStatusPending, models.OutboxStatusSubmitted}).
+				Count(&remaining).Error; err != nil {
+				return err
+			}
+			if remaining == 0 {
+				orderUpdates["chain_status"] = models.OrderChainStatusConfirmed
+			}
+		}
+		return db.Model(&models.Order{}).Where("id = ?", entry.OrderID).Updates(orderUpdates).Error
+	})
+	if err != nil {
+		logger.Error("更新链上操作结果失败", err, logrus.Fields{
+			"outbox_id": entry.ID,
+		})
+		return
+	}
+
+	fields := logrus.Fields{
+		"outbox_id": entry.ID,
+		"order_id":  entry.OrderID,
+		"action":    entry.Action,
+		"status":    status,
+	}
+	if status == models.OutboxStatusFailed {
+		fields["error"] = errMsg
+		logger.Warn("链上操作失败", fields)
+	} else {
+		logger.Info("链上操作已确认", fields)
+	}
+}
+
+// truncateError 截断错误信息以适配数据库字段长度
+func truncateError(msg string) string {
+	const maxLen = 500
+	runes := []rune(msg)
+	if len(runes) > maxLen {
+		return string(runes[:maxLen])
+	}
+	return msg
+}
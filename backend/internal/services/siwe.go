@@ -0,0 +1,143 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// siweHeaderSuffix EIP-4361消息首行的固定后缀
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+// SIWEMessage EIP-4361 (Sign-In with Ethereum) 消息
+type SIWEMessage struct {
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        string
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// ParseSIWEMessage 解析EIP-4361格式的登录消息
+func ParseSIWEMessage(message string) (*SIWEMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("SIWE消息格式错误")
+	}
+
+	// 第一行: ${domain} wants you to sign in with your Ethereum account:
+	if !strings.HasSuffix(lines[0], siweHeaderSuffix) {
+		return nil, fmt.Errorf("SIWE消息首行格式错误")
+	}
+	msg := &SIWEMessage{
+		Domain: strings.TrimSuffix(lines[0], siweHeaderSuffix),
+	}
+	if msg.Domain == "" {
+		return nil, fmt.Errorf("SIWE消息缺少domain")
+	}
+
+	// 第二行: ${address}
+	if !common.IsHexAddress(lines[1]) {
+		return nil, fmt.Errorf("SIWE消息地址无效: %s", lines[1])
+	}
+	msg.Address = lines[1]
+
+	// 地址之后是空行、可选的statement，然后是字段列表
+	i := 2
+	if i < len(lines) && lines[i] == "" {
+		i++
+	}
+	if i < len(lines) && !strings.HasPrefix(lines[i], "URI: ") {
+		msg.Statement = lines[i]
+		i++
+		if i < len(lines) && lines[i] == "" {
+			i++
+		}
+	}
+
+	inResources := false
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			continue
+		}
+		if inResources {
+			if !strings.HasPrefix(line, "- ") {
+				return nil, fmt.Errorf("SIWE消息资源列表格式错误: %s", line)
+			}
+			msg.Resources = append(msg.Resources, strings.TrimPrefix(line, "- "))
+			continue
+		}
+		if line == "Resources:" {
+			inResources = true
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ": ")
+		if !found {
+			return nil, fmt.Errorf("SIWE消息字段格式错误: %s", line)
+		}
+
+		switch key {
+		case "URI":
+			msg.URI = value
+		case "Version":
+			msg.Version = value
+		case "Chain ID":
+			msg.ChainID = value
+		case "Nonce":
+			msg.Nonce = value
+		case "Issued At":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("SIWE消息Issued At格式错误: %v", err)
+			}
+			msg.IssuedAt = t
+		case "Expiration Time":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("SIWE消息Expiration Time格式错误: %v", err)
+			}
+			msg.ExpirationTime = &t
+		case "Not Before":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("SIWE消息Not Before格式错误: %v", err)
+			}
+			msg.NotBefore = &t
+		case "Request ID":
+			msg.RequestID = value
+		default:
+			return nil, fmt.Errorf("SIWE消息包含未知字段: %s", key)
+		}
+	}
+
+	if msg.URI == "" || msg.Version == "" || msg.ChainID == "" || msg.Nonce == "" || msg.IssuedAt.IsZero() {
+		return nil, fmt.Errorf("SIWE消息缺少必填字段")
+	}
+	if msg.Version != "1" {
+		return nil, fmt.Errorf("不支持的SIWE版本: %s", msg.Version)
+	}
+
+	return msg, nil
+}
+
+// ValidateTime 校验消息的有效时间窗口
+func (m *SIWEMessage) ValidateTime(now time.Time) error {
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return fmt.Errorf("SIWE消息已过期")
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return fmt.Errorf("SIWE消息尚未生效")
+	}
+	return nil
+}
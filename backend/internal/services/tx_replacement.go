@@ -0,0 +1,197 @@
+package services
+
+import (
+	"fmt"
+	"nft-market/internal/blockchain"
+	"nft-market/internal/logger"
+	"nft-market/internal/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// stuckTxCheckInterval 检查卡住交易的间隔
+	stuckTxCheckInterval = time.Minute
+	// cancelMethod 取消交易记录的方法名
+	cancelMethod = "cancel"
+)
+
+// TxReplacementConfig 卡住交易处理配置
+type TxReplacementConfig struct {
+	StuckAfter     time.Duration // 交易待打包超过该时长视为卡住
+	MaxAutoSpeedUp int           // 同一nonce自动加速的最大次数，0表示只检测不自动加速
+}
+
+// TxReplacementService 检测热钱包卡住的交易，并以相同nonce加速或取消
+type TxReplacementService struct {
+	db        *gorm.DB
+	contract  *blockchain.NFTMarketplaceContract
+	tracker   *blockchain.TransactionTracker
+	config    TxReplacementConfig
+	stopChan  chan struct{}
+	isRunning bool
+}
+
+// NewTxReplacementService 创建卡住交易处理服务
+func NewTxReplacementService(db *gorm.DB, blockchainService *EnhancedBlockchainService, config TxReplacementConfig) *TxReplacementService {
+	return &TxReplacementService{
+		db:       db,
+		contract: blockchainService.contract,
+		tracker:  blockchainService.txTracker,
+		config:   config,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start 启动卡住交易检测
+func (s *TxReplacementService) Start() {
+	if s.isRunning {
+		return
+	}
+	s.isRunning = true
+	s.stopChan = make(chan struct{})
+	go s.run()
+}
+
+// Stop 停止卡住交易检测
+func (s *TxReplacementService) Stop() {
+	if !s.isRunning {
+		return
+	}
+	close(s.stopChan)
+	s.isRunning = false
+}
+
+// run 定期检测卡住的交易并按配置自动加速
+func (s *TxReplacementService) run() {
+	ticker := time.NewTicker(stuckTxCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checkStuck()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// checkStuck 检测卡住的交易
+func (s *TxReplacementService) checkStuck() {
+	stuck, err := s.ListStuckTransactions()
+	if err != nil {
+		logger.Error("查询卡住的交易失败", err)
+		return
+	}
+
+	for i := range stuck {
+		record := &stuck[i]
+		fields := logrus.Fields{
+			"tx_hash": record.TxHash,
+			"nonce":   record.Nonce,
+			"method":  record.Method,
+		}
+		logger.Warn("交易待打包时间过长", fields)
+
+		if record.Method == cancelMethod {
+			continue
+		}
+		replaced, err := s.replacementCount(record)
+		if err != nil {
+			logger.Error("查询交易替换次数失败", err, fields)
+			continue
+		}
+		if replaced >= int64(s.config.MaxAutoSpeedUp) {
+			continue
+		}
+		if _, err := s.SpeedUp(record.TxHash); err != nil {
+			logger.Error("自动加速交易失败", err, fields)
+		}
+	}
+}
+
+// ListStuckTransactions 获取待打包时间超过阈值且尚未被替换的交易
+func (s *TxReplacementService) ListStuckTransactions() ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	deadline := time.Now().Add(-s.config.StuckAfter).Unix()
+	err := s.db.Where("status = ? AND from_address = ? AND create_time <= ?",
+		models.TransactionStatusPending, s.contract.FromAddress().Hex(), deadline).
+		Order("nonce ASC").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询卡住的交易失败: %v", err)
+	}
+	return transactions, nil
+}
+
+// SpeedUp 以相同nonce和更高手续费重新发送交易
+func (s *TxReplacementService) SpeedUp(txHash string) (*models.Transaction, error) {
+	return s.replace(txHash, false)
+}
+
+// Cancel 以相同nonce发送0金额自转账取消交易
+func (s *TxReplacementService) Cancel(txHash string) (*models.Transaction, error) {
+	return s.replace(txHash, true)
+}
+
+// replace 发送替换交易并记录替换关系
+func (s *TxReplacementService) replace(txHash string, cancel bool) (*models.Transaction, error) {
+	var original models.Transaction
+	if err := s.db.Where("tx_hash = ?", common.HexToHash(txHash).Hex()).First(&original).Error; err != nil {
+		return nil, err
+	}
+	if original.Status != models.TransactionStatusPending {
+		return nil, fmt.Errorf("只能替换待打包的交易")
+	}
+
+	replacement, err := s.contract.ReplaceTransaction(common.HexToHash(original.TxHash), cancel)
+	if err != nil {
+		return nil, err
+	}
+
+	method := original.Method
+	if cancel {
+		method = cancelMethod
+	}
+	record, err := s.tracker.RecordReplacement(replacement, &original, method)
+	if err != nil {
+		// 替换交易已发送，记录失败只影响展示，收据轮询会按nonce处理原交易
+		logger.Error("记录替换交易失败", err, logrus.Fields{
+			"tx_hash":     original.TxHash,
+			"replacement": replacement.Hash().Hex(),
+		})
+		return nil, err
+	}
+
+	// 加速的创建订单交易上链后，事件监听器按新交易hash关联数据库订单
+	if !cancel {
+		if err := s.db.Model(&models.Order{}).
+			Where("tx_hash = ? AND block_hash IS NULL", original.TxHash).
+			Update("tx_hash", record.TxHash).Error; err != nil {
+			logger.Error("更新订单交易hash失败", err, logrus.Fields{
+				"tx_hash": original.TxHash,
+			})
+		}
+	}
+
+	logger.Info("已发送替换交易", logrus.Fields{
+		"tx_hash":     original.TxHash,
+		"replacement": record.TxHash,
+		"nonce":       original.Nonce,
+		"cancel":      cancel,
+	})
+	return record, nil
+}
+
+// replacementCount 统计同一nonce已发送的替换交易数
+func (s *TxReplacementService) replacementCount(record *models.Transaction) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.Transaction{}).
+		Where("from_address = ? AND nonce = ? AND replaces IS NOT NULL", record.FromAddress, record.Nonce).
+		Count(&count).Error
+	return count, err
+}
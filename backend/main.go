@@ -1,12 +1,17 @@
 package main
 
 import (
+	"errors"
+	"math/big"
 	"nft-market/internal/api"
+	"nft-market/internal/blockchain"
 	"nft-market/internal/config"
 	"nft-market/internal/database"
 	"nft-market/internal/logger"
 	"nft-market/internal/services"
+	"time"
 
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -32,6 +37,13 @@ func main() {
 		"env":  cfg.Environment,
 	})
 
+	// 使用默认密钥时任何人都能伪造登录令牌，拒绝启动
+	if cfg.JWTSecret == "" || cfg.JWTSecret == "your-secret-key" || cfg.JWTSecret == "your_jwt_secret_key" {
+		err := errors.New("JWT_SECRET未配置或仍为示例值")
+		logger.Error("请配置随机生成的JWT_SECRET", err)
+		panic(err)
+	}
+
 	// 初始化数据库
 	db, err := database.Init(cfg.DatabaseURL)
 	if err != nil {
@@ -40,25 +52,119 @@ func main() {
 	}
 	logger.Info("数据库连接成功")
 
-	// 初始化增强的区块链服务
-	blockchainService, err := services.NewEnhancedBlockchainService(cfg.EthereumRPC, cfg.ContractAddress, cfg.PrivateKey, db)
-	if err != nil {
-		logger.Error("增强区块链服务初始化失败", err)
+	// 初始化交易签名器
+	signer, err := blockchain.NewSigner(blockchain.SignerConfig{
+		Type:         cfg.SignerType,
+		PrivateKey:   cfg.PrivateKey,
+		KeystorePath: cfg.KeystorePath,
+		PasswordFile: cfg.KeystorePassword,
+		ClefURL:      cfg.ClefURL,
+		ClefAddress:  cfg.ClefAddress,
+	})
+	if errors.Is(err, blockchain.ErrSignerNotConfigured) {
+		// 未配置签名器时以只读模式启动，热钱包发送交易的接口返回503
+		logger.Warn("未配置交易签名器，链上写操作不可用")
+	} else if err != nil {
+		logger.Error("交易签名器初始化失败", err)
 		panic(err)
 	}
-	logger.Info("增强区块链服务初始化成功", map[string]interface{}{
-		"rpc_url":          cfg.EthereumRPC,
-		"contract_address": cfg.ContractAddress,
+
+	// 手续费上限为0时不限制
+	feeConfig := blockchain.FeeConfig{GasLimitMarginPercent: cfg.GasLimitMargin}
+	if cfg.MaxFeeCapGwei > 0 {
+		feeConfig.MaxFeeCap = new(big.Int).Mul(new(big.Int).SetUint64(cfg.MaxFeeCapGwei), big.NewInt(params.GWei))
+	}
+
+	// 初始化增强的区块链服务，未配置合约或节点不可用时只提供数据库中的数据
+	var blockchainService *services.EnhancedBlockchainService
+	if cfg.ContractAddress == "" {
+		logger.Warn("未配置合约地址，区块链相关接口不可用")
+	} else {
+		blockchainService, err = services.NewEnhancedBlockchainService(cfg.EthereumRPC, cfg.ContractAddress, signer, db, blockchain.ListenerConfig{
+			StartBlock:        cfg.SyncStartBlock,
+			ConfirmationDepth: cfg.ConfirmationDepth,
+			PollInterval:      time.Duration(cfg.EventPollInterval) * time.Second,
+			LogBlockRange:     cfg.LogBlockRange,
+		}, feeConfig)
+		if err != nil {
+			logger.Error("增强区块链服务初始化失败，区块链相关接口不可用", err)
+			blockchainService = nil
+		} else {
+			logger.Info("增强区块链服务初始化成功", map[string]interface{}{
+				"rpc_url":          cfg.EthereumRPC,
+				"contract_address": cfg.ContractAddress,
+			})
+		}
+	}
+	logger.Info("区块链功能可用性", map[string]interface{}{
+		"capabilities": blockchainService.Capabilities(),
 	})
 
 	// 初始化服务层
 	orderService := services.NewOrderService(db, blockchainService)
+	orderService.SetSignedOrdersEnabled(cfg.SignedOrders)
 	nftService := services.NewNFTService(db, blockchainService)
 	collectionService := services.NewCollectionService(db)
-	itemService := services.NewItemService(db)
+	itemService := services.NewItemService(db, blockchainService)
 	activityService := services.NewActivityService(db)
+	authService := services.NewAuthService(db, services.AuthConfig{
+		JWTSecret:      cfg.JWTSecret,
+		Domain:         cfg.SIWEDomain,
+		URI:            cfg.SIWEURI,
+		ChainID:        cfg.SIWEChainID,
+		AdminAddresses: cfg.AdminAddresses,
+	})
 	logger.Info("所有服务初始化完成")
 
+	// 启动卡住交易检测，超时未打包的热钱包交易按配置自动加速
+	var txReplacementService *services.TxReplacementService
+	if blockchainService.Capabilities().ChainWrite {
+		txReplacementService = services.NewTxReplacementService(db, blockchainService, services.TxReplacementConfig{
+			StuckAfter:     time.Duration(cfg.StuckTxTimeout) * time.Second,
+			MaxAutoSpeedUp: int(cfg.MaxAutoSpeedUp),
+		})
+		txReplacementService.Start()
+	}
+
+	// 启动链上操作发件箱任务
+	outboxWorker := services.NewOutboxWorker(db, blockchainService)
+	if err := outboxWorker.Start(); err != nil {
+		logger.Warn("链上操作发件箱任务未启动", map[string]interface{}{
+			"reason": err.Error(),
+		})
+	}
+
+	// 启动订单链上状态校验任务，定期标记挂单方已转出或取消授权、出价方余额不足的订单
+	orderValidator := services.NewOrderValidator(orderService, time.Duration(cfg.OrderRevalidate)*time.Second)
+	if err := orderValidator.Start(); err != nil {
+		logger.Warn("订单校验任务未启动", map[string]interface{}{
+			"reason": err.Error(),
+		})
+	}
+
+	// 启动物品元数据索引任务，定期从tokenURI获取元数据并更新特征出价使用的物品特征
+	metadataIndexer := services.NewMetadataIndexer(itemService, time.Duration(cfg.MetadataIndex)*time.Second)
+	if err := metadataIndexer.Start(); err != nil {
+		logger.Warn("元数据索引任务未启动", map[string]interface{}{
+			"reason": err.Error(),
+		})
+	}
+
+	// 启动撮合服务，从有效订单恢复订单簿并定期重建
+	matchingService := services.NewMatchingService(db, time.Duration(cfg.OrderMatching)*time.Second)
+	if err := matchingService.Start(); err != nil {
+		logger.Warn("撮合服务未启动", map[string]interface{}{
+			"reason": err.Error(),
+		})
+	}
+	orderService.SetMatchingService(matchingService)
+
+	// 启动订单过期任务，EXPIRE_ON_CHAIN开启时对已上链出价调用markOrderExpired退还托管的ETH，
+	// 同时清理超过PENDING_ORDER_TTL_SECONDS仍未上链的待上链订单
+	expiryScheduler := services.NewExpiryScheduler(db, orderService, time.Duration(cfg.OrderExpiry)*time.Second, cfg.ExpireOnChain,
+		time.Duration(cfg.PendingOrderTTL)*time.Second)
+	expiryScheduler.Start()
+
 	// 设置Gin模式
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -75,12 +181,12 @@ func main() {
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = []string{"http://localhost:3000", "http://localhost:3001"}
 	corsConfig.AllowCredentials = true
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-User-Address"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
 	router.Use(cors.New(corsConfig))
 
 	// 设置API路由
-	api.SetupRoutes(router, orderService, nftService, collectionService, itemService, activityService, blockchainService)
+	api.SetupRoutes(router, orderService, nftService, collectionService, itemService, activityService, blockchainService, authService, txReplacementService, expiryScheduler, matchingService)
 
 	// 启动服务器
 	logger.Info("服务器启动", map[string]interface{}{
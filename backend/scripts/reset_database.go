@@ -1,3 +1,6 @@
+//go:build ignore
+
+// 删除并重新创建数据库表，运行: go run scripts/reset_database.go
 package main
 
 import (
@@ -7,6 +10,7 @@ import (
 	"nft-market/internal/models"
 
 	"github.com/joho/godotenv"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -26,30 +30,43 @@ func main() {
 
 	fmt.Println("开始重置数据库表...")
 
-	// 删除现有表（如果存在）
-	tables := []string{
-		"orders", "activities", "items", "collections", "users",
+	// 与database.Init迁移的表保持一致，同步进度和已处理日志需一并清空，否则事件监听器不会重新索引
+	tables := []interface{}{
+		&models.Collection{},
+		&models.Item{},
+		&models.ItemAttribute{},
+		&models.ItemBalance{},
+		&models.Order{},
+		&models.OrderCriteria{},
+		&models.Activity{},
+		&models.User{},
+		&models.AuthNonce{},
+		&models.SyncState{},
+		&models.IndexedBlock{},
+		&models.ProcessedLog{},
+		&models.FailedLog{},
+		&models.ChainOutbox{},
+		&models.Transaction{},
+		&models.ExpiryRun{},
 	}
 
+	// 删除现有表（如果存在）
 	for _, table := range tables {
-		if err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table)).Error; err != nil {
-			fmt.Printf("删除表 %s 失败: %v\n", table, err)
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(table); err != nil {
+			panic("解析表结构失败: " + err.Error())
+		}
+		if err := db.Migrator().DropTable(table); err != nil {
+			fmt.Printf("删除表 %s 失败: %v\n", stmt.Table, err)
 		} else {
-			fmt.Printf("删除表 %s 成功\n", table)
+			fmt.Printf("删除表 %s 成功\n", stmt.Table)
 		}
 	}
 
 	fmt.Println("开始重新创建表...")
 
 	// 重新创建表
-	err = db.AutoMigrate(
-		&models.Collection{},
-		&models.Item{},
-		&models.Order{},
-		&models.Activity{},
-		&models.User{},
-	)
-	if err != nil {
+	if err := db.AutoMigrate(tables...); err != nil {
 		panic("表创建失败: " + err.Error())
 	}
 
@@ -1,3 +1,6 @@
+//go:build ignore
+
+// 创建购买测试所需的集合、物品和挂单，运行: go run scripts/setup_test_data.go
 package main
 
 import (
@@ -41,7 +44,7 @@ func main() {
 		Address:     "0x1234567890123456789012345678901234567890",
 		Name:        "测试NFT集合",
 		Symbol:      "TEST",
-		Description: "用于测试的NFT集合",
+		Description: strPtr("用于测试的NFT集合"),
 		Creator:     "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
 		CreateTime:  timePtr(time.Now().Unix()),
 		UpdateTime:  timePtr(time.Now().Unix()),
@@ -60,7 +63,6 @@ func main() {
 			ChainID:           models.ChainIDEthereum,
 			TokenID:           "1001",
 			Name:              "测试NFT #1001",
-			Description:       "第一个测试NFT",
 			Owner:             strPtr("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"),
 			CollectionAddress: strPtr("0x1234567890123456789012345678901234567890"),
 			Creator:           "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
@@ -72,7 +74,6 @@ func main() {
 			ChainID:           models.ChainIDEthereum,
 			TokenID:           "1002",
 			Name:              "测试NFT #1002",
-			Description:       "第二个测试NFT",
 			Owner:             strPtr("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
 			CollectionAddress: strPtr("0x1234567890123456789012345678901234567890"),
 			Creator:           "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
@@ -104,7 +105,7 @@ func main() {
 			OrderStatus:       models.OrderStatusActive,
 			CollectionAddress: "0x1234567890123456789012345678901234567890",
 			TokenID:           "1001",
-			Price:             0.5,
+			Price:             ethPrice("0.5"),
 			Maker:             "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
 			QuantityRemaining: 1,
 			Size:              1,
@@ -119,7 +120,7 @@ func main() {
 			OrderStatus:       models.OrderStatusActive,
 			CollectionAddress: "0x1234567890123456789012345678901234567890",
 			TokenID:           "1002",
-			Price:             0.3,
+			Price:             ethPrice("0.3"),
 			Maker:             "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
 			QuantityRemaining: 1,
 			Size:              1,
@@ -141,7 +142,7 @@ func main() {
 				"db_id":    order.ID,
 				"order_id": order.OrderID,
 				"maker":    order.Maker,
-				"price":    order.Price,
+				"price":    order.Price.String(),
 			})
 		}
 	}
@@ -157,3 +158,12 @@ func strPtr(s string) *string {
 func timePtr(t int64) *int64 {
 	return &t
 }
+
+// ethPrice 把ETH金额换算为wei
+func ethPrice(eth string) models.Wei {
+	price, err := models.ParseETH(eth)
+	if err != nil {
+		panic("价格格式错误: " + err.Error())
+	}
+	return price
+}
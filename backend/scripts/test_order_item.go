@@ -1,9 +1,13 @@
+//go:build ignore
+
+// 验证创建订单时同步创建物品记录，运行: go run scripts/test_order_item.go
 package main
 
 import (
 	"fmt"
 	"nft-market/internal/config"
 	"nft-market/internal/database"
+	"nft-market/internal/logger"
 	"nft-market/internal/models"
 	"nft-market/internal/services"
 
@@ -16,6 +20,11 @@ func main() {
 		fmt.Println("未找到.env文件，使用默认配置")
 	}
 
+	// 初始化日志系统
+	if err := logger.Init(logger.DefaultConfig()); err != nil {
+		panic("日志系统初始化失败: " + err.Error())
+	}
+
 	// 加载配置
 	cfg := config.Load()
 
@@ -25,18 +34,20 @@ func main() {
 		panic("数据库连接失败: " + err.Error())
 	}
 
-	// 初始化区块链服务（模拟）
-	blockchainService := &services.BlockchainService{}
+	// 不连接区块链，订单只写入数据库
+	orderService := services.NewOrderService(db, nil)
 
-	// 初始化订单服务
-	orderService := services.NewOrderService(db, blockchainService)
+	price, err := models.ParseETH("0.1")
+	if err != nil {
+		panic("价格格式错误: " + err.Error())
+	}
 
 	// 测试创建订单请求
 	req := &models.CreateOrderRequest{
 		CollectionAddress: "0x1234567890123456789012345678901234567890",
 		TokenID:           "1",
 		OrderType:         models.OrderTypeListing,
-		Price:             0.1,
+		Price:             price,
 		QuantityRemaining: 1,
 		Size:              1,
 		CurrencyAddress:   "0x0000000000000000000000000000000000000000",
@@ -61,7 +72,11 @@ func main() {
 		return
 	}
 
-	fmt.Printf("Item记录创建成功! ID: %d, Name: %s, Owner: %s\n", item.ID, item.Name, *item.Owner)
+	owner := ""
+	if item.Owner != nil {
+		owner = *item.Owner
+	}
+	fmt.Printf("Item记录创建成功! ID: %d, Name: %s, Owner: %s\n", item.ID, item.Name, owner)
 	fmt.Printf("ListPrice: %v, ListTime: %v\n", item.ListPrice, item.ListTime)
 
 	fmt.Println("测试完成！订单和Item都创建成功。")
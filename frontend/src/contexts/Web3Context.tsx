@@ -1,5 +1,6 @@
 import React, { createContext, useContext, useState, useEffect, useCallback, ReactNode } from 'react';
 import { ethers } from 'ethers';
+import { apiService } from '../services/api';
 
 interface Web3ContextType {
   account: string | null;
@@ -7,15 +8,55 @@ interface Web3ContextType {
   signer: ethers.JsonRpcSigner | null;
   isConnected: boolean;
   connectWallet: () => Promise<void>;
-  connectTestWallet: () => void;
+  connectTestWallet: () => Promise<void>;
   disconnectWallet: () => void;
   switchNetwork: (chainId: string) => Promise<void>;
   chainId: string | null;
   isTestMode: boolean;
+  sendUnsignedTransaction: (tx: UnsignedTransaction) => Promise<string>;
+}
+
+// 后端构建的未签名交易
+export interface UnsignedTransaction {
+  from: string;
+  to: string;
+  data: string;
+  value: string;
+  gas: number;
+  chain_id: string;
+  method: string;
 }
 
 const Web3Context = createContext<Web3ContextType | undefined>(undefined);
 
+// Hardhat测试账户#0的公开私钥，仅用于本地测试模式登录
+const HARDHAT_TEST_PRIVATE_KEY = '0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80';
+
+// 使用Sign-In with Ethereum (EIP-4361) 登录后端并保存令牌
+const signInWithEthereum = async (signer: ethers.Signer, chainId: string) => {
+  const address = await signer.getAddress();
+  const { data } = await apiService.getAuthNonce(address);
+
+  const message = [
+    `${data.domain} wants you to sign in with your Ethereum account:`,
+    data.address,
+    '',
+    '登录NFT市场',
+    '',
+    `URI: ${window.location.origin}`,
+    'Version: 1',
+    `Chain ID: ${chainId}`,
+    `Nonce: ${data.nonce}`,
+    `Issued At: ${new Date().toISOString().replace(/\.\d{3}Z$/, 'Z')}`,
+  ].join('\n');
+
+  const signature = await signer.signMessage(message);
+  const result = await apiService.login(message, signature);
+
+  localStorage.setItem('authToken', result.data.token);
+  localStorage.setItem('userAddress', result.data.address);
+};
+
 interface Web3ProviderProps {
   children: ReactNode;
 }
@@ -30,6 +71,8 @@ export const Web3Provider: React.FC<Web3ProviderProps> = ({ children }) => {
   const isConnected = account !== null;
 
   const disconnectWallet = useCallback(() => {
+    localStorage.removeItem('authToken');
+    localStorage.removeItem('userAddress');
     setAccount(null);
     setProvider(null);
     setSigner(null);
@@ -109,6 +152,8 @@ export const Web3Provider: React.FC<Web3ProviderProps> = ({ children }) => {
       setProvider(provider);
       setSigner(signer);
       setChainId(network.chainId.toString());
+
+      await signInWithEthereum(signer, network.chainId.toString());
     } catch (error) {
       console.error('连接钱包失败:', error);
       alert('连接钱包失败，请重试。');
@@ -134,7 +179,7 @@ export const Web3Provider: React.FC<Web3ProviderProps> = ({ children }) => {
   };
 
   // 测试模式连接（使用Hardhat测试账户）
-  const connectTestWallet = () => {
+  const connectTestWallet = async () => {
     const testAccounts = [
       '0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266', // Account #0
       '0x70997970C51812dc3A010C7d01b50e0d17dc79C8', // Account #1
@@ -147,12 +192,33 @@ export const Web3Provider: React.FC<Web3ProviderProps> = ({ children }) => {
     setChainId('31337'); // Hardhat链ID
     setIsTestMode(true);
     
-    // 保存到localStorage供API使用
-    localStorage.setItem('userAddress', selectedAccount);
+    // 使用测试账户私钥完成SIWE登录，令牌保存到localStorage供API使用
+    try {
+      await signInWithEthereum(new ethers.Wallet(HARDHAT_TEST_PRIVATE_KEY), '31337');
+    } catch (error) {
+      console.error('测试账户登录失败:', error);
+    }
     
     console.log('测试模式已启用，使用账户:', selectedAccount);
   };
 
+  // 使用当前钱包签名并发送后端构建的交易，返回交易hash
+  const sendUnsignedTransaction = async (tx: UnsignedTransaction): Promise<string> => {
+    if (!signer) {
+      throw new Error('请先连接钱包');
+    }
+    if (chainId && chainId !== tx.chain_id) {
+      throw new Error(`请切换到链 ${tx.chain_id}`);
+    }
+    const response = await signer.sendTransaction({
+      to: tx.to,
+      data: tx.data,
+      value: BigInt(tx.value),
+      gasLimit: BigInt(tx.gas),
+    });
+    return response.hash;
+  };
+
   const value: Web3ContextType = {
     account,
     provider,
@@ -164,6 +230,7 @@ export const Web3Provider: React.FC<Web3ProviderProps> = ({ children }) => {
     switchNetwork,
     chainId,
     isTestMode,
+    sendUnsignedTransaction,
   };
 
   return <Web3Context.Provider value={value}>{children}</Web3Context.Provider>;
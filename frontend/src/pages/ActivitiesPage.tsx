@@ -72,8 +72,11 @@ const ActivitiesPage: React.FC = () => {
     return `${address.slice(0, 6)}...${address.slice(-4)}`;
   };
 
-  const formatPrice = (price: number) => {
-    return price.toFixed(4) + ' ETH';
+  // 价格以后端返回的ETH展示字符串（*_eth字段）为准
+  const formatPrice = (price: string | null | undefined) => {
+    if (!price) return '暂无价格';
+    const value = parseFloat(price);
+    return (Number.isNaN(value) ? price : value.toFixed(4)) + ' ETH';
   };
 
   const getActivityTypeText = (activityType: number) => {
@@ -193,7 +196,7 @@ const ActivitiesPage: React.FC = () => {
                         </Box>
                         <Box sx={{ textAlign: 'right' }}>
                           <Typography variant="h6" color="primary">
-                            {formatPrice(activity.price)}
+                            {formatPrice(activity.price_eth)}
                           </Typography>
                           {activity.tx_hash && (
                             <Typography variant="body2" color="text.secondary">
@@ -277,7 +280,7 @@ const ActivitiesPage: React.FC = () => {
                         </Box>
                         <Box sx={{ textAlign: 'right' }}>
                           <Typography variant="h6" color="primary">
-                            {formatPrice(activity.price)}
+                            {formatPrice(activity.price_eth)}
                           </Typography>
                           {activity.tx_hash && (
                             <Typography variant="body2" color="text.secondary">
@@ -337,7 +340,7 @@ const ActivitiesPage: React.FC = () => {
                         </Box>
                         <Box sx={{ textAlign: 'right' }}>
                           <Typography variant="h6" color="primary">
-                            {formatPrice(activity.price)}
+                            {formatPrice(activity.price_eth)}
                           </Typography>
                           {activity.tx_hash && (
                             <Typography variant="body2" color="text.secondary">
@@ -397,7 +400,7 @@ const ActivitiesPage: React.FC = () => {
                         </Box>
                         <Box sx={{ textAlign: 'right' }}>
                           <Typography variant="h6" color="primary">
-                            {formatPrice(activity.price)}
+                            {formatPrice(activity.price_eth)}
                           </Typography>
                           {activity.tx_hash && (
                             <Typography variant="body2" color="text.secondary">
@@ -45,9 +45,11 @@ const CollectionsPage: React.FC = () => {
     return `${address.slice(0, 6)}...${address.slice(-4)}`;
   };
 
-  const formatPrice = (price: number | null) => {
+  // 价格以后端返回的ETH展示字符串（*_eth字段）为准
+  const formatPrice = (price: string | null | undefined) => {
     if (!price) return '暂无价格';
-    return price.toFixed(4) + ' ETH';
+    const value = parseFloat(price);
+    return (Number.isNaN(value) ? price : value.toFixed(4)) + ' ETH';
   };
 
   return (
@@ -138,14 +140,14 @@ const CollectionsPage: React.FC = () => {
 
                     <Box sx={{ mb: 2 }}>
                       <Typography variant="body2" color="text.secondary">
-                        地板价: {formatPrice(collection.floor_price)}
+                        地板价: {formatPrice(collection.floor_price_eth)}
                       </Typography>
                       <Typography variant="body2" color="text.secondary">
-                        最高出价: {formatPrice(collection.sale_price)}
+                        最高出价: {formatPrice(collection.sale_price_eth)}
                       </Typography>
                       {collection.volume_total && (
                         <Typography variant="body2" color="text.secondary">
-                          总交易量: {formatPrice(collection.volume_total)}
+                          总交易量: {formatPrice(collection.volume_total_eth)}
                         </Typography>
                       )}
                     </Box>
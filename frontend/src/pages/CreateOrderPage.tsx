@@ -14,6 +14,7 @@ import {
   Grid,
 } from '@mui/material';
 import { useForm, Controller } from 'react-hook-form';
+import { ethers } from 'ethers';
 import { useWeb3 } from '../contexts/Web3Context';
 import { apiService } from '../services/api';
 
@@ -71,7 +72,7 @@ const CreateOrderPage: React.FC = () => {
       const orderData = {
         collection_address: data.collectionAddress,
         token_id: data.tokenId,
-        price: parseFloat(data.price) || 0, // 转换为数字
+        price: ethers.parseEther(data.price || '0').toString(), // 转换为wei字符串
         order_type: data.orderType,
         expire_time: Math.floor(expirationDate.getTime() / 1000), // 转换为Unix时间戳
         quantity_remaining: 1,
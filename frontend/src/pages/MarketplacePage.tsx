@@ -57,7 +57,7 @@ const MarketplacePage: React.FC = () => {
   };
 
   // 购买订单处理函数
-  const handlePurchaseOrder = async (orderId: number, orderPrice: number) => {
+  const handlePurchaseOrder = async (orderId: number, orderPrice: string) => {
     if (!isConnected || !account) {
       alert('请先连接钱包');
       return;
@@ -108,12 +108,11 @@ const MarketplacePage: React.FC = () => {
     return config[orderType as keyof typeof config] || { label: `类型${orderType}`, color: 'default' as const };
   };
 
-  const formatPrice = (price: number) => {
-    try {
-      return price.toFixed(4) + ' ETH';
-    } catch {
-      return price.toString();
-    }
+  // 价格以后端返回的ETH展示字符串（*_eth字段）为准
+  const formatPrice = (price: string | null | undefined) => {
+    if (!price) return '暂无价格';
+    const value = parseFloat(price);
+    return (Number.isNaN(value) ? price : value.toFixed(4)) + ' ETH';
   };
 
   const formatAddress = (address: string) => {
@@ -173,7 +172,7 @@ const MarketplacePage: React.FC = () => {
                           />
                         </Box>
                         <Typography variant="h6" gutterBottom>
-                          {formatPrice(order.price)}
+                          {formatPrice(order.price_eth)}
                         </Typography>
                         <Typography variant="body2" color="text.secondary" gutterBottom>
                           集合: {formatAddress(order.collection_address || '')}
@@ -261,7 +260,7 @@ const MarketplacePage: React.FC = () => {
                           <Chip label="上架" color="error" size="small" />
                         </Box>
                         <Typography variant="h6" gutterBottom>
-                          {formatPrice(order.price)}
+                          {formatPrice(order.price_eth)}
                         </Typography>
                         <Typography variant="body2" color="text.secondary" gutterBottom>
                           集合: {formatAddress(order.collection_address || '')}
@@ -336,7 +335,7 @@ const MarketplacePage: React.FC = () => {
                           <Chip label="出价" color="success" size="small" />
                         </Box>
                         <Typography variant="h6" gutterBottom>
-                          {formatPrice(order.price)}
+                          {formatPrice(order.price_eth)}
                         </Typography>
                         <Typography variant="body2" color="text.secondary" gutterBottom>
                           集合: {formatAddress(order.collection_address || '')}
@@ -51,12 +51,11 @@ const NFTDetailPage: React.FC = () => {
     return `${address.slice(0, 6)}...${address.slice(-4)}`;
   };
 
-  const formatPrice = (price: number) => {
-    try {
-      return price.toFixed(4) + ' ETH';
-    } catch {
-      return price.toString();
-    }
+  // 价格以后端返回的ETH展示字符串（*_eth字段）为准
+  const formatPrice = (price: string | null | undefined) => {
+    if (!price) return '暂无价格';
+    const value = parseFloat(price);
+    return (Number.isNaN(value) ? price : value.toFixed(4)) + ' ETH';
   };
 
   const getOrderStatusColor = (status: number) => {
@@ -91,14 +90,14 @@ const NFTDetailPage: React.FC = () => {
   // 获取最佳价格
   const bestSellPrice = sellOrders
     .filter((order: any) => order.order_status === 0) // 活跃状态
-    .sort((a: any, b: any) => a.price - b.price)[0];
+    .sort((a: any, b: any) => parseFloat(a.price_eth) - parseFloat(b.price_eth))[0];
     
   const bestBuyPrice = buyOrders
     .filter((order: any) => order.order_status === 0) // 活跃状态
-    .sort((a: any, b: any) => b.price - a.price)[0];
+    .sort((a: any, b: any) => parseFloat(b.price_eth) - parseFloat(a.price_eth))[0];
 
   // 购买订单处理函数
-  const handlePurchaseOrder = async (orderId: number, orderPrice: number) => {
+  const handlePurchaseOrder = async (orderId: number, orderPrice: string) => {
     if (!isConnected || !account) {
       alert('请先连接钱包');
       return;
@@ -193,7 +192,7 @@ const NFTDetailPage: React.FC = () => {
                     最低售价
                   </Typography>
                   <Typography variant="h6" color="white">
-                    {bestSellPrice ? formatPrice(bestSellPrice.price) : '暂无'}
+                    {bestSellPrice ? formatPrice(bestSellPrice.price_eth) : '暂无'}
                   </Typography>
                 </Box>
               </Grid>
@@ -203,7 +202,7 @@ const NFTDetailPage: React.FC = () => {
                     最高出价
                   </Typography>
                   <Typography variant="h6" color="white">
-                    {bestBuyPrice ? formatPrice(bestBuyPrice.price) : '暂无'}
+                    {bestBuyPrice ? formatPrice(bestBuyPrice.price_eth) : '暂无'}
                   </Typography>
                 </Box>
               </Grid>
@@ -225,7 +224,7 @@ const NFTDetailPage: React.FC = () => {
                       购买中...
                     </>
                   ) : (
-                    `立即购买 ${formatPrice(bestSellPrice.price)}`
+                    `立即购买 ${formatPrice(bestSellPrice.price_eth)}`
                   )}
                 </Button>
               )}
@@ -236,7 +235,7 @@ const NFTDetailPage: React.FC = () => {
                   fullWidth
                   size="large"
                 >
-                  立即出售 {formatPrice(bestBuyPrice.price)}
+                  立即出售 {formatPrice(bestBuyPrice.price_eth)}
                 </Button>
               )}
             </Box>
@@ -291,7 +290,7 @@ const NFTDetailPage: React.FC = () => {
                             <TableRow key={order.id}>
                               <TableCell>
                                 <Typography variant="body2" fontWeight="bold">
-                                  {formatPrice(order.price)}
+                                  {formatPrice(order.price_eth)}
                                 </Typography>
                               </TableCell>
                               <TableCell>
@@ -379,7 +378,7 @@ const NFTDetailPage: React.FC = () => {
                       </TableCell>
                       <TableCell>
                         <Typography variant="body2" fontWeight="bold">
-                          {formatPrice(order.price)}
+                          {formatPrice(order.price_eth)}
                         </Typography>
                       </TableCell>
                       <TableCell>
@@ -14,6 +14,7 @@ import {
   CircularProgress,
   Chip
 } from '@mui/material';
+import { ethers } from 'ethers';
 import { apiService } from '../services/api';
 import logger from '../utils/logger';
 
@@ -88,7 +89,10 @@ const TestPage: React.FC = () => {
     },
     {
       name: '创建订单',
-      test: () => apiService.createOrder(testOrderData)
+      test: () => apiService.createOrder({
+        ...testOrderData,
+        price: ethers.parseEther(String(testOrderData.price)).toString() // 转换为wei字符串
+      })
     },
     {
       name: '购买订单',
@@ -131,7 +135,7 @@ const TestPage: React.FC = () => {
             await apiService.createOrder({
               ...testOrderData,
               order_type: 1, // listing
-              price: 0.1,
+              price: ethers.parseEther('0.1').toString(),
               token_id: String(Date.now()) // 使用时间戳确保唯一性
             });
           } finally {
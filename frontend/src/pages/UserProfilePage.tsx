@@ -88,12 +88,11 @@ const UserProfilePage: React.FC = () => {
     return `${addr.slice(0, 6)}...${addr.slice(-4)}`;
   };
 
-  const formatPrice = (price: number) => {
-    try {
-      return price.toFixed(4) + ' ETH';
-    } catch {
-      return price.toString();
-    }
+  // 价格以后端返回的ETH展示字符串（*_eth字段）为准
+  const formatPrice = (price: string | null | undefined) => {
+    if (!price) return '暂无价格';
+    const value = parseFloat(price);
+    return (Number.isNaN(value) ? price : value.toFixed(4)) + ' ETH';
   };
 
   const getOrderStatusColor = (status: number) => {
@@ -189,7 +188,7 @@ const UserProfilePage: React.FC = () => {
                       </Box>
                       
                       <Typography variant="h6" gutterBottom>
-                        {formatPrice(order.price)}
+                        {formatPrice(order.price_eth)}
                       </Typography>
                       
                       <Typography variant="body2" color="text.secondary" gutterBottom>
@@ -276,7 +275,7 @@ const UserProfilePage: React.FC = () => {
                     </Typography>
                     {item.list_price && (
                       <Typography variant="body2" color="text.secondary">
-                        上架价格: {formatPrice(item.list_price)}
+                        上架价格: {formatPrice(item.list_price_eth)}
                       </Typography>
                     )}
                   </CardContent>
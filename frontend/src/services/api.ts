@@ -18,10 +18,10 @@ api.interceptors.request.use(
     // 记录API调用
     logger.logApiCall(config.method?.toUpperCase() || 'GET', config.url || '', config.data);
     
-    // 添加用户地址到请求头（如果存在）
-    const userAddress = localStorage.getItem('userAddress');
-    if (userAddress) {
-      config.headers['X-User-Address'] = userAddress;
+    // 添加SIWE登录令牌到请求头（如果存在）
+    const authToken = localStorage.getItem('authToken');
+    if (authToken) {
+      config.headers['Authorization'] = `Bearer ${authToken}`;
     }
     return config;
   },
@@ -61,6 +61,17 @@ api.interceptors.response.use(
 );
 
 export const apiService = {
+  // 认证相关API
+  getAuthNonce: async (address: string) => {
+    const response = await api.get('/auth/nonce', { params: { address } });
+    return response.data;
+  },
+
+  login: async (message: string, signature: string) => {
+    const response = await api.post('/auth/login', { message, signature });
+    return response.data;
+  },
+
   // 订单相关API
   createOrder: async (orderData: any) => {
     const response = await api.post('/orders', orderData);
@@ -107,10 +118,35 @@ export const apiService = {
     return response.data;
   },
 
-  // 购买订单
-  purchaseOrder: async (orderID: number, price?: number) => {
+  // 购买订单（price为十进制wei字符串，不传表示接受订单原价）
+  purchaseOrder: async (orderID: number, price?: string) => {
     const response = await api.post(`/orders/${orderID}/purchase`, {
-      price: price || 0
+      price: price || '0'
+    });
+    return response.data;
+  },
+
+  // 非托管交易构建：返回未签名交易，由用户钱包签名发送
+  buildCreateOrderTx: async (orderData: any) => {
+    const response = await api.post('/orders/tx/create', orderData);
+    return response.data;
+  },
+
+  buildCancelOrderTx: async (id: number) => {
+    const response = await api.post(`/orders/${id}/tx/cancel`);
+    return response.data;
+  },
+
+  buildExecuteOrderTx: async (id: number) => {
+    const response = await api.post(`/orders/${id}/tx/execute`);
+    return response.data;
+  },
+
+  // price为十进制wei字符串，expireTime为秒级时间戳
+  buildEditOrderTx: async (id: number, price: string, expireTime: number) => {
+    const response = await api.post(`/orders/${id}/tx/edit`, {
+      price,
+      expire_time: expireTime
     });
     return response.data;
   },